@@ -22,14 +22,50 @@ func (c Config) GetLoggerLevel(pkg string) string {
 	}
 }
 
+// Supported database types
+const (
+	DATABASE_TYPE_MYSQL    = "mysql"
+	DATABASE_TYPE_POSTGRES = "postgres"
+	DATABASE_TYPE_SQLITE   = "sqlite"
+)
+
+// SQLITE_BUSY_TIMEOUT is how long, in milliseconds, SQLite waits for another writer before failing
+const SQLITE_BUSY_TIMEOUT = 5000
+
+// DatabaseType returns the configured database type, defaulting to MySQL
+func (c Config) DatabaseType() (string, error) {
+	switch strings.ToLower(c.Database.Type) {
+	case "", DATABASE_TYPE_MYSQL:
+		return DATABASE_TYPE_MYSQL, nil
+	case DATABASE_TYPE_POSTGRES, "postgresql":
+		return DATABASE_TYPE_POSTGRES, nil
+	case DATABASE_TYPE_SQLITE, "sqlite3":
+		return DATABASE_TYPE_SQLITE, nil
+	default:
+		return "", fmt.Errorf("Unsupported database type %s", c.Database.Type)
+	}
+}
+
 // Helper function for DB connections
 func (c Config) CreateDatabaseURL() (string, error) {
+	dbType, err := c.DatabaseType()
+	if err != nil {
+		return "", err
+	}
+	if dbType == DATABASE_TYPE_SQLITE {
+		return c.createSQLiteURL()
+	}
+
 	if len(c.Database.Hostname) == 0 ||
 		len(c.Database.Username) == 0 ||
 		len(c.Database.Password) == 0 ||
 		len(c.Database.Database) == 0 {
 		return "", fmt.Errorf("Database parameters is missing")
 	}
+	if dbType == DATABASE_TYPE_POSTGRES {
+		return c.createPostgresURL(), nil
+	}
+
 	var dbURL strings.Builder
 	if c.Database.Port == "3306" || c.Database.Port == "0" {
 		c.Logger.Debug("Assuming standard DB port")
@@ -48,6 +84,50 @@ func (c Config) CreateDatabaseURL() (string, error) {
 	return dbURL.String(), nil
 }
 
+// createPostgresURL returns a PostgreSQL connection URL. Timestamps are read in the configured location
+func (c Config) createPostgresURL() string {
+	port := c.Database.Port
+	if len(port) == 0 || port == "0" {
+		port = "5432"
+	}
+
+	params := url.Values{}
+	if len(c.Database.SSLMode) > 0 {
+		params.Set("sslmode", c.Database.SSLMode)
+	}
+	if len(c.Location) > 0 {
+		params.Set("timezone", c.Location)
+	}
+
+	dbURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Database.Username, c.Database.Password),
+		Host:     fmt.Sprintf("%s:%s", c.Database.Hostname, port),
+		Path:     c.Database.Database,
+		RawQuery: params.Encode(),
+	}
+	return dbURL.String()
+}
+
+// createSQLiteURL returns the SQLite file in WAL mode, so readers do not block the writer. Transactions take the write
+// lock when they begin, and wait for other writers up to the busy timeout. Timestamps are read in the configured location
+func (c Config) createSQLiteURL() (string, error) {
+	if len(c.Database.Path) == 0 {
+		return "", fmt.Errorf("Database path is missing")
+	}
+
+	params := url.Values{}
+	params.Set("_journal_mode", "WAL")
+	params.Set("_synchronous", "NORMAL")
+	params.Set("_busy_timeout", fmt.Sprintf("%d", SQLITE_BUSY_TIMEOUT))
+	params.Set("_txlock", "immediate")
+	if len(c.Location) > 0 {
+		params.Set("_loc", c.Location)
+	}
+
+	return fmt.Sprintf("file:%s?%s", c.Database.Path, params.Encode()), nil
+}
+
 func GetPackage(input string) string {
 	pkg := strings.ReplaceAll(input, "github.com/KvalitetsIT/kih-telecare-exporter/", "")
 	logrus.Debug("Returning ", pkg)
@@ -120,12 +200,29 @@ func bindAllEnv() {
 	viper.BindEnv("EXPORT.RETRYDAYS")
 	viper.BindEnv("EXPORT.NODEVICEWHITELIST")
 	viper.BindEnv("EXPORT.BACKEND")
+	viper.BindEnv("EXPORT.CATALOG")
+	viper.BindEnv("EXPORT.DEVICES")
 	viper.BindEnv("EXPORT.OIOXDS.XDSGENERATOR.URL")
 	viper.BindEnv("EXPORT.OIOXDS.XDSGENERATOR.HEALTHCHECK")
+	viper.BindEnv("EXPORT.OIOXDS.PROFILE")
+	viper.BindEnv("EXPORT.QUESTIONNAIRES.ENABLED")
+	viper.BindEnv("EXPORT.WAVEFORMS.ENABLED")
+	viper.BindEnv("EXPORT.WAVEFORMS.CTGNPU")
+	viper.BindEnv("EXPORT.SCHEDULES.ENABLED")
+	viper.BindEnv("EXPORT.SCHEDULES.WINDOW")
 
 	// CLINICIAN
 	viper.BindEnv("CLINICIAN.BATCHSIZE")
 	viper.BindEnv("CLINICIAN.URL")
+	viper.BindEnv("CLINICIAN.CACHE.TTL")
+	viper.BindEnv("CLINICIAN.CACHE.SIZE")
+	viper.BindEnv("CLINICIAN.CACHE.PERSISTENT")
+	viper.BindEnv("CLINICIAN.CACHE.PERSISTENTTTL")
+	viper.BindEnv("CLINICIAN.RECORD")
+	viper.BindEnv("CLINICIAN.REPLAY")
+
+	// APP
+	viper.BindEnv("APP.TOKEN")
 
 	// AUTHENTICATION
 	viper.BindEnv("AUTHENTICATION.KEY")
@@ -138,4 +235,6 @@ func bindAllEnv() {
 	viper.BindEnv("DATABASE.TYPE")
 	viper.BindEnv("DATABASE.PORT")
 	viper.BindEnv("DATABASE.DATABASE")
+	viper.BindEnv("DATABASE.SSLMODE")
+	viper.BindEnv("DATABASE.PATH")
 }
@@ -3,6 +3,7 @@ package app
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -58,38 +59,110 @@ func (c Config) String() string {
 type AppConfig struct {
 	URL  string `mapstructure:"url"`
 	Port int    `mapstructure:"port"`
+	// Token is required as bearer token by the /consent endpoint. The endpoint is disabled when it is not set
+	Token string `mapstructure:"token"`
 }
 
 // configure linan endpoint
 type ClincianConfig struct {
-	BatchSize int    `mapstructure:"batchsize"`
-	URL       string `mapstructure:"url"`
+	BatchSize int                `mapstructure:"batchsize"`
+	URL       string             `mapstructure:"url"`
+	Cache     PatientCacheConfig `mapstructure:"cache"`
+	// Record writes all clinician responses to this directory
+	Record string `mapstructure:"record"`
+	// Replay serves clinician responses from a recording instead of calling clinician
+	Replay string `mapstructure:"replay"`
+}
+
+// Patient cache in front of the clinician API
+type PatientCacheConfig struct {
+	TTL           time.Duration `mapstructure:"ttl"`
+	Size          int           `mapstructure:"size"`
+	Persistent    bool          `mapstructure:"persistent"`
+	PersistentTTL time.Duration `mapstructure:"persistentttl"`
 }
 
 // Export backends
 type ExportConfig struct {
-	StartDate         string       `mapstructure:"start"`
-	Backend           string       `mapstructure:"backend"`
-	CreatedBy         string       `mapstructure:"created_by"`
-	DaysToRetry       int          `mapstructure:"retrydays"`
-	NoDeviceWhiteList bool         `mapstructure:"nodevicewhitelist"`
-	OIOXDSExport      OIOXDSConfig `mapstructure:"oioxds"`
+	StartDate         string              `mapstructure:"start"`
+	Backend           string              `mapstructure:"backend"`
+	CreatedBy         string              `mapstructure:"created_by"`
+	DaysToRetry       int                 `mapstructure:"retrydays"`
+	NoDeviceWhiteList bool                `mapstructure:"nodevicewhitelist"`
+	OIOXDSExport      OIOXDSConfig        `mapstructure:"oioxds"`
+	Questionnaires    QuestionnaireConfig `mapstructure:"questionnaires"`
+	PatientGroups     PatientGroupConfig  `mapstructure:"patientgroups"`
+	Waveforms         WaveformConfig      `mapstructure:"waveforms"`
+	Schedules         ScheduleConfig      `mapstructure:"schedules"`
+	// Catalog is a measurement type catalog replacing the built-in catalog
+	Catalog string `mapstructure:"catalog"`
+	// Devices is a device registry replacing the built-in registry
+	Devices string `mapstructure:"devices"`
+	// Profiles are named selections of measurement types. Backends select a profile by name
+	Profiles map[string]TypeProfile `mapstructure:"profiles"`
+}
+
+// TypeProfile is a selection of measurement types from the catalog, with overrides of their definitions
+type TypeProfile struct {
+	// Catalog selects the definitions for a backend, kihdb or oioxds. Defaults to the backend using the profile
+	Catalog string `mapstructure:"catalog"`
+	// Types are the exported types. Empty means all types of the catalog
+	Types []string `mapstructure:"types"`
+	// Overrides change the definitions of simple types, keyed by type name
+	Overrides map[string]TypeOverride `mapstructure:"overrides"`
 }
 
-// Returns endpoint depending on configuration
-func (e ExportConfig) GetExportEndpoint() string {
-	switch e.Backend {
-	case "oioxds":
-		return e.OIOXDSExport.XdsGenerator.URL
-	default:
-		return "Unknown"
-	}
+// TypeOverride changes the definition of a type in a profile. Fields left out are not changed
+type TypeOverride struct {
+	NpuCode      string `mapstructure:"npu"`
+	Unit         string `mapstructure:"unit"`
+	AnalysisText string `mapstructure:"analysistext"`
+	Decimals     *int   `mapstructure:"decimals"`
+	Export       *bool  `mapstructure:"export"`
 }
 
 func (e ExportConfig) String() string {
 	return fmt.Sprintf("%s - OIOXDS: %s", e.Backend, e.OIOXDSExport)
 }
 
+// Patient groups, by name or link, whose measurements are allowed or denied export. An empty allow list allows all groups
+type PatientGroupConfig struct {
+	Allow []string `mapstructure:"allow"`
+	Deny  []string `mapstructure:"deny"`
+}
+
+// Export of questionnaire results
+type QuestionnaireConfig struct {
+	Enabled bool              `mapstructure:"enabled"`
+	Mapping []QuestionMapping `mapstructure:"mapping"`
+}
+
+// Export of ECG and CTG waveforms as document attachments
+type WaveformConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// CTG is only exported when the code for the fetal heart rate is given
+	CTGNpuCode      string `mapstructure:"ctgnpu"`
+	CTGAnalysisText string `mapstructure:"ctganalysistext"`
+}
+
+// Marking measurements as scheduled or unscheduled from the questionnaire schedules of the patient
+type ScheduleConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Window is how far from a scheduled time a measurement is still scheduled
+	Window time.Duration `mapstructure:"window"`
+}
+
+// Maps a question to the code used when exporting the answer. An empty questionnaire matches all questionnaires
+type QuestionMapping struct {
+	Questionnaire string `mapstructure:"questionnaire"`
+	Question      string `mapstructure:"question"`
+	NpuCode       string `mapstructure:"npu"`
+	AnalysisText  string `mapstructure:"analysistext"`
+	Unit          string `mapstructure:"unit"`
+	Encoding      string `mapstructure:"encoding"`
+	Decimals      int    `mapstructure:"decimals"`
+}
+
 // Setting up Sosi for DGWS
 type SosiConfig struct {
 	URL             string `mapstructure:"url"`
@@ -99,6 +172,8 @@ type SosiConfig struct {
 type OIOXDSConfig struct {
 	SkipSslVerify bool      `mapstructure:"skipSSLVerify"`
 	XdsGenerator  XdsConfig `mapstructure:"xdsgenerator"`
+	// Profile is the measurement type profile exported. Empty means all types of the catalog
+	Profile string `mapstructure:"profile"`
 }
 
 func (o OIOXDSConfig) String() string {
@@ -112,9 +187,15 @@ type XdsConfig struct {
 
 // Local database
 type DatabaseConfig struct {
+	// Type is mysql (default), postgres or sqlite
+	Type     string `mapstructure:"type"`
 	Hostname string `mapstructure:"hostname"`
 	Username string `mapstructure:"username"`
 	Port     string `mapstructure:"port"`
 	Password string `mapstructure:"password"`
 	Database string `mapstructure:"database"`
+	// SSLMode is the sslmode of PostgreSQL connections. Left out the driver default is used
+	SSLMode string `mapstructure:"sslmode"`
+	// Path is the database file of SQLite
+	Path string `mapstructure:"path"`
 }
@@ -7,7 +7,8 @@ import (
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/app"
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
-	"github.com/KvalitetsIT/kih-telecare-exporter/backend/oioxds"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/shared"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/registry"
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
 
@@ -20,8 +21,6 @@ var repo repository.Repository
 var cfg *app.Config
 var log *logrus.Logger
 
-const OIOXDS_BACKEND = "oioxds"
-
 func InitExporter(config *app.Config, measurementApi measurement.MeasurementApi, repos repository.Repository) (Exporter, error) {
 	cfg = config
 	api = measurementApi
@@ -29,32 +28,25 @@ func InitExporter(config *app.Config, measurementApi measurement.MeasurementApi,
 	wantedLevel := config.GetLoggerLevel(pkg)
 	log = app.NewLogger(wantedLevel)
 	repo = repos
-	exporter := exporterImpl{}
+	exporter := exporterImpl{groups: newPatientGroupFilter(config.Export.PatientGroups)}
 	log.Debug("Type: ", config.Export.Backend)
 
-	switch config.Export.Backend {
-	case OIOXDS_BACKEND:
-		log.Debug("Setting up OIOXDS export ")
-		oioxdsBackend := oioxds.InitExporter(config, api)
-		exporter.exporter = oioxdsBackend
-	default:
-		log.Warnf("Unsupported backend - %s", config.Export.Backend)
-		return &exporter, fmt.Errorf("Unsupported backend")
+	exportBackend, err := registry.Resolve(config.Export.Backend, config, api)
+	if err != nil {
+		log.Warnf("Error setting up backend %s - %v", config.Export.Backend, err)
+		return &exporter, err
 	}
+	exporter.exporter = exportBackend
 
 	return &exporter, nil
 }
 
-type ExportBackend interface {
-	ConvertMeasurement(m measurement.Measurement, mr repository.MeasurementExportState) (string, error)
-	ExportMeasurement(string) (string, error)
-	ShouldExport(m measurement.Measurement) bool
-	GetExportTypes() map[string]exporttypes.MeasurementType
-	CheckHealth() error
-}
+// ExportBackend is implemented by the backends in the registry
+type ExportBackend = registry.ExportBackend
 
 type exporterImpl struct {
 	exporter ExportBackend
+	groups   patientGroupFilter
 }
 
 func MeasurementToMeasurementType(measurement measurement.Measurement) repository.MeasurementExportState {
@@ -98,6 +90,23 @@ func (e exporterImpl) MarkPermanentFailed() error {
 		}
 	}
 
+	questionnaireResults, err := repo.FindQuestionnaireResultsByStatus(repository.TEMP_FAILURE)
+	if err != nil {
+		return errors.Wrap(err, "Error search temporarily failed questionnaire results")
+	}
+
+	for _, v := range questionnaireResults {
+		hours_parked := int(time.Since(v.CreatedAt.Time).Hours())
+		if hours_parked > 24*cfg.Export.DaysToRetry {
+			log.Debug("Marked temp failed for ", v, " temp failed for ", hours_parked, " hours")
+			v.Status = repository.FAILED
+
+			if _, err := repo.UpdateQuestionnaireResult(v); err != nil {
+				log.Errorf("Error updating repository - %+v", err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -134,6 +143,22 @@ func (e exporterImpl) ExportMeasurement(othMeasurement measurement.Measurement,
 	if nil != e.exporter {
 		res, err := e.exporter.ConvertMeasurement(localMeasurement, exportState)
 
+		// Measurements which will never convert are flagged, so they are not retried
+		if status, reason, ok := unconvertibleState(err); ok {
+			log.Warnf("Measurement not exported - id %s - %v", exportState.ID, errors.Cause(err))
+			exportState.Status = status
+			exportState.Reason = reason
+
+			m, errdb := repo.UpdateMeasurement(exportState)
+			if errdb != nil {
+				log.Errorf("Error updating measurement %+v - %s", errdb, m)
+			}
+
+			result.Success = false
+			result.Measurement = m
+			return result, nil
+		}
+
 		if err != nil {
 			errmsg := fmt.Sprintf("Error converting measurement - id %s - %v", exportState.ID, err)
 			log.Errorf(errmsg)
@@ -211,6 +236,7 @@ func (e exporterImpl) ExportMeasurements() ([]ExportResult, error) {
 	exports := []ExportResult{}
 
 	rejected := 0
+	invalid := 0
 	exported := 0
 	failed := 0
 	iteration := 0
@@ -246,14 +272,19 @@ func (e exporterImpl) ExportMeasurements() ([]ExportResult, error) {
 				log.Debug("M, ", m, " is flagged as no-export")
 				handled++
 				continue
+			case repository.INVALID:
+				log.Debug("M, ", m, " is flagged as invalid")
+				handled++
+				continue
 			case repository.FAILED:
 				log.Debug("M, ", m, " is already flaggged failed")
 				handled++
 				continue
 			default:
-				export, ex, fai, re, _ := e.HandleMeasurement(measurement, m)
+				export, ex, fai, re, inv, _ := e.HandleMeasurement(measurement, m)
 				exports = append(exports, export)
 				rejected += re
+				invalid += inv
 				exported += ex
 				failed += fai
 
@@ -262,6 +293,18 @@ func (e exporterImpl) ExportMeasurements() ([]ExportResult, error) {
 		iteration++
 	}
 
+	if cfg.Export.Questionnaires.Enabled {
+		qExports, ex, fai, re, err := e.ExportQuestionnaireResults(startTime.Lastrun)
+		if err != nil {
+			log.Errorf("Error exporting questionnaire results - %v", err)
+			fai++
+		}
+		exports = append(exports, qExports...)
+		rejected += re
+		exported += ex
+		failed += fai
+	}
+
 	if failed > 0 {
 		startTime.Status = repository.FAILED
 	} else {
@@ -272,15 +315,29 @@ func (e exporterImpl) ExportMeasurements() ([]ExportResult, error) {
 	}
 
 	log.Info(
-		fmt.Sprintf("type=export uuid=%s completed=%s starttime=%s iterations=%d tt=%d total=%d exported=%d rejected=%d failed=%d",
+		fmt.Sprintf("type=export uuid=%s completed=%s starttime=%s iterations=%d tt=%d total=%d exported=%d rejected=%d invalid=%d failed=%d",
 			startTime.Id.String(), time.Now().Format(time.RFC3339),
 			startTime.Lastrun.Format(time.RFC3339),
 			iteration, time.Since(start).Milliseconds(),
-			exported+failed+rejected, exported, rejected, failed))
+			exported+failed+rejected+invalid, exported, rejected, invalid, failed))
 
 	return exports, nil
 }
 
+// unconvertibleState returns the state of a measurement which failed conversion for a reason retrying will not change.
+// Unsupported units and control measurements are not exported, and implausible values are invalid
+func unconvertibleState(err error) (int, string, bool) {
+	switch cause := errors.Cause(err).(type) {
+	case exporttypes.UnitError:
+		return repository.NO_EXPORT, repository.REASON_UNSUPPORTED_UNIT, true
+	case exporttypes.ControlMeasurementError:
+		return repository.NO_EXPORT, repository.REASON_CONTROL_MEASUREMENT, true
+	case exporttypes.ValidationError:
+		return repository.INVALID, cause.Reason, true
+	}
+	return 0, "", false
+}
+
 // Handle by measurement
 // - Checks if it should be exported
 //   - If not, mark as NO_EXPORT ( status = 5 in DB) return 1 for rejected
@@ -290,25 +347,75 @@ func (e exporterImpl) ExportMeasurements() ([]ExportResult, error) {
 //   - Retry if marked as temporarily failed
 //
 // - Update state in db
-// - return result, and values indicating if exported,failed, or rejected
-func (e exporterImpl) HandleMeasurement(othMeasurement measurement.Measurement, exportState repository.MeasurementExportState) (ExportResult, int, int, int, error) {
+// - return result, and values indicating if exported,failed, rejected or invalid
+func (e exporterImpl) HandleMeasurement(othMeasurement measurement.Measurement, exportState repository.MeasurementExportState) (ExportResult, int, int, int, int, error) {
 	var export ExportResult
 	var err error
 	failed := 0
 	exported := 0
 	rejected := 0
+	invalid := 0
 	startTime := time.Now()
 
-	if e.exporter.ShouldExport(othMeasurement) {
+	consent, err := e.findWithdrawnConsent(exportState.Patient)
+	if err != nil {
+		log.Errorf("Error checking consent - %v", err)
+		exportState.Status = repository.TEMP_FAILURE
+		exportState, err = repo.UpdateMeasurement(exportState)
+		if err != nil {
+			log.Error("Error updating repository - ", exportState, " - ", err)
+		}
+		export.Measurement = exportState
+		failed++
+	} else if consent != nil {
+		exportState.Status = repository.NO_EXPORT
+		exportState.Reason = repository.REASON_CONSENT_WITHDRAWN
+		exportState.ConsentID = consent.ID.String()
+		exportState, err = repo.UpdateMeasurement(exportState)
+		if err != nil {
+			return export, exported, failed, rejected, invalid, errors.Wrap(err, "Error exporting measurement")
+		}
+		log.Debug("Noexport uuid=", exportState.ID.String(), " reason=", exportState.Reason, " consent=", exportState.ConsentID)
+		export.Measurement = exportState
+		rejected++
+	} else if e.exporter.ShouldExport(othMeasurement) {
 		log.Debug("Handling measuremnt - ", exportState)
 
-		if exportState.Status != repository.COMPLETED && exportState.Status != repository.NO_EXPORT {
+		excluded, err := e.isExcludedByPatientGroup(exportState.Patient)
+		if err != nil {
+			log.Errorf("Error looking up patient groups - %v", err)
+			exportState.Status = repository.TEMP_FAILURE
+			exportState, err = repo.UpdateMeasurement(exportState)
+			if err != nil {
+				log.Error("Error updating repository - ", exportState, " - ", err)
+			}
+			export.Measurement = exportState
+			failed++
+		} else if excluded {
+			exportState.Status = repository.NO_EXPORT
+			exportState.Reason = repository.REASON_PATIENT_GROUP_EXCLUDED
+			exportState, err = repo.UpdateMeasurement(exportState)
+			if err != nil {
+				return export, exported, failed, rejected, invalid, errors.Wrap(err, "Error exporting measurement")
+			}
+			log.Debug("Noexport uuid=", exportState.ID.String(), " reason=", exportState.Reason)
+			export.Measurement = exportState
+			rejected++
+		} else if exportState.Status != repository.COMPLETED && exportState.Status != repository.NO_EXPORT && exportState.Status != repository.INVALID {
 			export, err = e.ExportMeasurement(othMeasurement, exportState)
 			if err != nil {
 				log.Error("Error exporting measurement")
 				log.Debugf("Trace %+v", err)
 
 				failed++
+			} else if export.Measurement.Status == repository.INVALID {
+				exportState = export.Measurement
+				log.Debug("Invalid uuid=", exportState.ID.String(), " reason=", exportState.Reason)
+				invalid++
+			} else if export.Measurement.Status == repository.NO_EXPORT {
+				exportState = export.Measurement
+				log.Debug("Noexport uuid=", exportState.ID.String(), " reason=", exportState.Reason)
+				rejected++
 			} else {
 				exportState.Status = repository.COMPLETED
 				_, err := repo.UpdateMeasurement(exportState)
@@ -324,7 +431,7 @@ func (e exporterImpl) HandleMeasurement(othMeasurement measurement.Measurement,
 		export.Success = false
 		exportState, err = repo.UpdateMeasurement(exportState)
 		if err != nil {
-			return export, exported, failed, rejected, errors.Wrap(err, "Error exporting measurement")
+			return export, exported, failed, rejected, invalid, errors.Wrap(err, "Error exporting measurement")
 		}
 		rejected++
 		log.Debug("Noexport uuid=", exportState.ID.String(), " status=",
@@ -335,5 +442,152 @@ func (e exporterImpl) HandleMeasurement(othMeasurement measurement.Measurement,
 	log.Info(fmt.Sprintf("type=measurement uuid=%s status=%s exporttotal=%d ms", exportState.ID.String(),
 		repository.StatusToText(exportState.Status), time.Since(startTime).Milliseconds()))
 
-	return export, exported, failed, rejected, nil
+	return export, exported, failed, rejected, invalid, nil
+}
+
+// findWithdrawnConsent returns the consent entry blocking export for the patient, or nil if none. The patient is only looked up when entries are registered by cpr
+func (e exporterImpl) findWithdrawnConsent(patient string) (*repository.ConsentEntry, error) {
+	entries, err := repo.FindConsent(patient, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "Error retrieving consent")
+	}
+	if len(entries) > 0 {
+		return &entries[0], nil
+	}
+
+	byCPR, err := repo.HasConsentByCPR()
+	if err != nil {
+		return nil, errors.Wrap(err, "Error retrieving consent")
+	}
+	if !byCPR {
+		return nil, nil
+	}
+
+	p, err := api.FetchPatient(patient)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error retrieving patient")
+	}
+	entries, err = repo.FindConsent("", p.UniqueID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error retrieving consent")
+	}
+	if len(entries) > 0 {
+		return &entries[0], nil
+	}
+
+	return nil, nil
+}
+
+// isExcludedByPatientGroup looks up the patient and checks its groups against the configured allow and deny lists
+func (e exporterImpl) isExcludedByPatientGroup(patient string) (bool, error) {
+	if !e.groups.isActive() {
+		return false, nil
+	}
+
+	p, err := api.FetchPatient(patient)
+	if err != nil {
+		return false, errors.Wrap(err, "Error retrieving patient")
+	}
+	return e.groups.isExcluded(p), nil
+}
+
+// ExportQuestionnaireResults exports the questionnaire results completed since the timestamp
+func (e exporterImpl) ExportQuestionnaireResults(since time.Time) ([]ExportResult, int, int, int, error) {
+	start := time.Now()
+	exports := []ExportResult{}
+	exported := 0
+	failed := 0
+	rejected := 0
+	var err error
+
+	res := measurement.QuestionnaireResultResponse{}
+	res.Total = cfg.ClinicianConfig.BatchSize + 1 // make sure we at least run onces
+
+	// Handle pagination
+	for i := 0; res.Offset+cfg.ClinicianConfig.BatchSize < res.Total; i++ {
+		res, err = api.FetchQuestionnaireResults(since, i*cfg.ClinicianConfig.BatchSize)
+		if err != nil {
+			return exports, exported, failed, rejected, errors.Wrap(err, "Error fetching questionnaire results")
+		}
+
+		for _, q := range res.Results {
+			state := repository.QuestionnaireExportState{QuestionnaireResult: q.Links.QuestionnaireResult, Patient: q.Links.Patient}
+			state, err := repo.FindOrCreateQuestionnaireResult(state)
+			if err != nil {
+				return exports, exported, failed, rejected, errors.Wrap(err, "Error getting questionnaire result from DB")
+			}
+
+			switch state.Status {
+			case repository.COMPLETED, repository.NO_EXPORT, repository.FAILED:
+				log.Debug("Q, ", state, " is already handled")
+				continue
+			}
+
+			export := e.exportQuestionnaireResult(q, state)
+			exports = append(exports, export)
+			switch export.QuestionnaireResult.Status {
+			case repository.COMPLETED:
+				exported++
+			case repository.NO_EXPORT:
+				rejected++
+			default:
+				failed++
+			}
+		}
+	}
+
+	log.Info(
+		fmt.Sprintf("type=questionnaireexport completed=%s starttime=%s tt=%d total=%d exported=%d rejected=%d failed=%d",
+			time.Now().Format(time.RFC3339), since.Format(time.RFC3339), time.Since(start).Milliseconds(),
+			exported+failed+rejected, exported, rejected, failed))
+
+	return exports, exported, failed, rejected, nil
+}
+
+// exportQuestionnaireResult converts and exports a single questionnaire result and updates its state in the repository
+func (e exporterImpl) exportQuestionnaireResult(q measurement.QuestionnaireResult, state repository.QuestionnaireExportState) ExportResult {
+	result := ExportResult{}
+
+	consent, err := e.findWithdrawnConsent(state.Patient)
+	if err != nil {
+		log.Errorf("Error checking consent - %v", err)
+		state.Status = repository.TEMP_FAILURE
+	} else if consent != nil {
+		log.Debug("Noexport uuid=", state.ID.String(), " reason=", repository.REASON_CONSENT_WITHDRAWN, " consent=", consent.ID.String())
+		state.Status = repository.NO_EXPORT
+		state.Reason = repository.REASON_CONSENT_WITHDRAWN
+		state.ConsentID = consent.ID.String()
+	} else if !e.exporter.ShouldExportQuestionnaireResult(q) {
+		state.Status = repository.NO_EXPORT
+	} else if excluded, err := e.isExcludedByPatientGroup(state.Patient); err != nil {
+		log.Errorf("Error looking up patient groups - %v", err)
+		state.Status = repository.TEMP_FAILURE
+	} else if excluded {
+		log.Debug("Noexport uuid=", state.ID.String(), " reason=", repository.REASON_PATIENT_GROUP_EXCLUDED)
+		state.Status = repository.NO_EXPORT
+		state.Reason = repository.REASON_PATIENT_GROUP_EXCLUDED
+	} else if res, err := e.exporter.ConvertQuestionnaireResult(q, state); err != nil {
+		log.Errorf("Error converting questionnaire result - id %s - %v", state.ID, err)
+		state.Status = repository.TEMP_FAILURE
+		// An answer not matching the mapping will never convert, so it is not retried
+		if _, ok := errors.Cause(err).(shared.AnswerNotNumericError); ok {
+			state.Status = repository.FAILED
+			state.Reason = repository.REASON_ANSWER_NOT_NUMERIC
+		}
+	} else if _, err := e.exporter.ExportMeasurement(res); err != nil {
+		log.Errorf("Error exporting questionnaire result - id %s - %v", state.ID, err)
+		state.Status = repository.TEMP_FAILURE
+	} else {
+		state.Status = repository.COMPLETED
+		result.Success = true
+	}
+
+	state, err = repo.UpdateQuestionnaireResult(state)
+	if err != nil {
+		log.Error("Error updating questionnaire result - ", err, state)
+	}
+	log.Info(fmt.Sprintf("type=questionnaire uuid=%s status=%s", state.ID.String(), repository.StatusToText(state.Status)))
+
+	result.QuestionnaireResult = &state
+	return result
 }
@@ -13,6 +13,8 @@ import (
 	"time"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	_ "github.com/KvalitetsIT/kih-telecare-exporter/backend/oioxds"
 	othtest "github.com/KvalitetsIT/kih-telecare-exporter/internal/testutil"
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
@@ -27,7 +29,8 @@ import (
 var application *app.Config
 
 type mockApi struct {
-	measurements measurement.MeasurementResponse
+	measurements   measurement.MeasurementResponse
+	questionnaires measurement.QuestionnaireResultResponse
 }
 
 // CheckHealth implements measurement.MeasurementApi
@@ -44,6 +47,26 @@ func (ma mockApi) FetchMeasurement(mea string) (measurement.Measurement, error)
 	return ma.measurements.Results[0], nil
 }
 
+func (ma mockApi) FetchQuestionnaireResults(since time.Time, offset int) (measurement.QuestionnaireResultResponse, error) {
+	return ma.questionnaires, nil
+}
+
+func (ma mockApi) FetchQuestionnaireResult(q string) (measurement.QuestionnaireResult, error) {
+	return ma.questionnaires.Results[0], nil
+}
+
+func (ma mockApi) FetchPatientThresholds(t string) (measurement.PatientThresholds, error) {
+	return measurement.PatientThresholds{}, fmt.Errorf("No thresholds found for %s", t)
+}
+
+func (ma mockApi) FetchQuestionnaireSchedules(s string) (measurement.QuestionnaireSchedules, error) {
+	return measurement.QuestionnaireSchedules{}, fmt.Errorf("No schedules found for %s", s)
+}
+
+func (ma mockApi) FetchWaveform(w string) (measurement.Waveform, error) {
+	return measurement.Waveform{}, fmt.Errorf("No waveform found for %s", w)
+}
+
 func (ma mockApi) FetchPatient(person string) (measurement.PatientResult, error) {
 	fmt.Println("Person: ", person)
 	var filename string
@@ -440,6 +463,7 @@ func TestHandleTemporaryFailedMeasurements(t *testing.T) {
 	}
 	application.Logger = log
 	application.Export.DaysToRetry = 7
+	application.Export.OIOXDSExport.XdsGenerator.URL = "http://localhost:9010/api/createphmr"
 	application.Export.Backend = "oioxds"
 
 	mockApi := mockApi{}
@@ -465,3 +489,334 @@ func TestHandleTemporaryFailedMeasurements(t *testing.T) {
 
 	}
 }
+
+func TestExportQuestionnaireResults(t *testing.T) {
+	db, conn, repo, err := setupTestDatabase()
+	if err != nil {
+		t.Fatal("Error setting up DB")
+	}
+	defer func() {
+		repo.Close()
+		conn.Close()
+		db.Close()
+	}()
+
+	application, err := app.InitConfig()
+	if err != nil {
+		t.Errorf("error instantiating %+v", err)
+	}
+	application.Logger = log
+	application.Export.Backend = "oioxds"
+	application.ClinicianConfig.BatchSize = 100
+	application.Export.Questionnaires.Enabled = true
+	application.Export.Questionnaires.Mapping = []app.QuestionMapping{
+		{Question: "wellbeing", NpuCode: "MCS88202", AnalysisText: "Pt—Velbefindende; skala", Encoding: "numeric"},
+		{Questionnaire: "Textual", Question: "dyspnoea", NpuCode: "MCS88201", Encoding: "numeric"},
+	}
+
+	var request string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		request = string(body)
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+	application.Export.OIOXDSExport.XdsGenerator.URL = ts.URL
+
+	var q measurement.QuestionnaireResult
+	data, err := ioutil.ReadFile("kih/shared/testdata/questionnaire_result.json")
+	if err != nil {
+		t.Fatalf("Error reading input file - %v", err)
+	}
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatalf("Error parsing input file - %v", err)
+	}
+
+	unmapped := q
+	unmapped.Name = "Unmapped"
+	unmapped.Results = unmapped.Results[:1]
+	unmapped.Links.QuestionnaireResult = "http://clinician:8080/clinician/api/patients/13/questionnaire_results/53"
+
+	// A text answer mapped as numeric can never be exported
+	textual := q
+	textual.Name = "Textual"
+	textual.Results = textual.Results[:1]
+	textual.Links.QuestionnaireResult = "http://clinician:8080/clinician/api/patients/13/questionnaire_results/54"
+
+	mockApi := mockApi{}
+	mockApi.questionnaires.Results = []measurement.QuestionnaireResult{q, unmapped, textual}
+	mockApi.questionnaires.Total = 3
+
+	exprtr, err := InitExporter(application, mockApi, repo)
+	if err != nil {
+		t.Fatalf("error instantiating %+v", err)
+	}
+
+	res, exported, failed, rejected, err := exprtr.ExportQuestionnaireResults(time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(res) != 3 || exported != 1 || failed != 1 || rejected != 1 {
+		t.Errorf("Expected 1 exported, 1 failed and 1 rejected - got %d/%d/%d", exported, failed, rejected)
+	}
+	if !strings.Contains(request, "MCS88202") {
+		t.Errorf("NPU code not found in request %s", request)
+	}
+
+	// Already completed results are not exported again
+	res, exported, _, _, err = exprtr.ExportQuestionnaireResults(time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(res) != 0 || exported != 0 {
+		t.Errorf("Expected no exports on second run - got %d", len(res))
+	}
+
+	stored, err := repo.FindOrCreateQuestionnaireResult(repository.QuestionnaireExportState{QuestionnaireResult: q.Links.QuestionnaireResult})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if stored.Status != repository.COMPLETED {
+		t.Errorf("Expected questionnaire result to be completed - got %s", repository.StatusToText(stored.Status))
+	}
+
+	stored, err = repo.FindOrCreateQuestionnaireResult(repository.QuestionnaireExportState{QuestionnaireResult: textual.Links.QuestionnaireResult})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if stored.Status != repository.FAILED || stored.Reason != repository.REASON_ANSWER_NOT_NUMERIC {
+		t.Errorf("Expected questionnaire result to be failed as not numeric - got %s/%s", repository.StatusToText(stored.Status), stored.Reason)
+	}
+}
+
+func TestHandleMeasurementConsentWithdrawn(t *testing.T) {
+	db, conn, repo, err := setupTestDatabase()
+	if err != nil {
+		t.Fatal("Error setting up DB")
+	}
+	defer func() {
+		repo.Close()
+		conn.Close()
+		db.Close()
+	}()
+
+	application, err := app.InitConfig()
+	if err != nil {
+		t.Errorf("error instantiating %+v", err)
+	}
+	application.Logger = log
+	application.Export.OIOXDSExport.XdsGenerator.URL = "http://localhost:9010/api/createphmr"
+	application.Export.Backend = "oioxds"
+
+	exprtr, err := InitExporter(application, mockApi{}, repo)
+	if err != nil {
+		t.Fatalf("error instantiating %+v", err)
+	}
+
+	// Patient 13 has cpr 2512484916
+	consent, err := repo.CreateConsent(repository.ConsentEntry{CPR: "251248-4916"})
+	if err != nil {
+		t.Fatalf("Error creating consent %v", err)
+	}
+
+	mm, err := measurementFromFile("weight.json")
+	if err != nil {
+		t.Fatalf("Error reading measurement from file - %v", err)
+	}
+	state, err := repo.FindOrCreateMeasurement(MeasurementToMeasurementType(mm))
+	if err != nil {
+		t.Fatalf("Error getting measurement from repository - %v", err)
+	}
+
+	res, exported, failed, rejected, _, err := exprtr.HandleMeasurement(mm, state)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if exported != 0 || failed != 0 || rejected != 1 {
+		t.Errorf("Expected measurement to be rejected - got %d/%d/%d", exported, failed, rejected)
+	}
+	if res.Measurement.Reason != repository.REASON_CONSENT_WITHDRAWN || res.Measurement.ConsentID != consent.ID.String() {
+		t.Errorf("Expected reason and consent to be recorded - got '%s' '%s'", res.Measurement.Reason, res.Measurement.ConsentID)
+	}
+
+	stored, err := repo.FindMeasurement(state.ID.String())
+	if err != nil {
+		t.Fatalf("Error finding measurement %v", err)
+	}
+	if stored.Status != repository.NO_EXPORT || stored.ConsentID != consent.ID.String() {
+		t.Errorf("Expected stored no-export with consent - got %s '%s'", repository.StatusToText(stored.Status), stored.ConsentID)
+	}
+
+	// Blocked by patient link
+	if err := repo.DeleteConsent(consent.ID.String()); err != nil {
+		t.Fatalf("Error deleting consent %v", err)
+	}
+	if _, err := repo.CreateConsent(repository.ConsentEntry{Patient: mm.Links.Patient}); err != nil {
+		t.Fatalf("Error creating consent %v", err)
+	}
+	if _, _, _, rejected, _, _ := exprtr.HandleMeasurement(mm, state); rejected != 1 {
+		t.Error("Expected measurement to be rejected by patient link")
+	}
+}
+
+func TestHandleMeasurementUnsupportedUnit(t *testing.T) {
+	db, conn, repo, err := setupTestDatabase()
+	if err != nil {
+		t.Fatal("Error setting up DB")
+	}
+	defer func() {
+		repo.Close()
+		conn.Close()
+		db.Close()
+	}()
+
+	application, err := app.InitConfig()
+	if err != nil {
+		t.Errorf("error instantiating %+v", err)
+	}
+	application.Logger = log
+	application.Export.OIOXDSExport.XdsGenerator.URL = "http://localhost:9010/api/createphmr"
+	application.Export.Backend = "oioxds"
+
+	exprtr, err := InitExporter(application, mockApi{}, repo)
+	if err != nil {
+		t.Fatalf("error instantiating %+v", err)
+	}
+
+	mm, err := measurementFromFile("weight.json")
+	if err != nil {
+		t.Fatalf("Error reading measurement from file - %v", err)
+	}
+	mm.Measurement.Unit = "stone"
+
+	state, err := repo.FindOrCreateMeasurement(MeasurementToMeasurementType(mm))
+	if err != nil {
+		t.Fatalf("Error getting measurement from repository - %v", err)
+	}
+
+	res, exported, failed, rejected, _, err := exprtr.HandleMeasurement(mm, state)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if exported != 0 || failed != 0 || rejected != 1 {
+		t.Errorf("Expected measurement to be rejected - got %d/%d/%d", exported, failed, rejected)
+	}
+
+	stored, err := repo.FindMeasurement(state.ID.String())
+	if err != nil {
+		t.Fatalf("Error finding measurement %v", err)
+	}
+	if stored.Status != repository.NO_EXPORT || stored.Reason != repository.REASON_UNSUPPORTED_UNIT || res.Measurement.Reason != repository.REASON_UNSUPPORTED_UNIT {
+		t.Errorf("Expected no-export for unsupported unit - got %s '%s'", repository.StatusToText(stored.Status), stored.Reason)
+	}
+}
+
+func TestHandleMeasurementControl(t *testing.T) {
+	db, conn, repo, err := setupTestDatabase()
+	if err != nil {
+		t.Fatal("Error setting up DB")
+	}
+	defer func() {
+		repo.Close()
+		conn.Close()
+		db.Close()
+	}()
+
+	application, err := app.InitConfig()
+	if err != nil {
+		t.Errorf("error instantiating %+v", err)
+	}
+	application.Logger = log
+	application.Export.OIOXDSExport.XdsGenerator.URL = "http://localhost:9010/api/createphmr"
+	application.Export.Backend = "oioxds"
+
+	exprtr, err := InitExporter(application, mockApi{}, repo)
+	if err != nil {
+		t.Fatalf("error instantiating %+v", err)
+	}
+
+	mm, err := measurementFromFile("bloodsugar.json")
+	if err != nil {
+		t.Fatalf("Error reading measurement from file - %v", err)
+	}
+	mm.Measurement.IsControlMeasurement = true
+
+	state, err := repo.FindOrCreateMeasurement(MeasurementToMeasurementType(mm))
+	if err != nil {
+		t.Fatalf("Error getting measurement from repository - %v", err)
+	}
+
+	_, exported, failed, rejected, _, err := exprtr.HandleMeasurement(mm, state)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if exported != 0 || failed != 0 || rejected != 1 {
+		t.Errorf("Expected measurement to be rejected - got %d/%d/%d", exported, failed, rejected)
+	}
+
+	stored, err := repo.FindMeasurement(state.ID.String())
+	if err != nil {
+		t.Fatalf("Error finding measurement %v", err)
+	}
+	if stored.Status != repository.NO_EXPORT || stored.Reason != repository.REASON_CONTROL_MEASUREMENT {
+		t.Errorf("Expected control measurement not to be exported - got %s '%s'", repository.StatusToText(stored.Status), stored.Reason)
+	}
+}
+
+func TestHandleMeasurementInvalid(t *testing.T) {
+	db, conn, repo, err := setupTestDatabase()
+	if err != nil {
+		t.Fatal("Error setting up DB")
+	}
+	defer func() {
+		repo.Close()
+		conn.Close()
+		db.Close()
+	}()
+
+	application, err := app.InitConfig()
+	if err != nil {
+		t.Errorf("error instantiating %+v", err)
+	}
+	application.Logger = log
+	application.Export.OIOXDSExport.XdsGenerator.URL = "http://localhost:9010/api/createphmr"
+	application.Export.Backend = "oioxds"
+
+	exprtr, err := InitExporter(application, mockApi{}, repo)
+	if err != nil {
+		t.Fatalf("error instantiating %+v", err)
+	}
+
+	mm, err := measurementFromFile("weight.json")
+	if err != nil {
+		t.Fatalf("Error reading measurement from file - %v", err)
+	}
+	mm.Measurement.Value = measurement.EnumeratedValue("heavy")
+
+	state, err := repo.FindOrCreateMeasurement(MeasurementToMeasurementType(mm))
+	if err != nil {
+		t.Fatalf("Error getting measurement from repository - %v", err)
+	}
+
+	res, exported, failed, rejected, invalid, err := exprtr.HandleMeasurement(mm, state)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if exported != 0 || failed != 0 || rejected != 0 || invalid != 1 {
+		t.Errorf("Expected measurement to be invalid - got %d/%d/%d/%d", exported, failed, rejected, invalid)
+	}
+
+	stored, err := repo.FindMeasurement(state.ID.String())
+	if err != nil {
+		t.Fatalf("Error finding measurement %v", err)
+	}
+	if stored.Status != repository.INVALID || stored.Reason != exporttypes.INVALID_NOT_NUMERIC || res.Measurement.Reason != exporttypes.INVALID_NOT_NUMERIC {
+		t.Errorf("Expected invalid measurement - got %s '%s'", repository.StatusToText(stored.Status), stored.Reason)
+	}
+	if reasons := repo.GetReasons(repository.INVALID); reasons[exporttypes.INVALID_NOT_NUMERIC] != 1 {
+		t.Errorf("Expected invalid reason to be counted - got %v", reasons)
+	}
+	if _, _, _, _, invalid := repo.GetTotals(); invalid != 1 {
+		t.Errorf("Expected 1 invalid measurement - got %d", invalid)
+	}
+}
@@ -0,0 +1,522 @@
+package exporttypes
+
+import (
+	_ "embed"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Backends the built-in catalog restricts types to. The kihdb definitions are the layout of the shared KIH reports
+const (
+	BACKEND_KIHDB  = "kihdb"
+	BACKEND_OIOXDS = "oioxds"
+)
+
+// Kinds of catalog types
+const (
+	KIND_SIMPLE                 = "simple"
+	KIND_BLOOD_PRESSURE         = "blood_pressure"
+	KIND_CONTINUOUS_BLOOD_SUGAR = "continuous_blood_sugar"
+	KIND_URINE_COMBI            = "urine_combi"
+	KIND_SPIROMETRY             = "spirometry"
+)
+
+// Encodings of catalog type results
+const (
+	ENCODING_NUMERIC      = "numeric"
+	ENCODING_ALPHANUMERIC = "alphanumeric"
+)
+
+//go:embed catalog.yaml
+var builtinCatalog []byte
+
+var defaultCatalog = mustParseCatalog()
+
+// Catalog holds the measurement type definitions
+type Catalog struct {
+	Types    []TypeDefinition `yaml:"types"`
+	registry DeviceRegistry
+}
+
+// TypeDefinition describes how a measurement type is exported
+type TypeDefinition struct {
+	Name         string                    `yaml:"name"`
+	Backends     []string                  `yaml:"backends"`
+	Kind         string                    `yaml:"kind"`
+	NpuCode      string                    `yaml:"npu"`
+	Unit         string                    `yaml:"unit"`
+	AnalysisText string                    `yaml:"analysistext"`
+	Decimals     int                       `yaml:"decimals"`
+	Scale        float64                   `yaml:"scale"`
+	Values       map[string]string         `yaml:"values"`
+	Encoding     string                    `yaml:"encoding"`
+	Units        map[string]UnitConversion `yaml:"units"`
+	Min          *float64                  `yaml:"min"`
+	Max          *float64                  `yaml:"max"`
+	Operators    bool                      `yaml:"operators"`
+	Devices      []string                  `yaml:"devices"`
+	Export       *bool                     `yaml:"export"`
+	Systolic     *TypeDefinition           `yaml:"systolic"`
+	Diastolic    *TypeDefinition           `yaml:"diastolic"`
+	Components   []TypeDefinition          `yaml:"components"`
+	Optional     bool                      `yaml:"optional"`
+	// Circumstances maps the meal context and control measurements of simple types
+	Circumstances *CircumstanceMapping `yaml:"circumstances"`
+}
+
+func mustParseCatalog() Catalog {
+	catalog, err := ParseCatalog(builtinCatalog, defaultRegistry)
+	if err != nil {
+		panic(fmt.Sprintf("Built-in measurement type catalog is invalid - %v", err))
+	}
+	return catalog
+}
+
+// DefaultCatalog returns the built-in catalog
+func DefaultCatalog() Catalog {
+	return defaultCatalog
+}
+
+// InitCatalog loads the catalog from file, or the built-in catalog if no file is given. Devices are looked up in the registry
+func InitCatalog(file string, registry DeviceRegistry) (Catalog, error) {
+	if len(file) == 0 {
+		return ParseCatalog(builtinCatalog, registry)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return Catalog{}, errors.Wrap(err, "Error reading measurement type catalog")
+	}
+
+	catalog, err := ParseCatalog(data, registry)
+	if err != nil {
+		return Catalog{}, errors.Wrap(err, fmt.Sprintf("Error in measurement type catalog %s", file))
+	}
+	return catalog, nil
+}
+
+// ParseCatalog decodes and validates a catalog. Unknown fields are rejected
+func ParseCatalog(data []byte, registry DeviceRegistry) (Catalog, error) {
+	catalog := Catalog{registry: registry}
+	if err := yaml.UnmarshalStrict(data, &catalog); err != nil {
+		return catalog, errors.Wrap(err, "Error decoding catalog")
+	}
+	if err := catalog.validate(); err != nil {
+		return catalog, err
+	}
+	return catalog, nil
+}
+
+func (c Catalog) validate() error {
+	if len(c.Types) == 0 {
+		return fmt.Errorf("Catalog contains no types")
+	}
+
+	seen := make(map[string]bool)
+	restricted := make(map[string]bool)
+	for i, t := range c.Types {
+		if len(t.Name) == 0 {
+			return fmt.Errorf("Type %d has no name", i+1)
+		}
+		if t.Optional {
+			return fmt.Errorf("Type %s - only components can be optional", t.Name)
+		}
+		if t.Circumstances != nil {
+			if t.Kind != "" && t.Kind != KIND_SIMPLE {
+				return fmt.Errorf("Type %s - only simple types have circumstances", t.Name)
+			}
+			if err := t.Circumstances.validate(t.Name); err != nil {
+				return err
+			}
+		}
+
+		// Types without backends are shared by all backends, so a shared type cannot also be defined for a backend
+		if len(t.Backends) == 0 {
+			if seen[t.Name] || restricted[t.Name] {
+				return fmt.Errorf("Type %s is defined more than once", t.Name)
+			}
+			seen[t.Name] = true
+		}
+		for _, backend := range t.Backends {
+			if len(backend) == 0 {
+				return fmt.Errorf("Type %s - backend has no name", t.Name)
+			}
+			key := backend + "/" + t.Name
+			if seen[t.Name] || seen[key] {
+				return fmt.Errorf("Type %s is defined more than once for backend %s", t.Name, backend)
+			}
+			seen[key] = true
+			restricted[t.Name] = true
+		}
+
+		switch t.Kind {
+		case "", KIND_SIMPLE, KIND_CONTINUOUS_BLOOD_SUGAR:
+			if t.Systolic != nil || t.Diastolic != nil {
+				return fmt.Errorf("Type %s - only blood pressures have systolic and diastolic", t.Name)
+			}
+			if len(t.Components) > 0 {
+				return fmt.Errorf("Type %s - only urine combi and spirometry types have components", t.Name)
+			}
+			if err := t.validateValue(t.Name, c.registry); err != nil {
+				return err
+			}
+		case KIND_BLOOD_PRESSURE:
+			if len(t.Components) > 0 {
+				return fmt.Errorf("Type %s - only urine combi and spirometry types have components", t.Name)
+			}
+			if t.Systolic == nil || t.Diastolic == nil {
+				return fmt.Errorf("Type %s - blood pressure requires systolic and diastolic", t.Name)
+			}
+			if len(t.NpuCode) > 0 || len(t.Values) > 0 || len(t.Encoding) > 0 || len(t.Devices) > 0 || t.Min != nil || t.Max != nil {
+				return fmt.Errorf("Type %s - blood pressure is defined by systolic and diastolic", t.Name)
+			}
+			if err := validateUnits(t.Name, t.Units); err != nil {
+				return err
+			}
+			if err := t.Systolic.validateComponent(t.Name+" systolic", c.registry); err != nil {
+				return err
+			}
+			if err := t.Diastolic.validateComponent(t.Name+" diastolic", c.registry); err != nil {
+				return err
+			}
+		case KIND_URINE_COMBI:
+			if err := t.validateUrineCombi(c.registry); err != nil {
+				return err
+			}
+		case KIND_SPIROMETRY:
+			if err := t.validateSpirometry(c.registry); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Type %s - unknown kind %s", t.Name, t.Kind)
+		}
+	}
+	return nil
+}
+
+// validateUrineCombi requires named analytes, each defined like a simple type
+func (t TypeDefinition) validateUrineCombi(registry DeviceRegistry) error {
+	if t.Systolic != nil || t.Diastolic != nil {
+		return fmt.Errorf("Type %s - only blood pressures have systolic and diastolic", t.Name)
+	}
+	if len(t.NpuCode) > 0 || len(t.Values) > 0 || len(t.Encoding) > 0 || len(t.Devices) > 0 || len(t.Units) > 0 || t.Min != nil || t.Max != nil {
+		return fmt.Errorf("Type %s - urine combi is defined by its components", t.Name)
+	}
+	if len(t.Components) == 0 {
+		return fmt.Errorf("Type %s - urine combi requires components", t.Name)
+	}
+	return t.validateComponents(registry, false)
+}
+
+// validateSpirometry requires named values, each defined like a simple type. The devices are shared by the values
+func (t TypeDefinition) validateSpirometry(registry DeviceRegistry) error {
+	if t.Systolic != nil || t.Diastolic != nil {
+		return fmt.Errorf("Type %s - only blood pressures have systolic and diastolic", t.Name)
+	}
+	if len(t.NpuCode) > 0 || len(t.Values) > 0 || len(t.Encoding) > 0 || len(t.Units) > 0 || t.Min != nil || t.Max != nil {
+		return fmt.Errorf("Type %s - spirometry is defined by its components", t.Name)
+	}
+	if len(t.Components) == 0 {
+		return fmt.Errorf("Type %s - spirometry requires components", t.Name)
+	}
+	for _, id := range t.Devices {
+		if _, ok := registry.Device(id); !ok {
+			return fmt.Errorf("Type %s - unknown device %s", t.Name, id)
+		}
+	}
+
+	required := false
+	for _, component := range t.Components {
+		if len(component.Devices) > 0 {
+			return fmt.Errorf("Type %s - devices are defined for the spirometry, not for %s", t.Name, component.Name)
+		}
+		if !component.Optional {
+			required = true
+		}
+	}
+	if !required {
+		return fmt.Errorf("Type %s - spirometry requires a component that is not optional", t.Name)
+	}
+	return t.validateComponents(registry, true)
+}
+
+// validateComponents requires the components to be uniquely named and defined like simple types without units
+func (t TypeDefinition) validateComponents(registry DeviceRegistry, optional bool) error {
+	seen := make(map[string]bool)
+	for _, component := range t.Components {
+		if len(component.Name) == 0 {
+			return fmt.Errorf("Type %s - component has no name", t.Name)
+		}
+		if seen[component.Name] {
+			return fmt.Errorf("Type %s - component %s is defined more than once", t.Name, component.Name)
+		}
+		seen[component.Name] = true
+
+		if len(component.Backends) > 0 || component.Export != nil || component.Circumstances != nil || len(component.Kind) > 0 || component.Systolic != nil || component.Diastolic != nil || len(component.Components) > 0 {
+			return fmt.Errorf("Type %s - components only define the value", t.Name)
+		}
+		if len(component.Units) > 0 {
+			return fmt.Errorf("Type %s - components cannot have units", t.Name)
+		}
+		if component.Optional && !optional {
+			return fmt.Errorf("Type %s - component %s cannot be optional", t.Name, component.Name)
+		}
+		if err := component.validateValue(t.Name+" "+component.Name, registry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t TypeDefinition) validateComponent(name string, registry DeviceRegistry) error {
+	if len(t.Name) > 0 || len(t.Backends) > 0 || len(t.Kind) > 0 || t.Optional || t.Circumstances != nil || t.Systolic != nil || t.Diastolic != nil || len(t.Components) > 0 {
+		return fmt.Errorf("Type %s - components only define the value", name)
+	}
+	if len(t.Values) > 0 || len(t.Units) > 0 {
+		return fmt.Errorf("Type %s - components cannot have enumerated values or units", name)
+	}
+	return t.validateValue(name, registry)
+}
+
+func (t TypeDefinition) validateValue(name string, registry DeviceRegistry) error {
+	if len(t.NpuCode) == 0 && t.isToBeExported() {
+		return fmt.Errorf("Type %s has no NPU code", name)
+	}
+	if len(t.AnalysisText) == 0 {
+		return fmt.Errorf("Type %s has no analysis text", name)
+	}
+	if t.Decimals < 0 {
+		return fmt.Errorf("Type %s - decimals cannot be negative", name)
+	}
+	if t.Scale < 0 {
+		return fmt.Errorf("Type %s - scale cannot be negative", name)
+	}
+	if len(t.Values) > 0 && (t.Scale != 0 || t.Decimals != 0) {
+		return fmt.Errorf("Type %s - enumerated values cannot be scaled", name)
+	}
+	for _, id := range t.Devices {
+		if _, ok := registry.Device(id); !ok {
+			return fmt.Errorf("Type %s - unknown device %s", name, id)
+		}
+	}
+	if len(t.Values) > 0 && len(t.Units) > 0 {
+		return fmt.Errorf("Type %s - enumerated values cannot be converted", name)
+	}
+	if len(t.Values) > 0 && (t.Min != nil || t.Max != nil) {
+		return fmt.Errorf("Type %s - enumerated values cannot have a range", name)
+	}
+	if len(t.Values) > 0 && t.Operators {
+		return fmt.Errorf("Type %s - enumerated values cannot have operators", name)
+	}
+	switch t.Encoding {
+	case "", ENCODING_NUMERIC:
+		for value, result := range t.Values {
+			if _, err := strconv.ParseFloat(result, 64); err != nil {
+				return fmt.Errorf("Type %s - result %s of %s is not numeric", name, result, value)
+			}
+		}
+	case ENCODING_ALPHANUMERIC:
+		if len(t.Values) == 0 {
+			return fmt.Errorf("Type %s - only enumerated values can be alphanumeric", name)
+		}
+	default:
+		return fmt.Errorf("Type %s - unknown encoding %s", name, t.Encoding)
+	}
+	if t.Min != nil && t.Max != nil && *t.Min > *t.Max {
+		return fmt.Errorf("Type %s - min is above max", name)
+	}
+	return validateUnits(name, t.Units)
+}
+
+// validateUnits requires the units to be unique ignoring case, and at least one of them to be canonical
+func validateUnits(name string, units map[string]UnitConversion) error {
+	if len(units) == 0 {
+		return nil
+	}
+
+	canonical := false
+	seen := make(map[string]bool)
+	for unit, conversion := range units {
+		if len(unitName(unit)) == 0 {
+			return fmt.Errorf("Type %s - empty unit", name)
+		}
+		if seen[unitName(unit)] {
+			return fmt.Errorf("Type %s - unit %s is defined more than once", name, unit)
+		}
+		seen[unitName(unit)] = true
+		if conversion.Factor < 0 {
+			return fmt.Errorf("Type %s - factor for %s cannot be negative", name, unit)
+		}
+		if conversion.isIdentity() {
+			canonical = true
+		}
+	}
+	if !canonical {
+		return fmt.Errorf("Type %s - no canonical unit", name)
+	}
+	return nil
+}
+
+// ExportTypes returns the types used by the backend
+func (c Catalog) ExportTypes(backend string) map[string]MeasurementType {
+	types := make(map[string]MeasurementType)
+	for _, t := range c.Types {
+		if !t.usedBy(backend) {
+			continue
+		}
+		types[t.Name] = t.measurementType(c.registry)
+	}
+	return types
+}
+
+// simpleType returns the named type for the backend as a simple type
+func (c Catalog) simpleType(backend, name string) SimpleType {
+	for _, t := range c.Types {
+		if t.Name == name && t.usedBy(backend) {
+			return t.newSimpleType(c.registry, layoutValue)
+		}
+	}
+	panic(fmt.Sprintf("Type %s is not in the catalog", name))
+}
+
+// hasBackend tells whether the catalog restricts any type to the backend
+func (c Catalog) hasBackend(backend string) bool {
+	for _, t := range c.Types {
+		for _, b := range t.Backends {
+			if b == backend {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t TypeDefinition) usedBy(backend string) bool {
+	if len(t.Backends) == 0 {
+		return true
+	}
+	for _, b := range t.Backends {
+		if b == backend {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TypeDefinition) measurementType(registry DeviceRegistry) MeasurementType {
+	switch t.Kind {
+	case KIND_BLOOD_PRESSURE:
+		bp := CompositeType{isToBeExported: t.isToBeExported(), units: newUnitTable(t.Units)}
+		bp.addComponent(registry, measurement.COMPONENT_SYSTOLIC, false, bloodPressureValue(measurement.COMPONENT_SYSTOLIC), *t.Systolic)
+		bp.addComponent(registry, measurement.COMPONENT_DIASTOLIC, false, bloodPressureValue(measurement.COMPONENT_DIASTOLIC), *t.Diastolic)
+		return bp
+	case KIND_CONTINUOUS_BLOOD_SUGAR:
+		return ContinuousBloodSugarType{glucose: t.newSimpleType(registry, layoutValue), interval: CONTINUOUS_BLOOD_SUGAR_INTERVAL}
+	case KIND_URINE_COMBI:
+		// Analytes left out were not measured
+		urine := CompositeType{isToBeExported: t.isToBeExported()}
+		for _, c := range t.Components {
+			urine.addComponent(registry, c.Name, true, componentValue(c.Name), c)
+		}
+		return urine
+	case KIND_SPIROMETRY:
+		spirometry := CompositeType{isToBeExported: t.isToBeExported(), devices: t.devices(registry)}
+		for _, c := range t.Components {
+			spirometry.addComponent(registry, c.Name, c.Optional, componentValue(c.Name), c)
+		}
+		return spirometry
+	default:
+		return t.newSimpleType(registry, layoutValue)
+	}
+}
+
+// addComponent adds a component defined like a simple type. The devices of the component are matched for the whole measurement
+func (c *CompositeType) addComponent(registry DeviceRegistry, name string, optional bool, value func(m measurement.Measurement) measurement.Value, t TypeDefinition) {
+	component := CompositeComponent{name: name, optional: optional, value: value, export: t.newSimpleType(registry, value)}
+	if c.devices == nil {
+		c.devices = []MedicalDevice{}
+	}
+	c.devices = append(c.devices, component.export.GetDevices()...)
+	c.components = append(c.components, component)
+}
+
+func (t TypeDefinition) devices(registry DeviceRegistry) []MedicalDevice {
+	devices := []MedicalDevice{}
+	for _, id := range t.Devices {
+		d, _ := registry.Device(id)
+		devices = append(devices, d)
+	}
+	return devices
+}
+
+func (t TypeDefinition) isToBeExported() bool {
+	return t.Export == nil || *t.Export
+}
+
+func layoutValue(m measurement.Measurement) measurement.Value {
+	return m.Measurement.Value
+}
+
+// componentValue returns the named component of a composite measurement, e.g. the systolic pressure or an analyte
+func componentValue(name string) func(m measurement.Measurement) measurement.Value {
+	return func(m measurement.Measurement) measurement.Value {
+		v, _ := m.Measurement.Composite().Component(name)
+		return v
+	}
+}
+
+// bloodPressureValue returns the named component of a blood pressure. Clinician sends a missing component as 0
+func bloodPressureValue(name string) func(m measurement.Measurement) measurement.Value {
+	component := componentValue(name)
+	return func(m measurement.Measurement) measurement.Value {
+		v := component(m)
+		if n, err := v.Float(); err == nil && n == 0 {
+			return measurement.Value{}
+		}
+		return v
+	}
+}
+
+func (t TypeDefinition) newSimpleType(registry DeviceRegistry, value func(m measurement.Measurement) measurement.Value) SimpleType {
+	s := SimpleType{}
+	s.npuCode = t.NpuCode
+	s.isToBeExported = t.isToBeExported()
+	s.resultUnitText = t.Unit
+	s.analysisText = t.AnalysisText
+	s.decimal = t.Decimals
+	s.isAlphaNumeric = t.Encoding == ENCODING_ALPHANUMERIC
+	s.units = newUnitTable(t.Units)
+	s.rules = valueRules{min: t.Min, max: t.Max, values: t.Values, operators: t.Operators}
+	s.value = value
+	s.circumstances = t.Circumstances
+
+	s.devices = t.devices(registry)
+
+	if len(t.Values) > 0 {
+		values := t.Values
+		s.layoutResults = func(m measurement.Measurement) string {
+			return values[value(m).String()]
+		}
+		return s
+	}
+
+	scale := t.Scale
+	if scale == 0 {
+		scale = 1
+	}
+	decimals := t.Decimals
+	operators := t.Operators
+	s.layoutResults = func(m measurement.Measurement) string {
+		v := value(m)
+		if operators {
+			_, v = splitOperator(v)
+		}
+		return layoutNumber(v, scale, decimals)
+	}
+	return s
+}
@@ -0,0 +1,180 @@
+package exporttypes
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+func TestBuiltinCatalog(t *testing.T) {
+	kihdb := DefaultCatalog().ExportTypes(BACKEND_KIHDB)
+	oioxds := DefaultCatalog().ExportTypes(BACKEND_OIOXDS)
+
+	if len(kihdb) != 21 || len(oioxds) != 22 {
+		t.Fatalf("Unexpected number of types - kihdb: %d oioxds: %d", len(kihdb), len(oioxds))
+	}
+	if _, ok := kihdb[TYPE_NAME_RESPIRATORY_RATE]; ok {
+		t.Errorf("Respiratory rate is only exported to OIO XDS")
+	}
+	if kihdb[TYPE_NAME_PULSE].GetResultUnitText() != "x 1/min" || oioxds[TYPE_NAME_PULSE].GetResultUnitText() != "1/min" {
+		t.Errorf("Expected pulse to be defined per backend")
+	}
+	if kihdb[TYPE_NAME_FEV1_FEV6_RATIO].IsToBeExported() {
+		t.Errorf("Expected FEV1/FEV6 not to be exported")
+	}
+	if len(kihdb[TYPE_NAME_BLOOD_PRESSURE].GetDevices()) != 4 {
+		t.Errorf("Expected devices for systolic and diastolic")
+	}
+	if devices := kihdb[TYPE_NAME_SPIROMETER].GetDevices(); len(devices) != 1 || devices[0].GetMedcomID() != "MCI00014" {
+		t.Errorf("Expected the lung monitor for spirometry")
+	}
+
+	tests := []struct {
+		name   string
+		value  interface{}
+		result string
+	}{
+		{TYPE_NAME_WEIGHT, 80.06, "80.1"},
+		{TYPE_NAME_SATURATION, 97, "0.97"},
+		{TYPE_NAME_CRP, "12", "12"},
+		{TYPE_NAME_FEV1, 2.5, "2.50"},
+		{TYPE_NAME_URINE_PROTEIN, "+/-", "0"},
+		{TYPE_NAME_URINE_GLUCOSE, "+4", "3"},
+		{TYPE_NAME_URINE_NITRITE, "Pos.", "1"},
+		{TYPE_NAME_URINE_BLOOD, "+2", "2"},
+		{TYPE_NAME_URINE_LEUKOCYTES, "Unknown", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := measurement.Measurement{Type: tt.name}
+			m.Measurement.Value = measurement.ValueOf(tt.value)
+			if res := kihdb[tt.name].GetResultText(m); res != tt.result {
+				t.Errorf("Expected '%s' got '%s'", tt.result, res)
+			}
+		})
+	}
+
+	m := measurement.Measurement{}
+	m.Measurement.Systolic = 120
+	m.Measurement.Diastolic = 80
+	bp := kihdb[TYPE_NAME_BLOOD_PRESSURE].(CompositeType)
+	systolic, _ := bp.GetComponent(measurement.COMPONENT_SYSTOLIC)
+	diastolic, _ := bp.GetComponent(measurement.COMPONENT_DIASTOLIC)
+	if systolic.GetType().GetResultText(m) != "120" || diastolic.GetType().GetResultText(m) != "80" {
+		t.Errorf("Unexpected blood pressure results")
+	}
+}
+
+func TestResultEncoding(t *testing.T) {
+	types := GetOioXdsExportTypes()
+
+	tests := []struct {
+		name         string
+		value        measurement.Value
+		alphaNumeric bool
+		operator     string
+		result       string
+	}{
+		{TYPE_NAME_BLOODSUGAR, measurement.NumericValue(6.2), false, "", "6.2"},
+		{TYPE_NAME_BLOODSUGAR, measurement.EnumeratedValue("<1.1"), false, RESULT_OPERATOR_LESS_THAN, "1.1"},
+		{TYPE_NAME_CRP, measurement.EnumeratedValue(">200"), false, RESULT_OPERATOR_GREATER_THAN, "200"},
+		{TYPE_NAME_URINE_PROTEIN, measurement.EnumeratedValue("+1"), false, "", "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.value.String(), func(t *testing.T) {
+			s := types[tt.name].(SimpleType)
+			m := measurement.Measurement{Type: tt.name, Measurement: measurement.MeasurementValue{Value: tt.value}}
+			if s.IsAlphaNumeric() != tt.alphaNumeric || s.GetResultOperator(m) != tt.operator || s.GetResultText(m) != tt.result {
+				t.Errorf("Expected %v '%s' '%s' got %v '%s' '%s'", tt.alphaNumeric, tt.operator, tt.result, s.IsAlphaNumeric(), s.GetResultOperator(m), s.GetResultText(m))
+			}
+		})
+	}
+}
+
+func TestParseCatalog(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog string
+		err     string
+	}{
+		{"Valid", "types:\n  - name: weight\n    npu: NPU03804\n    unit: kg\n    analysistext: Pt—Legeme; masse = ? kg\n    decimals: 1\n    devices: [MCI00002]\n", ""},
+		{"Empty", "types: []\n", "no types"},
+		{"Unknown field", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    colour: red\n", "colour"},
+		{"Missing NPU", "types:\n  - name: weight\n    analysistext: text\n", "no NPU code"},
+		{"Missing NPU not exported", "types:\n  - name: pain_scale\n    analysistext: text\n    export: false\n", ""},
+		{"Unknown device", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    devices: [MCI99999]\n", "unknown device"},
+		{"Other backend", "types:\n  - name: weight\n    backends: [fhir]\n    npu: NPU03804\n    analysistext: text\n", ""},
+		{"Duplicate for backend", "types:\n  - name: weight\n    backends: [fhir]\n    npu: NPU03804\n    analysistext: text\n  - name: weight\n    backends: [oioxds, fhir]\n    npu: NPU03804\n    analysistext: text\n", "more than once"},
+		{"Unknown kind", "types:\n  - name: weight\n    kind: complex\n    npu: NPU03804\n    analysistext: text\n", "unknown kind"},
+		{"Duplicate", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n  - name: weight\n    backends: [oioxds]\n    npu: NPU03804\n    analysistext: text\n", "more than once"},
+		{"Scaled values", "types:\n  - name: nitrite_in_urine\n    npu: NPU21578\n    analysistext: text\n    scale: 2\n    values: {\"Neg.\": \"0\"}\n", "cannot be scaled"},
+		{"No canonical unit", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    units:\n      lbs: {factor: 0.45}\n", "no canonical unit"},
+		{"Duplicate unit", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    units:\n      kg: {}\n      KG: {}\n", "more than once"},
+		{"Text values numeric", "types:\n  - name: nitrite_in_urine\n    npu: NPU21578\n    analysistext: text\n    values: {\"Neg.\": \"Negativ\"}\n", "not numeric"},
+		{"Text values alphanumeric", "types:\n  - name: nitrite_in_urine\n    npu: NPU21578\n    analysistext: text\n    encoding: alphanumeric\n    values: {\"Neg.\": \"Negativ\"}\n", ""},
+		{"Alphanumeric number", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    encoding: alphanumeric\n", "only enumerated values"},
+		{"Unknown encoding", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    encoding: binary\n", "unknown encoding"},
+		{"Range on values", "types:\n  - name: nitrite_in_urine\n    npu: NPU21578\n    analysistext: text\n    max: 1\n    values: {\"Neg.\": \"0\"}\n", "cannot have a range"},
+		{"Min above max", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    min: 400\n    max: 1\n", "min is above max"},
+		{"Urine combi without components", "types:\n  - name: urine_measurement\n    kind: urine_combi\n", "requires components"},
+		{"Urine combi duplicate component", "types:\n  - name: urine_measurement\n    kind: urine_combi\n    components:\n      - {name: protein, npu: NPU04206, analysistext: text}\n      - {name: protein, npu: NPU04206, analysistext: text}\n", "more than once"},
+		{"Spirometry without components", "types:\n  - name: spirometry\n    kind: spirometry\n", "requires components"},
+		{"Spirometry only optional components", "types:\n  - name: spirometry\n    kind: spirometry\n    components:\n      - {name: pef, npu: MCS88016, analysistext: text, optional: true}\n", "not optional"},
+		{"Spirometry component devices", "types:\n  - name: spirometry\n    kind: spirometry\n    components:\n      - {name: fev1, npu: MCS88015, analysistext: text, devices: [MCI00014]}\n", "devices are defined for the spirometry"},
+		{"Optional urine component", "types:\n  - name: urine_measurement\n    kind: urine_combi\n    components:\n      - {name: protein, npu: NPU04206, analysistext: text, optional: true}\n", "cannot be optional"},
+		{"Optional type", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    optional: true\n", "only components can be optional"},
+		{"Circumstances on blood pressure", "types:\n  - name: blood_pressure\n    kind: blood_pressure\n    circumstances: {beforemeal: before}\n", "only simple types have circumstances"},
+		{"Unknown control handling", "types:\n  - name: bloodsugar\n    npu: NPU22089\n    analysistext: text\n    circumstances: {control: ignore}\n", "unknown handling of control measurements"},
+		{"Flagged control without classification", "types:\n  - name: bloodsugar\n    npu: NPU22089\n    analysistext: text\n    circumstances: {control: flag}\n", "require a classification"},
+		{"Excluded control with classification", "types:\n  - name: bloodsugar\n    npu: NPU22089\n    analysistext: text\n    circumstances: {classification: control}\n", "only used for flagged"},
+		{"Operators on enumerated values", "types:\n  - name: nitrite_in_urine\n    npu: NPU21578\n    analysistext: text\n    operators: true\n    values: {Pos.: \"1\"}\n", "cannot have operators"},
+		{"Components on simple type", "types:\n  - name: weight\n    npu: NPU03804\n    analysistext: text\n    components:\n      - {name: protein, npu: NPU04206, analysistext: text}\n", "only urine combi"},
+		{"Blood pressure without diastolic", "types:\n  - name: blood_pressure\n    kind: blood_pressure\n    systolic: {npu: DNK05472, analysistext: text}\n", "systolic and diastolic"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseCatalog([]byte(tt.catalog), DefaultDeviceRegistry())
+			if len(tt.err) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error - %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error containing '%s' got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestInitCatalog(t *testing.T) {
+	catalog, err := InitCatalog("", DefaultDeviceRegistry())
+	if err != nil || len(catalog.Types) != len(DefaultCatalog().Types) {
+		t.Fatalf("Expected the built-in catalog - %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "catalog.yaml")
+	if err := ioutil.WriteFile(file, []byte("types:\n  - name: crp\n    npu: NPU19748\n    unit: mg/dL\n    analysistext: P—C-reaktivt protein; massek. = ? mg/dL\n    decimals: 1\n    scale: 0.1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err = InitCatalog(file, DefaultDeviceRegistry())
+	if err != nil {
+		t.Fatalf("Error loading catalog - %v", err)
+	}
+	types := catalog.ExportTypes(BACKEND_OIOXDS)
+	m := measurement.Measurement{}
+	m.Measurement.Value = measurement.NumericValue(12)
+	if len(types) != 1 || types[TYPE_NAME_CRP].GetResultText(m) != "1.2" {
+		t.Errorf("Unexpected types from catalog %v", types)
+	}
+
+	if _, err := InitCatalog(filepath.Join(t.TempDir(), "missing.yaml"), DefaultDeviceRegistry()); err == nil {
+		t.Errorf("Expected error for missing catalog")
+	}
+}
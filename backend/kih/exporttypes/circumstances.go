@@ -0,0 +1,75 @@
+package exporttypes
+
+import (
+	"fmt"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// Handling of control measurements, e.g. a blood sugar meter measuring control solution
+const (
+	CONTROL_EXCLUDE = "exclude"
+	CONTROL_FLAG    = "flag"
+)
+
+// CircumstanceMapping maps the context of a measurement to the MedCom values of the report
+type CircumstanceMapping struct {
+	// BeforeMeal and AfterMeal are the measuring circumstances of measurements taken before or after a meal
+	BeforeMeal string `yaml:"beforemeal"`
+	AfterMeal  string `yaml:"aftermeal"`
+	// Control is exclude (default) or flag. Flagged control measurements are exported with the classification
+	Control        string `yaml:"control"`
+	Classification string `yaml:"classification"`
+}
+
+func (c CircumstanceMapping) validate(name string) error {
+	switch c.Control {
+	case "", CONTROL_EXCLUDE:
+		if len(c.Classification) > 0 {
+			return fmt.Errorf("Type %s - classification is only used for flagged control measurements", name)
+		}
+	case CONTROL_FLAG:
+		if len(c.Classification) == 0 {
+			return fmt.Errorf("Type %s - flagged control measurements require a classification", name)
+		}
+	default:
+		return fmt.Errorf("Type %s - unknown handling of control measurements %s", name, c.Control)
+	}
+	return nil
+}
+
+// ControlMeasurementError is returned for control measurements of types excluding them
+type ControlMeasurementError struct {
+	Type string
+}
+
+func (e ControlMeasurementError) Error() string {
+	return fmt.Sprintf("Control measurement of %s is not exported", e.Type)
+}
+
+// Circumstances returns the measuring circumstances and classification of a measurement. Types without a mapping
+// have neither. A measurement both before and after a meal is ambiguous, and gets no circumstances
+func Circumstances(t MeasurementType, m measurement.Measurement) (string, string, error) {
+	s, ok := t.(SimpleType)
+	if !ok || s.circumstances == nil {
+		return "", "", nil
+	}
+	mapping := s.circumstances
+
+	classification := ""
+	if m.Measurement.IsControlMeasurement {
+		if mapping.Control != CONTROL_FLAG {
+			return "", "", ControlMeasurementError{Type: m.Type}
+		}
+		classification = mapping.Classification
+	}
+
+	circumstances := ""
+	switch {
+	case m.Measurement.IsBeforeMeal && !m.Measurement.IsAfterMeal:
+		circumstances = mapping.BeforeMeal
+	case m.Measurement.IsAfterMeal && !m.Measurement.IsBeforeMeal:
+		circumstances = mapping.AfterMeal
+	}
+	return circumstances, classification, nil
+}
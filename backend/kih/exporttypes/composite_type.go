@@ -0,0 +1,99 @@
+package exporttypes
+
+import (
+	"strings"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// CompositeComponent is a value of a composite measurement, e.g. the systolic pressure of a blood pressure or an
+// analyte of a urine dipstick. It is reported on its own with the code, unit and layout of its type
+type CompositeComponent struct {
+	name     string
+	optional bool
+	value    func(m measurement.Measurement) measurement.Value
+	export   SimpleType
+}
+
+// GetName returns the name of the component in the measurement value
+func (c CompositeComponent) GetName() string {
+	return c.name
+}
+
+// IsOptional reports whether the measurement is valid without the component
+func (c CompositeComponent) IsOptional() bool {
+	return c.optional
+}
+
+// GetType returns the export type of the component
+func (c CompositeComponent) GetType() SimpleType {
+	return c.export
+}
+
+// GetValue returns the value of the component in the measurement
+func (c CompositeComponent) GetValue(m measurement.Measurement) measurement.Value {
+	return c.value(m)
+}
+
+// CompositeType is a measurement composed of several values, each reported on its own. The reports share the time of
+// the measurement and the instrument, which is matched against the devices of the type
+type CompositeType struct {
+	components     []CompositeComponent
+	devices        []MedicalDevice
+	isToBeExported bool
+	units          unitTable
+}
+
+func (c CompositeType) GetDevices() []MedicalDevice {
+	return c.devices
+}
+
+func (c CompositeType) IsToBeExported() bool {
+	return c.isToBeExported
+}
+func (c CompositeType) GetAnalysisText() string {
+	return ""
+}
+func (c CompositeType) GetResultUnitText() string {
+	return ""
+}
+func (c CompositeType) GetResultText(m measurement.Measurement) string {
+	return ""
+}
+func (c CompositeType) GetNpuCode() string {
+	var codes []string
+	for _, component := range c.components {
+		codes = append(codes, component.export.GetNpuCode())
+	}
+	return strings.Join(codes, ",")
+}
+
+// GetComponents returns the components in the order they are reported
+func (c CompositeType) GetComponents() []CompositeComponent {
+	return c.components
+}
+
+// GetComponent returns the named component
+func (c CompositeType) GetComponent(name string) (CompositeComponent, bool) {
+	for _, component := range c.components {
+		if component.name == name {
+			return component, true
+		}
+	}
+	return CompositeComponent{}, false
+}
+
+// GetMeasured returns the components present in the measurement, in the order they are reported
+func (c CompositeType) GetMeasured(m measurement.Measurement) []CompositeComponent {
+	var measured []CompositeComponent
+	for _, component := range c.components {
+		if !component.value(m).IsEmpty() {
+			measured = append(measured, component)
+		}
+	}
+	return measured
+}
+
+func NewBloodPressureType() CompositeType {
+	return defaultCatalog.ExportTypes(BACKEND_KIHDB)[TYPE_NAME_BLOOD_PRESSURE].(CompositeType)
+}
@@ -0,0 +1,58 @@
+package exporttypes
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// Length of the intervals a continuous blood sugar series is reported in
+const CONTINUOUS_BLOOD_SUGAR_INTERVAL = time.Hour
+
+// ContinuousBloodSugarType handles CGM series. A series is exported as a summary report for the whole series and a report per interval
+type ContinuousBloodSugarType struct {
+	glucose  SimpleType
+	interval time.Duration
+}
+
+func (c ContinuousBloodSugarType) GetDevices() []MedicalDevice {
+	return c.glucose.GetDevices()
+}
+
+func (c ContinuousBloodSugarType) IsToBeExported() bool {
+	return c.glucose.IsToBeExported()
+}
+func (c ContinuousBloodSugarType) GetAnalysisText() string {
+	return c.glucose.GetAnalysisText()
+}
+func (c ContinuousBloodSugarType) GetResultUnitText() string {
+	return c.glucose.GetResultUnitText()
+}
+
+// GetResultText returns the mean of the series
+func (c ContinuousBloodSugarType) GetResultText(m measurement.Measurement) string {
+	return c.FormatResult(measurement.Summarise(m.Measurement.Series).Mean)
+}
+func (c ContinuousBloodSugarType) GetNpuCode() string {
+	return c.glucose.GetNpuCode()
+}
+
+// FormatResult lays out a value with the decimals of the type
+func (c ContinuousBloodSugarType) FormatResult(value float64) string {
+	return strconv.FormatFloat(value, 'f', c.glucose.decimal, 64)
+}
+
+// GetSummary summarises the full series
+func (c ContinuousBloodSugarType) GetSummary(m measurement.Measurement) measurement.SeriesSummary {
+	return measurement.Summarise(m.Measurement.Series)
+}
+
+// GetIntervals summarises the series per interval
+func (c ContinuousBloodSugarType) GetIntervals(m measurement.Measurement) []measurement.SeriesSummary {
+	return measurement.SummariseIntervals(m.Measurement.Series, c.interval)
+}
+
+func NewContinuousBloodSugar() ContinuousBloodSugarType {
+	return defaultCatalog.ExportTypes(BACKEND_KIHDB)[TYPE_NAME_CONTINUOUS_BLOOD_SUGAR_MEASUREMENT].(ContinuousBloodSugarType)
+}
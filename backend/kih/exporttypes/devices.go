@@ -1,175 +1,326 @@
 package exporttypes
 
-// currently, this is a just a hard code list. The main drawback with that is, that it requires a release to handle that mapping.
-// Perhaps it would be prudent to make it more dynamically. One way was to do to create a dirrectory with a file per measuerement type
-//
-// Eash file would contain a list of debice. for each device we could specificy the following:
-// - medcom id
-// - manufacturer
-// - model
-// - hardwareversion
-// - softwareversion
-// - fimrwareversion
-
-// Then new devices can be added on the fly. The files can be parsed some what easier and get build into a decent structure to search in
+// Devices are defined in a registry. The built-in registry holds the devices on the MedCom whitelist, and new devices can be
+// added by supplying a registry file. Each device has rules matched against the device data of a measurement
 
 import (
+	_ "embed"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
 )
 
-func NewAnDMedical302PBTExportType() simpleDevice {
-	d := simpleDevice{}
+//go:embed devices.yaml
+var builtinDevices []byte
 
-	d.medComId = "MCI00010"
-	d.manufacturer = "A&D Medical"
-	d.productType = PRODUCT_TYPE_THERMOMETER
-	d.model = "UT-302PlusBT Bluetooth"
+var defaultRegistry = mustParseDeviceRegistry()
 
-	d.compareFunction = func(origin measurement.Origin) bool {
-		if strings.EqualFold(d.manufacturer, origin.DeviceMeasurement.Manufacturer) {
-			if strings.Contains(strings.ToLower(origin.DeviceMeasurement.Model), "302") {
-				return true
-			}
-		}
-		return false
+// DeviceRegistry holds the known devices by MedCom id
+type DeviceRegistry struct {
+	devices map[string]simpleDevice
+}
+
+type deviceFile struct {
+	Devices []DeviceDefinition `yaml:"devices"`
+}
+
+// DeviceDefinition describes a device in the registry
+type DeviceDefinition struct {
+	MedComID     string      `yaml:"medcomid"`
+	Manufacturer string      `yaml:"manufacturer"`
+	Model        string      `yaml:"model"`
+	ProductType  string      `yaml:"producttype"`
+	Priority     int         `yaml:"priority"`
+	Match        DeviceMatch `yaml:"match"`
+}
+
+// DeviceMatch holds the rules for the device data. Rules left out match anything
+type DeviceMatch struct {
+	Manufacturer *MatchRule `yaml:"manufacturer"`
+	Model        *MatchRule `yaml:"model"`
+	Hardware     *MatchRule `yaml:"hardware"`
+	Firmware     *MatchRule `yaml:"firmware"`
+	Software     *MatchRule `yaml:"software"`
+}
+
+// MatchRule matches a value exactly, by regular expression or by version range. Exact and regular expression ignores case
+type MatchRule struct {
+	Exact string `yaml:"exact"`
+	Regex string `yaml:"regex"`
+	Min   string `yaml:"min"`
+	Max   string `yaml:"max"`
+}
+
+func mustParseDeviceRegistry() DeviceRegistry {
+	registry, err := ParseDeviceRegistry(builtinDevices)
+	if err != nil {
+		panic(fmt.Sprintf("Built-in device registry is invalid - %v", err))
 	}
-	return d
+	return registry
 }
 
-func NewAnDMedical321PBTCExportType() simpleDevice {
-	d := simpleDevice{}
+// DefaultDeviceRegistry returns the built-in registry
+func DefaultDeviceRegistry() DeviceRegistry {
+	return defaultRegistry
+}
 
-	d.medComId = "MCI00002"
-	d.manufacturer = "A&D Medical"
-	d.productType = PRODUCT_TYPE_WEIGHT
-	d.model = "UC-321PlusBT-C Bluetooth"
+// InitDeviceRegistry loads the registry from file, or returns the built-in registry if no file is given
+func InitDeviceRegistry(file string) (DeviceRegistry, error) {
+	if len(file) == 0 {
+		return defaultRegistry, nil
+	}
 
-	d.compareFunction = func(origin measurement.Origin) bool {
-		if strings.EqualFold(d.manufacturer, origin.DeviceMeasurement.Manufacturer) {
-			if strings.Contains(strings.ToLower(origin.DeviceMeasurement.Model), "321") {
-				return true
-			}
-		}
-		return false
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return DeviceRegistry{}, errors.Wrap(err, "Error reading device registry")
 	}
-	return d
+
+	registry, err := ParseDeviceRegistry(data)
+	if err != nil {
+		return DeviceRegistry{}, errors.Wrap(err, fmt.Sprintf("Error in device registry %s", file))
+	}
+	return registry, nil
 }
 
-func NewAnDMedical351PBTCiExportType() simpleDevice {
-	d := simpleDevice{}
-	d.medComId = "MCI00011"
-	d.productType = PRODUCT_TYPE_WEIGHT
-	d.manufacturer = "A&D Medical"
-	d.model = "UC-351PlusBT-Ci Bluetooth"
-
-	d.compareFunction = func(origin measurement.Origin) bool {
-		if strings.EqualFold(d.manufacturer, origin.DeviceMeasurement.Manufacturer) {
-			if strings.Contains(strings.ToLower(origin.DeviceMeasurement.Model), "351") {
-				return true
-			}
-		}
-		return false
+// ParseDeviceRegistry decodes and validates a registry. Unknown fields are rejected
+func ParseDeviceRegistry(data []byte) (DeviceRegistry, error) {
+	var file deviceFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return DeviceRegistry{}, errors.Wrap(err, "Error decoding device registry")
 	}
 
-	return d
+	registry := DeviceRegistry{devices: make(map[string]simpleDevice)}
+	for i, def := range file.Devices {
+		if len(def.MedComID) == 0 {
+			return registry, fmt.Errorf("Device %d has no MedCom id", i+1)
+		}
+		if _, ok := registry.devices[def.MedComID]; ok {
+			return registry, fmt.Errorf("Device %s is defined more than once", def.MedComID)
+		}
+		if len(def.Manufacturer) == 0 || len(def.Model) == 0 {
+			return registry, fmt.Errorf("Device %s requires manufacturer and model", def.MedComID)
+		}
+
+		compare, err := def.Match.compile()
+		if err != nil {
+			return registry, errors.Wrap(err, fmt.Sprintf("Device %s", def.MedComID))
+		}
+
+		registry.devices[def.MedComID] = simpleDevice{
+			medComId:        def.MedComID,
+			manufacturer:    def.Manufacturer,
+			model:           def.Model,
+			productType:     def.ProductType,
+			priority:        def.Priority,
+			compareFunction: compare,
+		}
+	}
+	return registry, nil
 }
 
-func NewAnDMedical767PBTCExportType() simpleDevice {
+// Device returns the device with the MedCom id
+func (r DeviceRegistry) Device(medComID string) (MedicalDevice, bool) {
+	d, ok := r.devices[medComID]
+	return d, ok
+}
 
-	d := simpleDevice{}
+func (m DeviceMatch) compile() (func(origin measurement.Origin) bool, error) {
+	type field struct {
+		name  string
+		rule  *MatchRule
+		value func(dm measurement.DeviceMeasurement) string
+	}
+	fields := []field{
+		{"manufacturer", m.Manufacturer, func(dm measurement.DeviceMeasurement) string { return dm.Manufacturer }},
+		{"model", m.Model, func(dm measurement.DeviceMeasurement) string { return dm.Model }},
+		{"hardware", m.Hardware, func(dm measurement.DeviceMeasurement) string { return dm.HardwareVersion }},
+		{"firmware", m.Firmware, func(dm measurement.DeviceMeasurement) string { return dm.FirmwareVersion }},
+		{"software", m.Software, func(dm measurement.DeviceMeasurement) string { return dm.SoftwareVersion }},
+	}
 
-	d.medComId = "MCI00004"
-	d.productType = PRODUCT_TYPE_BLOODPRESSUREMONITOR
-	d.manufacturer = "A&D Medical"
-	d.model = "UA-767PlusBT-C Bluetooth"
+	var matchers []func(dm measurement.DeviceMeasurement) bool
+	for _, f := range fields {
+		if f.rule == nil {
+			continue
+		}
+		matches, err := f.rule.compile()
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("Invalid %s rule", f.name))
+		}
+		value := f.value
+		matchers = append(matchers, func(dm measurement.DeviceMeasurement) bool {
+			return matches(value(dm))
+		})
+	}
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("No match rules")
+	}
 
-	d.compareFunction = func(origin measurement.Origin) bool {
-		model := strings.ToLower(origin.DeviceMeasurement.Model)
-		if strings.EqualFold(d.manufacturer, origin.DeviceMeasurement.Manufacturer) {
-			if strings.Contains(model, "767") {
-				if strings.Contains(model, "c") {
-					return true
-				}
+	return func(origin measurement.Origin) bool {
+		for _, matches := range matchers {
+			if !matches(origin.DeviceMeasurement) {
+				return false
 			}
 		}
-		return false
-	}
-	return d
+		return true
+	}, nil
 }
 
-func NewAnDMedical767PBTCiExportType() simpleDevice {
-	d := simpleDevice{}
-
-	d.medComId = "MCI00012"
-	d.productType = PRODUCT_TYPE_BLOODPRESSUREMONITOR
-	d.manufacturer = "A&D Medical"
-	d.model = "UA-767PlusBT-Ci Bluetooth"
-
-	d.compareFunction = func(origin measurement.Origin) bool {
-		model := strings.ToLower(origin.DeviceMeasurement.Model)
-		if strings.EqualFold(d.manufacturer, origin.DeviceMeasurement.Manufacturer) {
-			if strings.Contains(model, "767") {
-				if strings.Contains(model, "ci") {
-					return true
-				}
+func (r MatchRule) compile() (func(value string) bool, error) {
+	isRange := len(r.Min) > 0 || len(r.Max) > 0
+	rules := 0
+	for _, set := range []bool{len(r.Exact) > 0, len(r.Regex) > 0, isRange} {
+		if set {
+			rules++
+		}
+	}
+	if rules != 1 {
+		return nil, fmt.Errorf("Exactly one of exact, regex or min/max is required")
+	}
+
+	switch {
+	case len(r.Exact) > 0:
+		exact := r.Exact
+		return func(value string) bool {
+			return strings.EqualFold(exact, value)
+		}, nil
+	case len(r.Regex) > 0:
+		re, err := regexp.Compile("(?i)" + r.Regex)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	default:
+		var min, max []int
+		var err error
+		if len(r.Min) > 0 {
+			if min, err = parseVersion(r.Min); err != nil {
+				return nil, err
 			}
 		}
-		return false
+		if len(r.Max) > 0 {
+			if max, err = parseVersion(r.Max); err != nil {
+				return nil, err
+			}
+		}
+		return func(value string) bool {
+			version, err := parseVersion(value)
+			if err != nil {
+				return false
+			}
+			if min != nil && compareVersions(version, min) < 0 {
+				return false
+			}
+			if max != nil && compareVersions(version, max) > 0 {
+				return false
+			}
+			return true
+		}, nil
 	}
-	return d
 }
 
-func NewNonin3230ExportType() simpleDevice {
+// parseVersion parses a dotted version such as 1.2.10. A leading v is allowed
+func parseVersion(version string) ([]int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(strings.ToLower(version)), "v")
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("Empty version")
+	}
 
-	d := simpleDevice{}
+	var parts []int
+	for _, part := range strings.Split(trimmed, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("Invalid version %s", version)
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
 
-	d.medComId = "MCI00013"
-	d.productType = PRODUCT_TYPE_PULSEOXIMETER
-	d.manufacturer = "Nonin"
-	d.model = "3230 Bluetooth Smart Pulse Oximeter"
-	d.compareFunction = func(origin measurement.Origin) bool {
-		if strings.EqualFold(d.manufacturer, origin.DeviceMeasurement.Manufacturer) {
-			if strings.Contains(strings.ToLower(origin.DeviceMeasurement.Model), "3230") {
-				return true
+// compareVersions compares part by part. Missing parts count as 0
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			if x < y {
+				return -1
 			}
+			return 1
 		}
-		return false
 	}
-	return d
+	return 0
 }
 
-func NewNonin9560ExportType() simpleDevice {
-	d := simpleDevice{}
-
-	d.medComId = "MCI00005"
-	d.productType = PRODUCT_TYPE_PULSEOXIMETER
-	d.manufacturer = "Nonin"
-	d.model = "Onyx II 9560 Bluetooth Pulse Oximeter"
-	d.compareFunction = func(origin measurement.Origin) bool {
-		if strings.EqualFold(d.manufacturer, origin.DeviceMeasurement.Manufacturer) {
-			if strings.Contains(strings.ToLower(origin.DeviceMeasurement.Model), "9560") {
-				return true
-			}
+// MatchDevice returns the device matching the origin. When several devices match the highest priority wins, then the lowest MedCom id
+func MatchDevice(devices []MedicalDevice, origin measurement.Origin) (MedicalDevice, bool) {
+	var matches []MedicalDevice
+	for _, d := range devices {
+		if d.CheckIfSameModel(origin) {
+			matches = append(matches, d)
 		}
-		return false
 	}
+	if len(matches) == 0 {
+		return nil, false
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].GetPriority() != matches[j].GetPriority() {
+			return matches[i].GetPriority() > matches[j].GetPriority()
+		}
+		return matches[i].GetMedcomID() < matches[j].GetMedcomID()
+	})
+	return matches[0], true
+}
 
+func registeredDevice(medComID string) simpleDevice {
+	d, ok := defaultRegistry.devices[medComID]
+	if !ok {
+		panic(fmt.Sprintf("Device %s is not in the registry", medComID))
+	}
 	return d
 }
 
-func NewVitalograph4000ExportType() simpleDevice {
-	d := simpleDevice{}
+func NewAnDMedical302PBTExportType() simpleDevice {
+	return registeredDevice("MCI00010")
+}
 
-	d.medComId = "MCI00014"
-	d.productType = PRODUCT_TYPE_LUNGMONITOR
-	d.manufacturer = "Vitalograph"
-	d.model = "4000 Lung Monitor Bluetooth"
+func NewAnDMedical321PBTCExportType() simpleDevice {
+	return registeredDevice("MCI00002")
+}
 
-	d.compareFunction = func(origin measurement.Origin) bool {
-		return strings.EqualFold(d.manufacturer, origin.DeviceMeasurement.Manufacturer)
-	}
+func NewAnDMedical351PBTCiExportType() simpleDevice {
+	return registeredDevice("MCI00011")
+}
 
-	return d
+func NewAnDMedical767PBTCExportType() simpleDevice {
+	return registeredDevice("MCI00004")
+}
+
+func NewAnDMedical767PBTCiExportType() simpleDevice {
+	return registeredDevice("MCI00012")
+}
+
+func NewNonin3230ExportType() simpleDevice {
+	return registeredDevice("MCI00013")
+}
+
+func NewNonin9560ExportType() simpleDevice {
+	return registeredDevice("MCI00005")
+}
+
+func NewVitalograph4000ExportType() simpleDevice {
+	return registeredDevice("MCI00014")
 }
@@ -1,6 +1,7 @@
 package exporttypes
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
@@ -50,3 +51,104 @@ func TestNewAnDMedical302PBTExportType(t *testing.T) {
 		})
 	}
 }
+
+func TestParseDeviceRegistry(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry string
+		err      string
+	}{
+		{"Valid", "devices:\n  - medcomid: MCI00099\n    manufacturer: Acme\n    model: Scale 1\n    match:\n      manufacturer: {exact: Acme}\n      firmware: {min: 1.2, max: 2}\n", ""},
+		{"Unknown field", "devices:\n  - medcomid: MCI00099\n    manufacturer: Acme\n    model: Scale 1\n    vendor: Acme\n    match:\n      manufacturer: {exact: Acme}\n", "vendor"},
+		{"No rules", "devices:\n  - medcomid: MCI00099\n    manufacturer: Acme\n    model: Scale 1\n", "No match rules"},
+		{"Two rules", "devices:\n  - medcomid: MCI00099\n    manufacturer: Acme\n    model: Scale 1\n    match:\n      model: {exact: Scale 1, regex: Scale}\n", "Exactly one"},
+		{"Invalid regex", "devices:\n  - medcomid: MCI00099\n    manufacturer: Acme\n    model: Scale 1\n    match:\n      model: {regex: \"(\"}\n", "model rule"},
+		{"Invalid version", "devices:\n  - medcomid: MCI00099\n    manufacturer: Acme\n    model: Scale 1\n    match:\n      software: {min: one}\n", "Invalid version"},
+		{"Duplicate", "devices:\n  - medcomid: MCI00099\n    manufacturer: Acme\n    model: Scale 1\n    match:\n      model: {exact: Scale 1}\n  - medcomid: MCI00099\n    manufacturer: Acme\n    model: Scale 2\n    match:\n      model: {exact: Scale 2}\n", "more than once"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDeviceRegistry([]byte(tt.registry))
+			if len(tt.err) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error - %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error containing '%s' got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestMatchDevice(t *testing.T) {
+	registry, err := ParseDeviceRegistry([]byte(`devices:
+  - medcomid: MCI00092
+    manufacturer: Acme
+    model: Scale
+    match:
+      manufacturer: {exact: Acme}
+  - medcomid: MCI00091
+    manufacturer: Acme
+    model: Scale
+    match:
+      manufacturer: {exact: acme}
+  - medcomid: MCI00093
+    manufacturer: Acme
+    model: Scale v2
+    priority: 10
+    match:
+      manufacturer: {exact: Acme}
+      firmware: {min: 2.0}
+`))
+	if err != nil {
+		t.Fatalf("Error parsing registry - %v", err)
+	}
+
+	var devices []MedicalDevice
+	for _, id := range []string{"MCI00092", "MCI00091", "MCI00093"} {
+		d, _ := registry.Device(id)
+		devices = append(devices, d)
+	}
+
+	tests := []struct {
+		name     string
+		firmware string
+		medcomID string
+	}{
+		{"Lowest id wins", "1.9.9", "MCI00091"},
+		{"Priority wins", "2.0.1", "MCI00093"},
+		{"Unparseable version", "beta", "MCI00091"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			origin := measurement.Origin{DeviceMeasurement: measurement.DeviceMeasurement{Manufacturer: "Acme", Model: "Scale", FirmwareVersion: tt.firmware}}
+			d, ok := MatchDevice(devices, origin)
+			if !ok || d.GetMedcomID() != tt.medcomID {
+				t.Errorf("Expected %s got %v", tt.medcomID, d)
+			}
+		})
+	}
+
+	if _, ok := MatchDevice(devices, measurement.Origin{}); ok {
+		t.Errorf("Expected no match for manual measurement")
+	}
+}
+
+func TestBuiltinDevicesDoNotOverlap(t *testing.T) {
+	c := NewAnDMedical767PBTCExportType()
+	ci := NewAnDMedical767PBTCiExportType()
+	for _, model := range []string{"UA-767PlusBT-C Bluetooth", "UA-767PlusBT-Ci Bluetooth"} {
+		origin := measurement.Origin{DeviceMeasurement: measurement.DeviceMeasurement{Manufacturer: "A&D Medical", Model: model}}
+		if c.CheckIfSameModel(origin) == ci.CheckIfSameModel(origin) {
+			t.Errorf("Expected exactly one device to match %s", model)
+		}
+	}
+
+	other := measurement.Origin{DeviceMeasurement: measurement.DeviceMeasurement{Manufacturer: "Vitalograph", Model: "Asma-1"}}
+	if NewVitalograph4000ExportType().CheckIfSameModel(other) {
+		t.Errorf("Expected Vitalograph 4000 not to match other Vitalograph models")
+	}
+}
@@ -1,49 +1,50 @@
 package exporttypes
 
 import (
-	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/sirupsen/logrus"
 )
 
 func layoutZeroDigit(m measurement.Measurement) string {
-	return strconv.FormatFloat(handleConversionToFloat(m.Measurement.Value), 'f', 0, 64)
+	return layoutNumber(m.Measurement.Value, 1, 0)
 }
 
-func layoutSingleDigit(m measurement.Measurement) string {
-	return strconv.FormatFloat(handleConversionToFloat(m.Measurement.Value), 'f', 1, 64)
-}
-
-func handleConversionToFloat(val interface{}) float64 {
-	var value float64
-	typeOfValue := reflect.ValueOf(val)
-	switch typeOfValue.Kind() {
-	case reflect.Int:
-		value = float64(val.(int))
-	case reflect.Float32:
-		value = float64(val.(float32))
-	case reflect.Float64:
-		value = float64(val.(float64))
-	case reflect.String:
-		val, err := strconv.ParseFloat(val.(string), 64)
-		if err != nil {
-			logrus.Errorln("Error parsing number, ", val)
-			return 0.0
-		}
-		value = val
+// layoutNumber formats the value multiplied by scale. Values are validated before layout, so a value that is not numeric
+// is logged and laid out as an empty result
+func layoutNumber(v measurement.Value, scale float64, decimals int) string {
+	number, err := v.Float()
+	if err != nil {
+		logrus.Errorf("Error laying out value - %v", err)
+		return ""
 	}
-
-	return value
+	return strconv.FormatFloat(number*scale, 'f', decimals, 64)
 }
 
-func layoutDoubleDigit(m measurement.Measurement) string {
-	return strconv.FormatFloat(handleConversionToFloat(m.Measurement.Value), 'f', 2, 64)
+// Operators of results outside the range of the device
+const (
+	RESULT_OPERATOR_LESS_THAN    = "less_than"
+	RESULT_OPERATOR_GREATER_THAN = "greater_than"
+)
+
+var resultOperators = map[string]string{
+	"<": RESULT_OPERATOR_LESS_THAN,
+	">": RESULT_OPERATOR_GREATER_THAN,
 }
 
-func layoutPercentDoubleDigit(m measurement.Measurement) string {
-	return strconv.FormatFloat(handleConversionToFloat(m.Measurement.Value)/100, 'f', 2, 64)
+// splitOperator splits a value like <1.1 into the operator symbol and the number. Other values have no operator
+func splitOperator(v measurement.Value) (string, measurement.Value) {
+	if v.Kind() != measurement.VALUE_ENUMERATED {
+		return "", v
+	}
+	text := strings.TrimSpace(v.String())
+	symbol := text[:1]
+	if _, ok := resultOperators[symbol]; !ok {
+		return "", v
+	}
+	return symbol, measurement.EnumeratedValue(strings.TrimSpace(text[1:]))
 }
 
 func GetDevicesForType(t string) []MedicalDevice {
@@ -6,109 +6,58 @@ import (
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 )
 
-func TestFloatConversion(t *testing.T) {
+func TestLayoutNumber(t *testing.T) {
 	tests := []struct {
-		name   string
-		input  interface{}
-		output float64
+		name     string
+		input    interface{}
+		scale    float64
+		decimals int
+		output   string
 	}{
-		{"String", "26.2", 26.2},
-		{"Int", 26, 26.0},
-		{"Float", 26.2, 26.2},
+		{"No digits String", "26", 1, 1, "26.0"},
+		{"Two digits String", "26.00", 1, 1, "26.0"},
+		{"Int", 26, 1, 2, "26.00"},
+		{"Float two digits", 26.02, 1, 2, "26.02"},
+		{"Float rounding", 26.06, 1, 1, "26.1"},
+		{"String rounding", "26.015", 1, 2, "26.02"},
+		{"Zero digits", 26.6, 1, 0, "27"},
+		{"Scaled", "26.60", 0.01, 2, "0.27"},
+		{"Not numeric", "Lidt bedre", 1, 1, ""},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res := handleConversionToFloat(tt.input)
-			if tt.output != res {
-				t.Errorf("Expected '%f' got '%f'", tt.output, res)
-			}
-		})
-	}
-}
-
-func TestSingleDigitLayout(t *testing.T) {
-	tests := []struct {
-		name   string
-		input  interface{}
-		output string
-	}{
-		{"No digits String", "26", "26.0"},
-		{"one digits String", "26.0", "26.0"},
-		{"Two digits String", "26.00", "26.0"},
-		{"Int", 26, "26.0"},
-		{"Float one digits ", 26.0, "26.0"},
-		{"Float two digits ", 26.02, "26.0"},
-		{"Float two digits rounding ", 26.06, "26.1"},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			m := measurement.Measurement{}
-			m.Measurement.Value = tt.input
-
-			res := layoutSingleDigit(m)
+			res := layoutNumber(measurement.ValueOf(tt.input), tt.scale, tt.decimals)
 			if tt.output != res {
 				t.Errorf("Expected '%v' got '%v'", tt.output, res)
 			}
 		})
 	}
-
 }
 
-func TestLayoutDoubleDigiti(t *testing.T) {
+func TestQuestionnaireTypeLayout(t *testing.T) {
 	tests := []struct {
-		name   string
-		input  interface{}
-		output string
+		name         string
+		alphaNumeric bool
+		decimals     int
+		input        interface{}
+		output       string
 	}{
-		{"No digits String", "26", "26.00"},
-		{"one digits String", "26.0", "26.00"},
-		{"Two digits String", "26.010", "26.01"},
-		{"Two digits String", "26.015", "26.02"},
-		{"Int", 26, "26.00"},
-		{"Float one digits ", 26.0, "26.00"},
-		{"Float two digits ", 26.02, "26.02"},
-		{"Float two digits rounding ", 26.060, "26.06"},
-		{"Float two digits rounding ", 26.006, "26.01"},
+		{"Numeric", false, 0, 7.0, "7"},
+		{"Numeric decimals", false, 1, "7.25", "7.2"},
+		{"Numeric bool", false, 0, true, "1"},
+		{"Text", true, 0, "Lidt bedre", "Lidt bedre"},
+		{"Text bool", true, 0, false, "Nej"},
+		{"Text number", true, 0, 3.0, "3"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := measurement.Measurement{}
-			m.Measurement.Value = tt.input
+			m.Measurement.Value = measurement.ValueOf(tt.input)
 
-			res := layoutDoubleDigit(m)
-			if tt.output != res {
-				t.Errorf("Expected '%v' got '%v'", tt.output, res)
-			}
-		})
-	}
-}
-
-func TestLayoutPercentDoubleDigits(t *testing.T) {
-	tests := []struct {
-		name   string
-		input  interface{}
-		output string
-	}{
-		{"No digits String", "26", "0.26"},
-		{"one digits String", "26.0", "0.26"},
-		{"Two digits String", "26.60", "0.27"},
-		{"Two digits String", "26.015", "0.26"},
-		{"Int", 26, "0.26"},
-		{"Float one digits ", 26.0, "0.26"},
-		{"Float two digits ", 26.02, "0.26"},
-		{"Float two digits rounding ", 26.60, "0.27"},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			m := measurement.Measurement{}
-			m.Measurement.Value = tt.input
-
-			res := layoutPercentDoubleDigit(m)
-			if tt.output != res {
+			s := NewQuestionnaireType("MCS88001", "Symptom", "", tt.decimals, tt.alphaNumeric)
+			if res := s.GetResultText(m); tt.output != res {
 				t.Errorf("Expected '%v' got '%v'", tt.output, res)
 			}
 		})
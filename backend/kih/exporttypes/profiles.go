@@ -0,0 +1,81 @@
+package exporttypes
+
+import (
+	"fmt"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+)
+
+// ProfileTypes returns the types of a profile for the backend. The definitions are copied before they are overridden,
+// so the catalog is not changed and backends can use different profiles of the same catalog
+func (c Catalog) ProfileTypes(backend string, profile app.TypeProfile) (map[string]MeasurementType, error) {
+	if len(profile.Catalog) > 0 {
+		backend = profile.Catalog
+	}
+	if !c.hasBackend(backend) {
+		return nil, fmt.Errorf("Profile uses unknown backend %s - the catalog defines no types for it", backend)
+	}
+
+	definitions := make(map[string]TypeDefinition)
+	var names []string
+	for _, t := range c.Types {
+		if t.usedBy(backend) {
+			definitions[t.Name] = t
+			names = append(names, t.Name)
+		}
+	}
+	if len(profile.Types) > 0 {
+		names = profile.Types
+	}
+
+	selected := Catalog{registry: c.registry}
+	enabled := make(map[string]bool)
+	for _, name := range names {
+		enabled[name] = true
+		t, ok := definitions[name]
+		if !ok {
+			return nil, fmt.Errorf("Profile type %s is not in the catalog for %s", name, backend)
+		}
+		t.Backends = nil
+		if override, ok := profile.Overrides[name]; ok {
+			if err := t.override(override); err != nil {
+				return nil, err
+			}
+		}
+		selected.Types = append(selected.Types, t)
+	}
+	for name := range profile.Overrides {
+		if !enabled[name] {
+			return nil, fmt.Errorf("Profile overrides type %s, which is not in the profile", name)
+		}
+	}
+
+	if err := selected.validate(); err != nil {
+		return nil, err
+	}
+	return selected.ExportTypes(backend), nil
+}
+
+// override changes the definition of a simple type
+func (t *TypeDefinition) override(o app.TypeOverride) error {
+	if t.Kind != "" && t.Kind != KIND_SIMPLE {
+		return fmt.Errorf("Type %s - only simple types can be overridden", t.Name)
+	}
+	if len(o.NpuCode) > 0 {
+		t.NpuCode = o.NpuCode
+	}
+	if len(o.Unit) > 0 {
+		t.Unit = o.Unit
+	}
+	if len(o.AnalysisText) > 0 {
+		t.AnalysisText = o.AnalysisText
+	}
+	if o.Decimals != nil {
+		t.Decimals = *o.Decimals
+	}
+	if o.Export != nil {
+		export := *o.Export
+		t.Export = &export
+	}
+	return nil
+}
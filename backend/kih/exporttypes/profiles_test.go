@@ -0,0 +1,78 @@
+package exporttypes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+func TestProfileTypes(t *testing.T) {
+	decimals := 1
+	export := false
+	profile := app.TypeProfile{
+		Catalog: BACKEND_KIHDB,
+		Types:   []string{TYPE_NAME_PULSE, TYPE_NAME_WEIGHT, TYPE_NAME_BLOOD_PRESSURE},
+		Overrides: map[string]app.TypeOverride{
+			TYPE_NAME_PULSE:  {Unit: "slag/min", Decimals: &decimals},
+			TYPE_NAME_WEIGHT: {Export: &export},
+		},
+	}
+
+	types, err := DefaultCatalog().ProfileTypes(BACKEND_OIOXDS, profile)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(types) != 3 {
+		t.Fatalf("Expected the types of the profile - got %d", len(types))
+	}
+
+	pulse := types[TYPE_NAME_PULSE]
+	m := measurement.Measurement{Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(72)}}
+	if pulse.GetResultUnitText() != "slag/min" || pulse.GetResultText(m) != "72.0" {
+		t.Errorf("Expected pulse to be overridden - got '%s' '%s'", pulse.GetResultUnitText(), pulse.GetResultText(m))
+	}
+	if pulse.GetAnalysisText() != NewPulseType().GetAnalysisText() {
+		t.Errorf("Expected the kihdb definition of pulse - got %s", pulse.GetAnalysisText())
+	}
+	if types[TYPE_NAME_WEIGHT].IsToBeExported() {
+		t.Errorf("Expected weight not to be exported")
+	}
+
+	// The catalog is not changed
+	if DefaultCatalog().ExportTypes(BACKEND_KIHDB)[TYPE_NAME_PULSE].GetResultUnitText() != "x 1/min" || !NewWeightType().IsToBeExported() {
+		t.Errorf("Expected the catalog to be unchanged")
+	}
+
+	all, err := DefaultCatalog().ProfileTypes(BACKEND_OIOXDS, app.TypeProfile{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(all) != len(GetOioXdsExportTypes()) {
+		t.Errorf("Expected an empty profile to have all types - got %d", len(all))
+	}
+}
+
+func TestProfileTypesErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile app.TypeProfile
+		err     string
+	}{
+		{"Unknown backend", app.TypeProfile{Catalog: "kih"}, "unknown backend"},
+		{"Unknown type", app.TypeProfile{Types: []string{"height_of_tree"}}, "not in the catalog"},
+		{"Type of other backend", app.TypeProfile{Catalog: BACKEND_KIHDB, Types: []string{TYPE_NAME_RESPIRATORY_RATE}}, "not in the catalog"},
+		{"Type enabled twice", app.TypeProfile{Types: []string{TYPE_NAME_WEIGHT, TYPE_NAME_WEIGHT}}, "more than once"},
+		{"Override outside profile", app.TypeProfile{Types: []string{TYPE_NAME_WEIGHT}, Overrides: map[string]app.TypeOverride{TYPE_NAME_PULSE: {Unit: "BPM"}}}, "not in the profile"},
+		{"Override of composite", app.TypeProfile{Overrides: map[string]app.TypeOverride{TYPE_NAME_BLOOD_PRESSURE: {Unit: "kPa"}}}, "only simple types"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DefaultCatalog().ProfileTypes(BACKEND_OIOXDS, tt.profile)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected error containing '%s' got %v", tt.err, err)
+			}
+		})
+	}
+}
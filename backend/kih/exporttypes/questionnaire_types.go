@@ -0,0 +1,35 @@
+package exporttypes
+
+import (
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// NewQuestionnaireType creates the export type for a mapped question. The answer is carried as the measurement value
+func NewQuestionnaireType(npuCode, analysisText, unit string, decimals int, alphaNumeric bool) SimpleType {
+	s := SimpleType{}
+	s.npuCode = npuCode
+	s.isToBeExported = true
+	s.resultUnitText = unit
+	s.analysisText = analysisText
+	s.decimal = decimals
+	s.isAlphaNumeric = alphaNumeric
+	s.devices = []MedicalDevice{}
+	if alphaNumeric {
+		s.layoutResults = layoutAnswerText
+	} else {
+		s.layoutResults = func(m measurement.Measurement) string {
+			return layoutNumber(m.Measurement.Value, 1, decimals)
+		}
+	}
+	return s
+}
+
+func layoutAnswerText(m measurement.Measurement) string {
+	if b, err := m.Measurement.Value.Boolean(); err == nil {
+		if b {
+			return "Ja"
+		}
+		return "Nej"
+	}
+	return m.Measurement.Value.String()
+}
@@ -28,7 +28,10 @@ type SimpleType struct {
 	decimal        int
 	isAlphaNumeric bool
 	layoutResults  func(m measurement.Measurement) string
-	values         interface{}
+	value          func(m measurement.Measurement) measurement.Value
+	units          unitTable
+	rules          valueRules
+	circumstances  *CircumstanceMapping
 }
 
 // Implementation
@@ -49,6 +52,15 @@ func (s SimpleType) GetAnalysisText() string {
 func (s SimpleType) IsAlphaNumeric() bool {
 	return s.isAlphaNumeric
 }
+
+// GetResultOperator returns the operator of a value outside the range of the device, e.g. less_than for <1.1
+func (s SimpleType) GetResultOperator(m measurement.Measurement) string {
+	if !s.rules.operators || s.value == nil {
+		return ""
+	}
+	symbol, _ := splitOperator(s.value(m))
+	return resultOperators[symbol]
+}
 func (s SimpleType) GetDevices() []MedicalDevice {
 	return s.devices
 }
@@ -63,6 +75,7 @@ type MedicalDevice interface {
 	GetMedcomID() string
 	GetManufacturer() string
 	GetModel() string
+	GetPriority() int
 	CheckIfSameModel(origin measurement.Origin) bool
 }
 
@@ -72,6 +85,7 @@ type simpleDevice struct {
 	manufacturer    string
 	model           string
 	productType     string
+	priority        int
 	compareFunction func(origin measurement.Origin) bool
 }
 
@@ -87,6 +101,10 @@ func (d simpleDevice) GetModel() string {
 	return d.model
 }
 
+func (d simpleDevice) GetPriority() int {
+	return d.priority
+}
+
 func (d simpleDevice) CheckIfSameModel(origin measurement.Origin) bool {
 	return d.compareFunction(origin)
 }
@@ -95,80 +113,19 @@ func (d simpleDevice) String() string {
 	return fmt.Sprintf("[%s] model: %s - medcom id: %s", d.manufacturer, d.model, d.medComId)
 }
 
-// Setup the types to export
-func init() {
-	exportTypes = make(map[string]MeasurementType)
-	exportTypes[TYPE_NAME_PULSE] = NewPulseType()
-	exportTypes[TYPE_NAME_WEIGHT] = NewWeightType()
-	exportTypes[TYPE_NAME_URINE_LEUKOCYTES] = NewUrineLeukocytes()
-	exportTypes[TYPE_NAME_SATURATION] = NewSaturation()
-	exportTypes[TYPE_NAME_URINE_NITRITE] = NewUrineNitrite()
-	//exportTypes[TYPE_NAME_URINE_BLOOD] = NewUrineBlood()
-	exportTypes[TYPE_NAME_URINE_BLOOD] = NewUrineErythrocytes()
-	exportTypes[TYPE_NAME_URINE_PROTEIN] = NewUrineProtein()
-	exportTypes[TYPE_NAME_URINE_GLUCOSE] = NewUrineGlucose()
-	exportTypes[TYPE_NAME_URINE_ERYTHROCYTES] = NewUrineErythrocytes()
-	exportTypes[TYPE_NAME_BLOODSUGAR] = NewBloodSugar()
-	exportTypes[TYPE_NAME_BLOOD_PRESSURE] = NewBloodPressureType()
-	exportTypes[TYPE_NAME_CRP] = NewCrp()
-	exportTypes[TYPE_NAME_FEV1] = NewFev1()
-	exportTypes[TYPE_NAME_FEV1_FEV6_RATIO] = NewFev1Fev6()
-	exportTypes[TYPE_NAME_FEV6] = NewFev6()
-	exportTypes[TYPE_NAME_TEMPERATURE] = NewTemperature()
-}
-
-var exportTypes map[string]MeasurementType
-
+// Get the export types of the built-in catalog
 func GetExportTypes() map[string]MeasurementType {
-	return exportTypes
+	return GetKihdbExportTypes()
 }
 
 // Get export types for KIH DB
 func GetKihdbExportTypes() map[string]MeasurementType {
-	exportTypes = make(map[string]MeasurementType)
-	exportTypes[TYPE_NAME_PULSE] = NewPulseType()
-	exportTypes[TYPE_NAME_WEIGHT] = NewWeightType()
-	exportTypes[TYPE_NAME_URINE_LEUKOCYTES] = NewUrineLeukocytes()
-	exportTypes[TYPE_NAME_SATURATION] = NewSaturation()
-	exportTypes[TYPE_NAME_URINE_NITRITE] = NewUrineNitrite()
-	//exportTypes[TYPE_NAME_URINE_BLOOD] = NewUrineBlood()
-	exportTypes[TYPE_NAME_URINE_BLOOD] = NewUrineErythrocytes()
-	exportTypes[TYPE_NAME_URINE_PROTEIN] = NewUrineProtein()
-	exportTypes[TYPE_NAME_URINE_GLUCOSE] = NewUrineGlucose()
-	exportTypes[TYPE_NAME_URINE_ERYTHROCYTES] = NewUrineErythrocytes()
-	exportTypes[TYPE_NAME_BLOODSUGAR] = NewBloodSugar()
-	exportTypes[TYPE_NAME_BLOOD_PRESSURE] = NewBloodPressureType()
-	exportTypes[TYPE_NAME_CRP] = NewCrp()
-	exportTypes[TYPE_NAME_FEV1] = NewFev1()
-	exportTypes[TYPE_NAME_FEV1_FEV6_RATIO] = NewFev1Fev6()
-	exportTypes[TYPE_NAME_FEV6] = NewFev6()
-	exportTypes[TYPE_NAME_TEMPERATURE] = NewTemperature()
-
-	return exportTypes
+	return defaultCatalog.ExportTypes(BACKEND_KIHDB)
 }
 
 // Get export types for OIO XDS export
 func GetOioXdsExportTypes() map[string]MeasurementType {
-	exportTypes = make(map[string]MeasurementType)
-	exportTypes[TYPE_NAME_PULSE] = NewXdsPulseType()
-	exportTypes[TYPE_NAME_WEIGHT] = NewWeightType()
-	exportTypes[TYPE_NAME_URINE_LEUKOCYTES] = NewUrineLeukocytes()
-	exportTypes[TYPE_NAME_SATURATION] = NewSaturation()
-	exportTypes[TYPE_NAME_URINE_NITRITE] = NewUrineNitrite()
-	exportTypes[TYPE_NAME_URINE_BLOOD] = NewUrineErythrocytes()
-	exportTypes[TYPE_NAME_URINE_PROTEIN] = NewUrineProtein()
-	exportTypes[TYPE_NAME_URINE_GLUCOSE] = NewUrineGlucose()
-	exportTypes[TYPE_NAME_URINE_ERYTHROCYTES] = NewUrineErythrocytes()
-	exportTypes[TYPE_NAME_BLOODSUGAR] = NewBloodSugar()
-	exportTypes[TYPE_NAME_BLOOD_PRESSURE] = NewBloodPressureType()
-	exportTypes[TYPE_NAME_CRP] = NewCrp()
-	exportTypes[TYPE_NAME_FEV1] = NewFev1()
-	exportTypes[TYPE_NAME_FEV1_FEV6_RATIO] = NewFev1Fev6()
-	exportTypes[TYPE_NAME_FEV6] = NewFev6()
-	exportTypes[TYPE_NAME_TEMPERATURE] = NewTemperature()
-	exportTypes[TYPE_NAME_RESPIRATORY_RATE] = NewRespiratoryRate()
-
-	return exportTypes
+	return defaultCatalog.ExportTypes(BACKEND_OIOXDS)
 }
 
 // Compiled handled names for measurement types
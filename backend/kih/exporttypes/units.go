@@ -0,0 +1,119 @@
+package exporttypes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// UnitConversion converts a value to the canonical unit of a type as value * factor + offset. A factor of 0 counts as 1
+type UnitConversion struct {
+	Factor float64 `yaml:"factor"`
+	Offset float64 `yaml:"offset"`
+}
+
+func (c UnitConversion) isIdentity() bool {
+	return (c.Factor == 0 || c.Factor == 1) && c.Offset == 0
+}
+
+func (c UnitConversion) apply(value float64) float64 {
+	factor := c.Factor
+	if factor == 0 {
+		factor = 1
+	}
+	return value*factor + c.Offset
+}
+
+// UnitError is returned for measurements in a unit the type cannot convert
+type UnitError struct {
+	Type string
+	Unit string
+}
+
+func (e UnitError) Error() string {
+	return fmt.Sprintf("Unsupported unit '%s' for %s", e.Unit, e.Type)
+}
+
+// unitTable holds the units accepted for a type by normalised name. An empty table accepts any unit unconverted
+type unitTable map[string]UnitConversion
+
+func unitName(unit string) string {
+	return strings.ToLower(strings.TrimSpace(unit))
+}
+
+func newUnitTable(units map[string]UnitConversion) unitTable {
+	if len(units) == 0 {
+		return nil
+	}
+	table := make(unitTable)
+	for unit, conversion := range units {
+		table[unitName(unit)] = conversion
+	}
+	return table
+}
+
+// lookup returns the conversion for the unit. Measurements without a unit are taken to be in the canonical unit
+func (u unitTable) lookup(unit string) (UnitConversion, bool) {
+	if len(u) == 0 || len(unitName(unit)) == 0 {
+		return UnitConversion{}, true
+	}
+	conversion, ok := u[unitName(unit)]
+	return conversion, ok
+}
+
+func unitsOf(t MeasurementType) unitTable {
+	switch exportType := t.(type) {
+	case SimpleType:
+		return exportType.units
+	case CompositeType:
+		return exportType.units
+	case ContinuousBloodSugarType:
+		return exportType.glucose.units
+	case WaveformType:
+		return exportType.heartRate.units
+	}
+	return nil
+}
+
+// ConvertValue converts a value in the unit to the canonical unit of the type
+func ConvertValue(t MeasurementType, measurementType, unit string, value float64) (float64, error) {
+	conversion, ok := unitsOf(t).lookup(unit)
+	if !ok {
+		return value, UnitError{Type: measurementType, Unit: unit}
+	}
+	return conversion.apply(value), nil
+}
+
+// NormalizeUnit converts the values of the measurement to the canonical unit of the type. The measurement passed is not modified
+func NormalizeUnit(t MeasurementType, m measurement.Measurement) (measurement.Measurement, error) {
+	conversion, ok := unitsOf(t).lookup(m.Measurement.Unit)
+	if !ok {
+		return m, UnitError{Type: m.Type, Unit: m.Measurement.Unit}
+	}
+	if conversion.isIdentity() {
+		return m, nil
+	}
+
+	if value, err := m.Measurement.Value.Float(); err == nil {
+		m.Measurement.Value = measurement.NumericValue(conversion.apply(value))
+	} else if symbol, number := splitOperator(m.Measurement.Value); len(symbol) > 0 {
+		// Values outside the range of the device keep their operator, e.g. <20 mg/dL is <1.11 mmol/L
+		if value, err := number.Float(); err == nil {
+			m.Measurement.Value = measurement.EnumeratedValue(symbol + strconv.FormatFloat(conversion.apply(value), 'f', -1, 64))
+		}
+	}
+	if m.Measurement.Systolic != 0 || m.Measurement.Diastolic != 0 {
+		m.Measurement.Systolic = float32(conversion.apply(float64(m.Measurement.Systolic)))
+		m.Measurement.Diastolic = float32(conversion.apply(float64(m.Measurement.Diastolic)))
+	}
+	if len(m.Measurement.Series) > 0 {
+		series := make([]measurement.SeriesValue, len(m.Measurement.Series))
+		for i, v := range m.Measurement.Series {
+			series[i] = measurement.SeriesValue{Timestamp: v.Timestamp, Value: conversion.apply(v.Value)}
+		}
+		m.Measurement.Series = series
+	}
+	return m, nil
+}
@@ -0,0 +1,100 @@
+package exporttypes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+func TestNormalizeUnit(t *testing.T) {
+	types := GetOioXdsExportTypes()
+
+	tests := []struct {
+		name   string
+		mType  string
+		unit   string
+		value  interface{}
+		result string
+		err    bool
+	}{
+		{"Canonical", TYPE_NAME_WEIGHT, "kg", 80.0, "80.0", false},
+		{"No unit", TYPE_NAME_WEIGHT, "", 80.0, "80.0", false},
+		{"Pounds", TYPE_NAME_WEIGHT, "lbs", 176.37, "80.0", false},
+		{"Case and spaces", TYPE_NAME_WEIGHT, " LB ", "176.37", "80.0", false},
+		{"Fahrenheit", TYPE_NAME_TEMPERATURE, "°F", 98.6, "37.0", false},
+		{"mg/dL", TYPE_NAME_BLOODSUGAR, "mg/dL", 126, "7.0", false},
+		{"Millilitres", TYPE_NAME_FEV1, "mL", 2500, "2.50", false},
+		{"Alias", TYPE_NAME_PULSE, "1/min", 60, "60", false},
+		{"Unknown unit", TYPE_NAME_WEIGHT, "stone", 12.6, "", true},
+		{"Operator", TYPE_NAME_BLOODSUGAR, "mg/dL", "<20", "1.1", false},
+		{"Enumerated type is not checked", TYPE_NAME_URINE_NITRITE, "-", "Pos.", "1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := measurement.Measurement{Type: tt.mType}
+			m.Measurement.Unit = tt.unit
+			m.Measurement.Value = measurement.ValueOf(tt.value)
+
+			normalized, err := NormalizeUnit(types[tt.mType], m)
+			if tt.err {
+				if _, ok := err.(UnitError); !ok {
+					t.Fatalf("Expected unit error got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error %v", err)
+			}
+			if res := types[tt.mType].GetResultText(normalized); res != tt.result {
+				t.Errorf("Expected '%s' got '%s'", tt.result, res)
+			}
+		})
+	}
+}
+
+func TestNormalizeUnitCompositeTypes(t *testing.T) {
+	types := GetOioXdsExportTypes()
+
+	m := measurement.Measurement{Type: TYPE_NAME_BLOOD_PRESSURE}
+	m.Measurement.Unit = "kPa"
+	m.Measurement.Systolic = 16
+	m.Measurement.Diastolic = 10.7
+	normalized, err := NormalizeUnit(types[TYPE_NAME_BLOOD_PRESSURE], m)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	bp := types[TYPE_NAME_BLOOD_PRESSURE].(CompositeType)
+	systolic, _ := bp.GetComponent(measurement.COMPONENT_SYSTOLIC)
+	diastolic, _ := bp.GetComponent(measurement.COMPONENT_DIASTOLIC)
+	if systolic.GetType().GetResultText(normalized) != "120" || diastolic.GetType().GetResultText(normalized) != "80" {
+		t.Errorf("Unexpected blood pressure %v/%v", normalized.Measurement.Systolic, normalized.Measurement.Diastolic)
+	}
+
+	now := time.Now()
+	series := []measurement.SeriesValue{{Timestamp: now, Value: 90}, {Timestamp: now.Add(time.Minute), Value: 180}}
+	m = measurement.Measurement{Type: TYPE_NAME_CONTINUOUS_BLOOD_SUGAR_MEASUREMENT}
+	m.Measurement.Unit = "mg/dL"
+	m.Measurement.Series = series
+	normalized, err = NormalizeUnit(types[TYPE_NAME_CONTINUOUS_BLOOD_SUGAR_MEASUREMENT], m)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if res := types[TYPE_NAME_CONTINUOUS_BLOOD_SUGAR_MEASUREMENT].GetResultText(normalized); res != "7.5" {
+		t.Errorf("Expected mean of 7.5 got %s", res)
+	}
+	if series[0].Value != 90 {
+		t.Errorf("Expected the original series to be left as is")
+	}
+}
+
+func TestConvertValue(t *testing.T) {
+	weight := GetOioXdsExportTypes()[TYPE_NAME_WEIGHT]
+	if v, err := ConvertValue(weight, TYPE_NAME_WEIGHT, "g", 80000); err != nil || v != 80 {
+		t.Errorf("Expected 80 got %v %v", v, err)
+	}
+	if _, err := ConvertValue(weight, TYPE_NAME_WEIGHT, "stone", 12); err == nil {
+		t.Errorf("Expected error for unknown unit")
+	}
+}
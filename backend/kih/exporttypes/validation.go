@@ -0,0 +1,106 @@
+package exporttypes
+
+import (
+	"fmt"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// Reasons a measurement is invalid. They are stored with the measurement, so they must not change
+const (
+	INVALID_MISSING_VALUE = "missing value"
+	INVALID_NOT_NUMERIC   = "value not numeric"
+	INVALID_OUT_OF_RANGE  = "value out of range"
+	INVALID_UNKNOWN_VALUE = "unknown value"
+	// INVALID_UNSUPPORTED_VALUE is a value which cannot be decoded, e.g. a list, or several values where one is expected
+	INVALID_UNSUPPORTED_VALUE = "unsupported value"
+)
+
+// ValidationError is returned for measurements with values that are not plausible for the type
+type ValidationError struct {
+	Type   string
+	Field  string
+	Reason string
+	Value  measurement.Value
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("Invalid %s for %s - %s (%v)", e.Field, e.Type, e.Reason, e.Value)
+}
+
+// valueRules holds the validation rules for a value. Ranges are in the canonical unit, before scaling
+type valueRules struct {
+	min    *float64
+	max    *float64
+	values map[string]string
+	// operators allows values outside the range of the device, e.g. <1.1. The number is validated
+	operators bool
+}
+
+func (r valueRules) check(measurementType, field string, value measurement.Value) error {
+	invalid := func(reason string) error {
+		return ValidationError{Type: measurementType, Field: field, Reason: reason, Value: value}
+	}
+	number := value
+	if r.operators {
+		_, number = splitOperator(value)
+	}
+
+	switch value.Kind() {
+	case measurement.VALUE_NONE:
+		return invalid(INVALID_MISSING_VALUE)
+	case measurement.VALUE_INVALID, measurement.VALUE_COMPOSITE:
+		return invalid(INVALID_UNSUPPORTED_VALUE)
+	}
+	if len(r.values) > 0 {
+		if _, ok := r.values[value.String()]; !ok {
+			return invalid(INVALID_UNKNOWN_VALUE)
+		}
+		return nil
+	}
+
+	v, err := number.Float()
+	if err != nil {
+		return invalid(INVALID_NOT_NUMERIC)
+	}
+	if (r.min != nil && v < *r.min) || (r.max != nil && v > *r.max) {
+		return invalid(INVALID_OUT_OF_RANGE)
+	}
+	return nil
+}
+
+// Validate checks the values of a measurement against the rules of the type. Values are expected in the canonical unit
+func Validate(t MeasurementType, m measurement.Measurement) error {
+	switch exportType := t.(type) {
+	case SimpleType:
+		return exportType.rules.check(m.Type, "value", m.Measurement.Value)
+	case CompositeType:
+		value := m.Measurement.Composite()
+		if len(exportType.GetMeasured(m)) == 0 {
+			reason := INVALID_MISSING_VALUE
+			if !value.IsEmpty() && value.Kind() != measurement.VALUE_COMPOSITE {
+				reason = INVALID_UNSUPPORTED_VALUE
+			}
+			return ValidationError{Type: m.Type, Field: "value", Reason: reason, Value: value}
+		}
+		for _, c := range exportType.components {
+			v := c.value(m)
+			if v.IsEmpty() && c.optional {
+				continue
+			}
+			if err := c.export.rules.check(m.Type, c.name, v); err != nil {
+				return err
+			}
+		}
+	case ContinuousBloodSugarType:
+		if len(m.Measurement.Series) == 0 {
+			return ValidationError{Type: m.Type, Field: "series", Reason: INVALID_MISSING_VALUE}
+		}
+		for _, v := range m.Measurement.Series {
+			if err := exportType.glucose.rules.check(m.Type, "series", measurement.NumericValue(v.Value)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
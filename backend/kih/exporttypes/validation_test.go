@@ -0,0 +1,62 @@
+package exporttypes
+
+import (
+	"testing"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+func TestValidate(t *testing.T) {
+	types := GetOioXdsExportTypes()
+
+	tests := []struct {
+		name   string
+		m      measurement.Measurement
+		reason string
+	}{
+		{"Valid weight", measurement.Measurement{Type: TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(80.5)}}, ""},
+		{"Numeric string", measurement.Measurement{Type: TYPE_NAME_CRP, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("12")}}, ""},
+		{"Missing value", measurement.Measurement{Type: TYPE_NAME_WEIGHT}, INVALID_MISSING_VALUE},
+		{"Empty value", measurement.Measurement{Type: TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("")}}, INVALID_MISSING_VALUE},
+		{"Not numeric", measurement.Measurement{Type: TYPE_NAME_TEMPERATURE, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("warm")}}, INVALID_NOT_NUMERIC},
+		{"Too low", measurement.Measurement{Type: TYPE_NAME_TEMPERATURE, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(3.7)}}, INVALID_OUT_OF_RANGE},
+		{"Too high", measurement.Measurement{Type: TYPE_NAME_SATURATION, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(101)}}, INVALID_OUT_OF_RANGE},
+		{"Unsupported value", measurement.Measurement{Type: TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.ValueOf([]int{80})}}, INVALID_UNSUPPORTED_VALUE},
+		{"Below device range", measurement.Measurement{Type: TYPE_NAME_BLOODSUGAR, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("<1.1")}}, ""},
+		{"Above device range", measurement.Measurement{Type: TYPE_NAME_CRP, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("> 200")}}, ""},
+		{"Operator without number", measurement.Measurement{Type: TYPE_NAME_BLOODSUGAR, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("<LO")}}, INVALID_NOT_NUMERIC},
+		{"Operator out of range", measurement.Measurement{Type: TYPE_NAME_BLOODSUGAR, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue(">60")}}, INVALID_OUT_OF_RANGE},
+		{"Operator not allowed", measurement.Measurement{Type: TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("<50")}}, INVALID_NOT_NUMERIC},
+		{"Known enumeration", measurement.Measurement{Type: TYPE_NAME_URINE_PROTEIN, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("+/-")}}, ""},
+		{"Unknown enumeration", measurement.Measurement{Type: TYPE_NAME_URINE_PROTEIN, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("+5")}}, INVALID_UNKNOWN_VALUE},
+		{"Urine combi", measurement.Measurement{Type: TYPE_NAME_URINE_COMBI, Measurement: measurement.MeasurementValue{Value: measurement.ValueOf(map[string]interface{}{"protein": "+1", "nitrite": "Neg."})}}, ""},
+		{"Urine combi unknown analyte value", measurement.Measurement{Type: TYPE_NAME_URINE_COMBI, Measurement: measurement.MeasurementValue{Value: measurement.ValueOf(map[string]interface{}{"protein": "+1", "glucose": "+7"})}}, INVALID_UNKNOWN_VALUE},
+		{"Urine combi without analytes", measurement.Measurement{Type: TYPE_NAME_URINE_COMBI, Measurement: measurement.MeasurementValue{Value: measurement.ValueOf(map[string]interface{}{"colour": "yellow"})}}, INVALID_MISSING_VALUE},
+		{"Urine combi single value", measurement.Measurement{Type: TYPE_NAME_URINE_COMBI, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("+1")}}, INVALID_UNSUPPORTED_VALUE},
+		{"Spirometry", measurement.Measurement{Type: TYPE_NAME_SPIROMETER, Measurement: measurement.MeasurementValue{Value: measurement.ValueOf(map[string]interface{}{"fev1": 2.95, "fev6": 3.85, "fev1/fev6": 76.6})}}, ""},
+		{"Spirometry missing FEV6", measurement.Measurement{Type: TYPE_NAME_SPIROMETER, Measurement: measurement.MeasurementValue{Value: measurement.ValueOf(map[string]interface{}{"fev1": 2.95, "fev1/fev6": 76.6, "pef": 410})}}, INVALID_MISSING_VALUE},
+		{"Spirometry FEV1 out of range", measurement.Measurement{Type: TYPE_NAME_SPIROMETER, Measurement: measurement.MeasurementValue{Value: measurement.ValueOf(map[string]interface{}{"fev1": 29.5, "fev6": 3.85, "fev1/fev6": 76.6, "pef": 410})}}, INVALID_OUT_OF_RANGE},
+		{"Spirometry single value", measurement.Measurement{Type: TYPE_NAME_SPIROMETER, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(2.95)}}, INVALID_UNSUPPORTED_VALUE},
+		{"Blood pressure", measurement.Measurement{Type: TYPE_NAME_BLOOD_PRESSURE, Measurement: measurement.MeasurementValue{Systolic: 120, Diastolic: 80}}, ""},
+		{"Blood pressure without diastolic", measurement.Measurement{Type: TYPE_NAME_BLOOD_PRESSURE, Measurement: measurement.MeasurementValue{Systolic: 120}}, INVALID_MISSING_VALUE},
+		{"Blood pressure out of range", measurement.Measurement{Type: TYPE_NAME_BLOOD_PRESSURE, Measurement: measurement.MeasurementValue{Systolic: 1200, Diastolic: 80}}, INVALID_OUT_OF_RANGE},
+		{"Continuous without readings", measurement.Measurement{Type: TYPE_NAME_CONTINUOUS_BLOOD_SUGAR_MEASUREMENT}, INVALID_MISSING_VALUE},
+		{"Continuous out of range", measurement.Measurement{Type: TYPE_NAME_CONTINUOUS_BLOOD_SUGAR_MEASUREMENT, Measurement: measurement.MeasurementValue{Series: []measurement.SeriesValue{{Value: 6.2}, {Value: 126}}}}, INVALID_OUT_OF_RANGE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(types[tt.m.Type], tt.m)
+			if len(tt.reason) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error - %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(ValidationError)
+			if !ok || validationErr.Reason != tt.reason {
+				t.Errorf("Expected reason '%s' got %v", tt.reason, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package exporttypes
+
+import (
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// Formats waveforms are attached in
+const (
+	WAVEFORM_FORMAT_AECG    = "aecg"
+	WAVEFORM_FORMAT_CTG_CSV = "ctg-csv"
+)
+
+// WaveformType handles ECG and CTG measurements. The heart rate is exported as a lab report and the waveform as an attachment
+type WaveformType struct {
+	heartRate SimpleType
+	format    string
+}
+
+func (w WaveformType) GetDevices() []MedicalDevice {
+	return w.heartRate.GetDevices()
+}
+func (w WaveformType) IsToBeExported() bool {
+	return w.heartRate.IsToBeExported()
+}
+func (w WaveformType) GetAnalysisText() string {
+	return w.heartRate.GetAnalysisText()
+}
+func (w WaveformType) GetResultUnitText() string {
+	return w.heartRate.GetResultUnitText()
+}
+func (w WaveformType) GetResultText(m measurement.Measurement) string {
+	return w.heartRate.GetResultText(m)
+}
+func (w WaveformType) GetNpuCode() string {
+	return w.heartRate.GetNpuCode()
+}
+
+// GetFormat returns the format the waveform is attached in
+func (w WaveformType) GetFormat() string {
+	return w.format
+}
+
+// NewEcg exports the heart rate of an ECG and attaches the waveform as HL7 aECG
+func NewEcg() WaveformType {
+	s := NewXdsPulseType()
+	s.devices = []MedicalDevice{}
+	return WaveformType{heartRate: s, format: WAVEFORM_FORMAT_AECG}
+}
+
+// NewCtg exports the fetal heart rate of a CTG and attaches the waveform as CSV
+func NewCtg(npuCode, analysisText string) WaveformType {
+	s := SimpleType{}
+	s.npuCode = npuCode
+	s.analysisText = analysisText
+	s.resultUnitText = "1/min"
+	s.decimal = 0
+	s.isAlphaNumeric = false
+	s.isToBeExported = true
+	s.layoutResults = layoutZeroDigit
+	return WaveformType{heartRate: s, format: WAVEFORM_FORMAT_CTG_CSV}
+}
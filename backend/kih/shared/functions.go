@@ -2,6 +2,7 @@ package shared
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
@@ -26,6 +27,8 @@ const (
 	MEASUREMENT_TRANSFERED_BY_HCPROF    = "typedbyhcprof"
 	MEASUREMENT_TRANSFERED_BY_TYPED     = "typed"
 	MEASUREMENT_TRANSFERED_BY_AUTOMATIC = "automatic"
+	MEASURING_DATA_SUMMARY              = "summary"
+	MEASURING_DATA_INTERVAL             = "interval"
 )
 
 // Map OTH Measurment to Laboratory Report structure
@@ -39,6 +42,20 @@ func ReportFromMeasurement(exportedTypes map[string]exporttypes.MeasurementType,
 		return reports, fmt.Errorf("Export type for measurement type %s not found", m.Type)
 	}
 
+	// Control measurements are checked first, as their values are not readings of the patient and need not be plausible
+	circumstances, classification, err := exporttypes.Circumstances(exportType, m)
+	if err != nil {
+		return reports, err
+	}
+	// Values are laid out in the canonical unit of the type. Unit and validation errors are returned as is, so they can be told apart
+	m, err = exporttypes.NormalizeUnit(exportType, m)
+	if err != nil {
+		return reports, err
+	}
+	if err := exporttypes.Validate(exportType, m); err != nil {
+		return reports, err
+	}
+
 	switch exportType.(type) {
 	case exporttypes.SimpleType:
 		reports, err = handleSimpleType(reports, m, mr, exportType)
@@ -46,8 +63,20 @@ func ReportFromMeasurement(exportedTypes map[string]exporttypes.MeasurementType,
 			return reports, fmt.Errorf("Error converting to format - %v", err)
 		}
 
-	case exporttypes.BloodPressureType:
-		reports, err = handleBloodPressure(reports, m, mr, exportType)
+	case exporttypes.CompositeType:
+		reports, err = handleComposite(reports, m, mr, exportType)
+		if err != nil {
+			return reports, fmt.Errorf("Error converting to format - %v", err)
+		}
+
+	case exporttypes.WaveformType:
+		reports, err = handleWaveform(reports, m, mr, exportType)
+		if err != nil {
+			return reports, fmt.Errorf("Error converting to format - %v", err)
+		}
+
+	case exporttypes.ContinuousBloodSugarType:
+		reports, err = handleContinuousBloodSugar(reports, m, mr, exportType)
 		if err != nil {
 			return reports, fmt.Errorf("Error converting to format - %v", err)
 		}
@@ -55,6 +84,19 @@ func ReportFromMeasurement(exportedTypes map[string]exporttypes.MeasurementType,
 		return reports, fmt.Errorf("Unknown measurement type for %v", exportType)
 	}
 
+	for i := range reports {
+		if len(circumstances) > 0 {
+			reports[i].MeasuringCircumstances = circumstances
+		}
+		if len(classification) > 0 {
+			reports[i].MeasuringDataClassification = classification
+		}
+		// The origin overrides the location, e.g. for measurements taken at the clinic
+		if len(m.Origin.Location) > 0 {
+			reports[i].MeasurementLocation = m.Origin.Location
+		}
+	}
+
 	log.Debug("Returning - # of reports ", len(reports))
 	return reports, nil
 }
@@ -64,24 +106,15 @@ func measurmentToInstrument(exportType exporttypes.MeasurementType, m measuremen
 	log.Debug("mapping origin ", m.Origin.DeviceMeasurement)
 	var instrument *InstrumentType
 
-	var device exporttypes.MedicalDevice
-	for _, d := range exportType.GetDevices() {
-		log.Debug("Found - ", d)
-		if d.CheckIfSameModel(m.Origin) {
-			log.Debug("Origin", m.Origin, " MATCHES", d)
-			device = d
-		} else {
-			log.Debug("Origin", m.Origin, " does not match", d)
-		}
-	}
-	if nil != device {
-		log.Debug("Device found")
+	device, found := exporttypes.MatchDevice(exportType.GetDevices(), m.Origin)
+	if found {
+		log.Debug("Origin", m.Origin, " MATCHES", device)
 		instrument = &InstrumentType{}
 		instrument.SoftwareVersion = device.GetManufacturer()
 		instrument.MedComID = device.GetMedcomID()
 		instrument.Model = device.GetModel()
 	} else {
-		log.Debug("Value of device is ", device)
+		log.Debug("Origin", m.Origin, " does not match any device")
 	}
 
 	return instrument
@@ -169,38 +202,18 @@ func performGenericMapping(lr *LaboratoryReportExtended) {
 	lr.ResultTypeOfInterval = UNSPECIFIED
 }
 
-// func performMeasurementMapping(m measurement.Measurement, lr *LaboratoryReportExtended) error {
-// 	exportType, ok := exportedTypes[m.Type]
-
-// 	if !ok {
-// 		return fmt.Errorf("Export type for measurement type %s not found", m.Type)
-// 	}
-
-// 	switch exportType.(type) {
-// 	case exporttypes.SimpleType:
-// 		err := performSimpleMapping(m, lr, exportType)
-// 	case exporttypes.BloodPressureType:
-// 		t := exportType.(exporttypes.BloodPressureType)
-// 		return t.GetNpuCodes()
-// 	default:
-// 		return fmt.Errorf("Unknown measurement type for %v", exportType)
-// 	}
-
-// 	lr.AnalysisText = m.Type
-// 	lr.ResultUnitText = m.Measurement.Unit
+// performResultMapping lays out the result of a type with a single result, with its encoding and operator
+func performResultMapping(exportType exporttypes.MeasurementType, lr *LaboratoryReportExtended, m measurement.Measurement) {
+	lr.ResultText = exportType.GetResultText(m)
+	lr.ResultEncodingIdentifier = RESULT_ENCODING_NUMERIC
 
-// 	lr.ResultText = fmt.Sprintf("%f", m.Measurement.Value)
-
-// 	/*
-// 	   if (isAlphanumeric) {
-// 	       type.resultEncodingIdentifier = EncodingIdentifierType.ALPHANUMERIC
-// 	   } else {
-// 	       type.resultEncodingIdentifier = EncodingIdentifierType.NUMERIC
-// 	   }
-// 	*/
-
-// 	return nil
-// }
+	if t, ok := exportType.(exporttypes.SimpleType); ok {
+		if t.IsAlphaNumeric() {
+			lr.ResultEncodingIdentifier = RESULT_ENCODING_ALPHANUMERIC
+		}
+		lr.ResultOperatorIdentifier = t.GetResultOperator(m)
+	}
+}
 
 func performBaseMapping(exportType exporttypes.MeasurementType, lr *LaboratoryReportExtended, m measurement.Measurement, mr repository.MeasurementExportState) {
 	lr.UuidIdentifier = mr.ID.String()
@@ -220,43 +233,121 @@ func handleSimpleType(reports []LaboratoryReportExtended, m measurement.Measurem
 
 	r.AnalysisText = exportType.GetAnalysisText()
 	r.ResultUnitText = exportType.GetResultUnitText()
-	r.ResultText = exportType.GetResultText(m)
+	performResultMapping(exportType, &r, m)
 
 	reports = append(reports, r)
 
 	return reports, nil
 }
 
-func handleBloodPressure(reports []LaboratoryReportExtended, m measurement.Measurement, mr repository.MeasurementExportState, exportType exporttypes.MeasurementType) ([]LaboratoryReportExtended, error) {
-	// var err error
-	t := exportType.(exporttypes.BloodPressureType)
-	systolic := LaboratoryReportExtended{}
-	diastolic := LaboratoryReportExtended{}
-	performBaseMapping(exportType, &systolic, m, mr)
-	performGenericMapping(&systolic)
-	if err := handleOrigin(exportType, m, &systolic); err != nil {
-		return reports, errors.Wrap(err, fmt.Sprintf("Error parsing origin %v", err))
+// componentReportID returns the id of the report of a component. The first declared component keeps the id of the measurement,
+// the others get ids derived from it and the component, so the ids do not depend on which components are present
+func componentReportID(id uuid.UUID, t exporttypes.CompositeType, component string) string {
+	if components := t.GetComponents(); len(components) > 0 && components[0].GetName() == component {
+		return id.String()
+	}
+	return uuid.NewSHA1(id, []byte(component)).String()
+}
+
+// handleComposite reports each component present in the measurement on its own, e.g. systolic and diastolic pressure.
+// The reports share the time of the measurement and the instrument
+func handleComposite(reports []LaboratoryReportExtended, m measurement.Measurement, mr repository.MeasurementExportState, exportType exporttypes.MeasurementType) ([]LaboratoryReportExtended, error) {
+	t := exportType.(exporttypes.CompositeType)
+	for _, c := range t.GetMeasured(m) {
+		r := LaboratoryReportExtended{}
+		performBaseMapping(exportType, &r, m, mr)
+		performGenericMapping(&r)
+		if err := handleOrigin(exportType, m, &r); err != nil {
+			return reports, errors.Wrap(err, fmt.Sprintf("Error parsing origin %v", err))
+		}
+
+		r.UuidIdentifier = componentReportID(mr.ID, t, c.GetName())
+		r.IupacIdentifier = c.GetType().GetNpuCode()
+		r.AnalysisText = c.GetType().GetAnalysisText()
+		r.ResultUnitText = c.GetType().GetResultUnitText()
+		performResultMapping(c.GetType(), &r, m)
+
+		reports = append(reports, r)
 	}
 
-	systolic.IupacIdentifier = t.GetSystolic().GetNpuCode()
-	systolic.AnalysisText = t.GetSystolic().GetAnalysisText()
-	systolic.ResultUnitText = t.GetSystolic().GetResultUnitText()
-	systolic.ResultText = t.GetSystolic().GetResultText(m)
+	return reports, nil
+}
 
-	performBaseMapping(exportType, &diastolic, m, mr)
-	performGenericMapping(&diastolic)
-	if err := handleOrigin(exportType, m, &diastolic); err != nil {
-		return reports, errors.Wrap(err, fmt.Sprintf("Error parsing origin %v", err))
+// formatDuration lays out a duration as ISO 8601, e.g. PT1H30M
+func formatDuration(d time.Duration) string {
+	var sb strings.Builder
+	sb.WriteString("PT")
+
+	d = d.Round(time.Second)
+	if h := d / time.Hour; h > 0 {
+		sb.WriteString(fmt.Sprintf("%dH", h))
+	}
+	if m := (d % time.Hour) / time.Minute; m > 0 {
+		sb.WriteString(fmt.Sprintf("%dM", m))
+	}
+	if s := (d % time.Minute) / time.Second; s > 0 || d == 0 {
+		sb.WriteString(fmt.Sprintf("%dS", s))
+	}
+	return sb.String()
+}
+
+// handleContinuousBloodSugar reports a CGM series as a summary of the full series followed by a report per interval
+func handleContinuousBloodSugar(reports []LaboratoryReportExtended, m measurement.Measurement, mr repository.MeasurementExportState, exportType exporttypes.MeasurementType) ([]LaboratoryReportExtended, error) {
+	t := exportType.(exporttypes.ContinuousBloodSugarType)
+	if len(m.Measurement.Series) == 0 {
+		return reports, fmt.Errorf("No readings in continuous measurement %s", m.Links.Measurement)
+	}
+
+	newReport := func(summary measurement.SeriesSummary, classification string) (LaboratoryReportExtended, error) {
+		r := LaboratoryReportExtended{}
+		performBaseMapping(exportType, &r, m, mr)
+		performGenericMapping(&r)
+		if err := handleOrigin(exportType, m, &r); err != nil {
+			return r, errors.Wrap(err, fmt.Sprintf("Error parsing origin %v", err))
+		}
+
+		r.CreatedDateTime = summary.Start.Format(time.RFC3339)
+		r.AnalysisText = t.GetAnalysisText()
+		r.ResultUnitText = t.GetResultUnitText()
+		r.ResultText = t.FormatResult(summary.Mean)
+		r.ResultEncodingIdentifier = RESULT_ENCODING_NUMERIC
+		r.MeasurementDuration = formatDuration(summary.Duration())
+		r.MeasuringDataClassification = classification
+		return r, nil
 	}
 
-	diastolic.UuidIdentifier = uuid.New().String()
-	diastolic.IupacIdentifier = t.GetDiastolic().GetNpuCode()
-	diastolic.AnalysisText = t.GetDiastolic().GetAnalysisText()
-	diastolic.ResultUnitText = t.GetDiastolic().GetResultUnitText()
-	diastolic.ResultText = t.GetDiastolic().GetResultText(m)
+	summary, err := newReport(t.GetSummary(m), MEASURING_DATA_SUMMARY)
+	if err != nil {
+		return reports, err
+	}
+	reports = append(reports, summary)
 
-	reports = append(reports, systolic)
-	reports = append(reports, diastolic)
+	for _, interval := range t.GetIntervals(m) {
+		r, err := newReport(interval, MEASURING_DATA_INTERVAL)
+		if err != nil {
+			return reports, err
+		}
+		// Interval reports get stable ids derived from the measurement
+		r.UuidIdentifier = uuid.NewSHA1(mr.ID, []byte(interval.Start.Format(time.RFC3339))).String()
+		reports = append(reports, r)
+	}
 
 	return reports, nil
 }
+
+// handleWaveform reports the heart rate of an ECG or CTG. The waveform itself is attached by the backend
+func handleWaveform(reports []LaboratoryReportExtended, m measurement.Measurement, mr repository.MeasurementExportState, exportType exporttypes.MeasurementType) ([]LaboratoryReportExtended, error) {
+	if m.Measurement.Value.IsEmpty() {
+		return reports, fmt.Errorf("No heart rate for %s", m.Links.Measurement)
+	}
+
+	reports, err := handleSimpleType(reports, m, mr, exportType)
+	if err != nil {
+		return reports, err
+	}
+
+	if m.Measurement.Duration > 0 {
+		reports[len(reports)-1].MeasurementDuration = formatDuration(time.Duration(m.Measurement.Duration * float64(time.Second)))
+	}
+	return reports, nil
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -194,8 +195,9 @@ func simpleValidation(t *testing.T, exportedTypes map[string]exporttypes.Measure
 	if lr.UuidIdentifier != mt.ID.String() {
 		t.Errorf("Expected '%s' got '%s'", mt.ID, lr.UuidIdentifier)
 	}
-	if lr.ResultText != strconv.FormatFloat(float64(mm.Measurement.Value.(float64)), 'f', 1, 64) {
-		t.Errorf("Expected %f - got %s", mm.Measurement.Value, lr.ResultText)
+	number, err := mm.Measurement.Value.Float()
+	if err != nil || lr.ResultText != strconv.FormatFloat(number, 'f', 1, 64) {
+		t.Errorf("Expected %s - got %s", mm.Measurement.Value, lr.ResultText)
 	}
 
 	if lr.IupacIdentifier != exportType.GetNpuCode() {
@@ -298,57 +300,57 @@ func TestReportFromMeasurementSimpleTypes(t *testing.T) {
 		{"FEV1",
 			measurement.Measurement{Type: exporttypes.TYPE_NAME_FEV1,
 				Timestamp:   now,
-				Measurement: measurement.MeasurementValue{Unit: "%", Value: 4.98}},
+				Measurement: measurement.MeasurementValue{Unit: "L", Value: measurement.NumericValue(4.98)}},
 			"MCS88015", true, "4.98", "L", "Lunge—Lungefunktionsundersøgelse FEV1; vol. = ? L"},
 		{"Weight",
 			measurement.Measurement{Type: exporttypes.TYPE_NAME_WEIGHT,
 				Timestamp:   now,
-				Measurement: measurement.MeasurementValue{Unit: "kg", Value: 98}},
+				Measurement: measurement.MeasurementValue{Unit: "kg", Value: measurement.NumericValue(98)}},
 			"NPU03804", true, "98.0", "kg", "Pt—Legeme; masse = ? kg"},
 		{"Temperature",
 			measurement.Measurement{Type: exporttypes.TYPE_NAME_TEMPERATURE,
 				Timestamp:   now,
-				Measurement: measurement.MeasurementValue{Unit: "M", Value: 37.0}},
+				Measurement: measurement.MeasurementValue{Unit: "°C", Value: measurement.NumericValue(37.0)}},
 			"NPU08676", true, "37.0", "°C", "Pt—Legeme; temp. = ? °C"},
 		{"Saturation",
 			measurement.Measurement{Type: exporttypes.TYPE_NAME_SATURATION,
 				Timestamp:   now,
-				Measurement: measurement.MeasurementValue{Unit: "%", Value: 98}},
+				Measurement: measurement.MeasurementValue{Unit: "%", Value: measurement.NumericValue(98)}},
 			"NPU03011", true, "0.98", "", "Hb(Fe; O2-bind.; aB)—Oxygen(O2); mætn. = ?"},
 		{"Pulse",
 			measurement.Measurement{Type: exporttypes.TYPE_NAME_PULSE,
 				Timestamp:   now,
-				Measurement: measurement.MeasurementValue{Unit: "BPM", Value: 58}},
+				Measurement: measurement.MeasurementValue{Unit: "BPM", Value: measurement.NumericValue(58)}},
 			"NPU21692", true, "58", "x 1/min", "Hjerte—Systole; frekv. = ? × 1/min"},
 		{"BloodSugar",
 			measurement.Measurement{Type: exporttypes.TYPE_NAME_BLOODSUGAR,
 				Timestamp:   now,
-				Measurement: measurement.MeasurementValue{Unit: "%", Value: 8.4}},
+				Measurement: measurement.MeasurementValue{Unit: "mmol/L", Value: measurement.NumericValue(8.4)}},
 			"NPU22089", true, "8.4", "mmol/L", "P(kB)—Glucose; stofk. = ? mmol/L"},
 		// {"Urine Glucose",
 		// 	measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_GLUCOSE,
 		// 		Timestamp:   now,
-		// 		Measurement: measurement.MeasurementValue{Unit: "%", Value: "+2"}},
+		// 		Measurement: measurement.MeasurementValue{Unit: "%", Value: measurement.EnumeratedValue("+2")}},
 		// 	"NPU04207", true, "28", "mg/L", "P—C-reaktivt protein; massek. = ? mg/l"},
 		// {"Urine Nitrite",
 		// 	measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_NITRITE,
 		// 		Timestamp:   now,
-		// 		Measurement: measurement.MeasurementValue{Unit: "%", Value: "+2"}},
+		// 		Measurement: measurement.MeasurementValue{Unit: "%", Value: measurement.EnumeratedValue("+2")}},
 		// 	"NPU21578", true, "28", "mg/L", "P—C-reaktivt protein; massek. = ? mg/L"},
 		// {"Urine Leukocytes",
 		// 	measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_LEUKOCYTES,
 		// 		Timestamp:   now,
-		// 		Measurement: measurement.MeasurementValue{Unit: "%", Value: "+2"}},
+		// 		Measurement: measurement.MeasurementValue{Unit: "%", Value: measurement.EnumeratedValue("+2")}},
 		// 	"NPU03978", true, "28", "mg/L", "P—C-reaktivt protein; massek. = ? mg/L"},
 		{"CRP",
 			measurement.Measurement{Type: exporttypes.TYPE_NAME_CRP,
 				Timestamp:   now,
-				Measurement: measurement.MeasurementValue{Unit: "%", Value: 28}},
+				Measurement: measurement.MeasurementValue{Unit: "mg/L", Value: measurement.NumericValue(28)}},
 			"NPU19748", true, "28", "mg/L", "P—C-reaktivt protein; massek. = ? mg/L"},
 		// {"Urine Erythrocytes",
 		// 	measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_ERYTHROCYTES,
 		// 		Timestamp:   now,
-		// 		Measurement: measurement.MeasurementValue{Unit: "%", Value: 28}},
+		// 		Measurement: measurement.MeasurementValue{Unit: "%", Value: measurement.NumericValue(28)}},
 		// 	"NPU03963", true, "28", "mg/L", "P—C-reaktivt protein; massek. = ? mg/L"},
 		// {"Blood Pressure", fields{}, args{m: measurement.Measurement{Type: exporttypes.TYPE_NAME_BLOOD_PRESSURE}}, "DNK05472,DNK05473", true},
 		// //{"Blood Pressure Diastolic", fields{}, args{m: measurement.Measurement{Type: exporttypes.TYPE_NAME_BLOOD_PRESSURE_DIASTOLIC}}, "DNK05473", true},
@@ -437,6 +439,7 @@ func TestReportFromMeasurementSimpleTypesFromFile(t *testing.T) {
 		{"Urine Leukocytes Plus Four", "testdata/leukocytes_in_urine_plus_four.json", false, "NPU03987", true, "3", "", "U—Leukocytter; arb.k.(proc.) = ?"},
 		// blood_urine
 		{"Urine Blood Negative", "testdata/blood_in_urine_negative.json", false, "NPU03963", true, "0", "", "U—Erythrocytter; arb.k.(proc.) = ?"},
+		{"Urine Blood Plus Minus", "testdata/blood_in_urine_plus_minus.json", false, "NPU03963", true, "0", "", "U—Erythrocytter; arb.k.(proc.) = ?"},
 		{"Urine Blood Plus One", "testdata/blood_in_urine_plus_one.json", false, "NPU03963", true, "1", "", "U—Erythrocytter; arb.k.(proc.) = ?"},
 		{"Urine blood Plus Two", "testdata/blood_in_urine_plus_two.json", false, "NPU03963", true, "2", "", "U—Erythrocytter; arb.k.(proc.) = ?"},
 		{"Urine blood Plus Three", "testdata/blood_in_urine_plus_three.json", false, "NPU03963", true, "3", "", "U—Erythrocytter; arb.k.(proc.) = ?"},
@@ -505,6 +508,53 @@ func TestReportFromMeasurementSimpleTypesFromFile(t *testing.T) {
 	}
 }
 
+func TestReportFromMeasurementMonitoringTypes(t *testing.T) {
+	now := time.Now()
+	typetests := []struct {
+		name         string
+		typeName     string
+		unit         string
+		value        float64
+		npu          string
+		result       string
+		resultUnit   string
+		analysisText string
+	}{
+		{"Height", "height", "cm", 178, "NPU03794", "1.78", "m", "Pt—Legeme; længde = ? m"},
+		{"Hemoglobin", "hemoglobin", "mmol/L", 8.4, "NPU02319", "8.4", "mmol/L", "B—Hæmoglobin(Fe); stofk. = ? mmol/L"},
+	}
+	for _, tt := range typetests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := repository.MeasurementExportState{ID: uuid.New()}
+			m := measurement.Measurement{Timestamp: now, Type: tt.typeName}
+			m.Measurement.Unit = tt.unit
+			m.Measurement.Value = measurement.NumericValue(tt.value)
+			m.Origin.ManualMeasurement.EnteredBy = "citizen"
+
+			reports, err := ReportFromMeasurement(exporttypes.GetKihdbExportTypes(), m, mr)
+			if err != nil {
+				t.Fatalf("Error converting measurement %v", err)
+			}
+			if len(reports) != 1 {
+				t.Fatalf("Expected 1 report got %d", len(reports))
+			}
+			report := reports[0]
+			if report.ResultText != tt.result {
+				t.Errorf("Expected '%s' got '%s'", tt.result, report.ResultText)
+			}
+			if report.ResultUnitText != tt.resultUnit {
+				t.Errorf("Expected '%s' got '%s'", tt.resultUnit, report.ResultUnitText)
+			}
+			if report.IupacIdentifier != tt.npu {
+				t.Errorf("Expected '%s' got '%s'", tt.npu, report.IupacIdentifier)
+			}
+			if report.AnalysisText != tt.analysisText {
+				t.Errorf("Expected '%s' got '%s'", tt.analysisText, report.AnalysisText)
+			}
+		})
+	}
+}
+
 func TestReportFromMeasurementComplexTypesFromFile(t *testing.T) {
 	now := time.Now()
 	typetests := []struct {
@@ -518,6 +568,11 @@ func TestReportFromMeasurementComplexTypesFromFile(t *testing.T) {
 		analysisText []string
 	}{
 		{"Blood Pressure", "testdata/blood_pressure.json", false, []string{"DNK05472", "DNK05473"}, true, []string{"130", "80"}, []string{"mmHg", "mmHg"}, []string{"Arm—Blodtryk(systolisk); tryk = ? mmHg", "Arm—Blodtryk(diastolisk); tryk = ? mmHg"}},
+		{"Urine combi", "testdata/urine_measurement.json", false, []string{"NPU04206", "NPU04207", "NPU03987", "NPU21578", "NPU03963"}, true, []string{"1", "0", "2", "1", "1"}, []string{"", "", "", "", ""}, []string{"U—Protein; arb.k.(proc.) = ?", "U—Glucose; arb.k.(proc.) = ?", "U—Leukocytter; arb.k.(proc.) = ?", "U—Nitrit; arb.k.(proc.) = ?", "U—Erythrocytter; arb.k.(proc.) = ?"}},
+		{"Urine combi partial", "testdata/urine_measurement_partial.json", false, []string{"NPU04206", "NPU21578"}, true, []string{"3", "0"}, []string{"", ""}, []string{"U—Protein; arb.k.(proc.) = ?", "U—Nitrit; arb.k.(proc.) = ?"}},
+		{"Spirometry peak flow ignored", "testdata/spirometry.json", false, []string{"MCS88015", "MCS88100", "MCS88099"}, true, []string{"2.95", "3.85", "0.77"}, []string{"L", "L", ""}, []string{"Lunge—Lungefunktionsundersøgelse FEV1; vol. = ? L", "Lunge—Lungefunktionsundersøgelse COPD FEV6; vol. = ? L", "Lunge—FEV1/FEV6 ratio = ?"}},
+		{"Spirometry without peak flow", "testdata/spirometry_without_pef.json", false, []string{"MCS88015", "MCS88100", "MCS88099"}, true, []string{"2.10", "3.50", "0.60"}, []string{"L", "L", ""}, []string{"Lunge—Lungefunktionsundersøgelse FEV1; vol. = ? L", "Lunge—Lungefunktionsundersøgelse COPD FEV6; vol. = ? L", "Lunge—FEV1/FEV6 ratio = ?"}},
+		{"Spirometry without FEV6", "testdata/invalid/spirometry_without_fev6.json", true, []string{}, true, []string{}, []string{}, []string{}},
 	}
 	for _, tt := range typetests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -556,6 +611,9 @@ func TestReportFromMeasurementComplexTypesFromFile(t *testing.T) {
 				if report.ResultUnitText != tt.unit[i] {
 					t.Errorf("Expected '%s' got '%s'", tt.unit[i], report.ResultUnitText)
 				}
+				if report.ResultText != tt.value[i] {
+					t.Errorf("Expected '%s' got '%s'", tt.value[i], report.ResultText)
+				}
 
 				if report.IupacIdentifier != tt.npu[i] {
 					t.Errorf("Expected '%s' got '%s'", tt.npu[i], report.IupacIdentifier)
@@ -572,3 +630,347 @@ func TestReportFromMeasurementComplexTypesFromFile(t *testing.T) {
 		})
 	}
 }
+
+func TestReportFromCompositeIdentifiers(t *testing.T) {
+	tests := []struct {
+		datafile string
+		reports  int
+	}{
+		{"testdata/blood_pressure.json", 2},
+		{"testdata/urine_measurement.json", 5},
+		{"testdata/spirometry.json", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.datafile, func(t *testing.T) {
+			inputdata, err := ioutil.ReadFile(tt.datafile)
+			if err != nil {
+				t.Fatalf("Error reading file %v", err)
+			}
+			var m measurement.Measurement
+			if err := json.Unmarshal(inputdata, &m); err != nil {
+				t.Fatalf("Error converting measurement - %v", err)
+			}
+
+			mr := repository.MeasurementExportState{ID: uuid.New()}
+			first, err := ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), m, mr)
+			if err != nil {
+				t.Fatalf("Error converting measurement %v", err)
+			}
+			again, err := ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), m, mr)
+			if err != nil {
+				t.Fatalf("Error converting measurement %v", err)
+			}
+			if len(first) != tt.reports {
+				t.Fatalf("Expected %d reports got %d", tt.reports, len(first))
+			}
+
+			if first[0].UuidIdentifier != mr.ID.String() {
+				t.Errorf("Expected first report to have the measurement id - got %s", first[0].UuidIdentifier)
+			}
+			seen := make(map[string]bool)
+			for i, r := range first {
+				if seen[r.UuidIdentifier] {
+					t.Errorf("Report id %s is used more than once", r.UuidIdentifier)
+				}
+				seen[r.UuidIdentifier] = true
+				if again[i].UuidIdentifier != r.UuidIdentifier {
+					t.Errorf("Expected the same id when exported again - got %s and %s", r.UuidIdentifier, again[i].UuidIdentifier)
+				}
+			}
+		})
+	}
+}
+
+func TestReportFromCompositeIdentifiersMissingComponent(t *testing.T) {
+	mr := repository.MeasurementExportState{ID: uuid.New()}
+	full := measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_COMBI}
+	full.Measurement.Value = measurement.ValueOf(map[string]interface{}{"protein": "+1", "glucose": "Neg.", "nitrite": "Pos."})
+	partial := measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_COMBI}
+	partial.Measurement.Value = measurement.ValueOf(map[string]interface{}{"glucose": "Neg.", "nitrite": "Pos."})
+
+	fullReports, err := ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), full, mr)
+	if err != nil {
+		t.Fatalf("Error converting measurement %v", err)
+	}
+	partialReports, err := ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), partial, mr)
+	if err != nil {
+		t.Fatalf("Error converting measurement %v", err)
+	}
+
+	// The id of a component does not change when an earlier component is missing
+	ids := make(map[string]string)
+	for _, r := range fullReports {
+		ids[r.IupacIdentifier] = r.UuidIdentifier
+	}
+	for _, r := range partialReports {
+		if r.UuidIdentifier == mr.ID.String() {
+			t.Errorf("Expected only the first declared component to have the measurement id - got %s", r.IupacIdentifier)
+		}
+		if ids[r.IupacIdentifier] != r.UuidIdentifier {
+			t.Errorf("Expected %s to keep id %s - got %s", r.IupacIdentifier, ids[r.IupacIdentifier], r.UuidIdentifier)
+		}
+	}
+}
+
+func TestReportFromSpirometry(t *testing.T) {
+	inputdata, err := ioutil.ReadFile("testdata/spirometry.json")
+	if err != nil {
+		t.Fatalf("Error reading file %v", err)
+	}
+	var m measurement.Measurement
+	if err := json.Unmarshal(inputdata, &m); err != nil {
+		t.Fatalf("Error converting measurement - %v", err)
+	}
+
+	mr := repository.MeasurementExportState{ID: uuid.New()}
+	reports, err := ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), m, mr)
+	if err != nil {
+		t.Fatalf("Error converting measurement %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("Expected 3 reports got %d", len(reports))
+	}
+
+	if reports[0].UuidIdentifier != mr.ID.String() {
+		t.Errorf("Expected first report to have the measurement id - got %s", reports[0].UuidIdentifier)
+	}
+	seen := make(map[string]bool)
+	for _, r := range reports {
+		if seen[r.UuidIdentifier] {
+			t.Errorf("Report id %s is used more than once", r.UuidIdentifier)
+		}
+		seen[r.UuidIdentifier] = true
+		if r.CreatedDateTime != "2020-05-27T10:41:05+02:00" {
+			t.Errorf("Expected the time of the test got %s", r.CreatedDateTime)
+		}
+		if r.Instrument == nil || r.Instrument.MedComID != "MCI00014" {
+			t.Errorf("Expected the lung monitor as instrument got %+v", r.Instrument)
+		}
+		if r.MeasurementTransferredBy != MEASUREMENT_TRANSFERED_BY_AUTOMATIC {
+			t.Errorf("Expected automatic transfer got %s", r.MeasurementTransferredBy)
+		}
+	}
+}
+
+func TestReportFromBloodSugarCircumstances(t *testing.T) {
+	tests := []struct {
+		datafile       string
+		circumstances  string
+		classification string
+	}{
+		{"testdata/bloodsugar.json", "", ""},
+		{"testdata/bloodsugar_before_meal.json", "Før måltid", ""},
+		{"testdata/bloodsugar_after_meal.json", "Efter måltid", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.datafile, func(t *testing.T) {
+			inputdata, err := ioutil.ReadFile(tt.datafile)
+			if err != nil {
+				t.Fatalf("Error reading file %v", err)
+			}
+			var m measurement.Measurement
+			if err := json.Unmarshal(inputdata, &m); err != nil {
+				t.Fatalf("Error converting measurement - %v", err)
+			}
+
+			reports, err := ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), m, repository.MeasurementExportState{ID: uuid.New()})
+			if err != nil {
+				t.Fatalf("Error converting measurement %v", err)
+			}
+			if reports[0].MeasuringCircumstances != tt.circumstances || reports[0].MeasuringDataClassification != tt.classification {
+				t.Errorf("Expected '%s' '%s' got '%s' '%s'", tt.circumstances, tt.classification, reports[0].MeasuringCircumstances, reports[0].MeasuringDataClassification)
+			}
+		})
+	}
+}
+
+func TestReportFromBloodSugarControl(t *testing.T) {
+	inputdata, err := ioutil.ReadFile("testdata/bloodsugar_control.json")
+	if err != nil {
+		t.Fatalf("Error reading file %v", err)
+	}
+	var m measurement.Measurement
+	if err := json.Unmarshal(inputdata, &m); err != nil {
+		t.Fatalf("Error converting measurement - %v", err)
+	}
+
+	_, err = ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), m, repository.MeasurementExportState{ID: uuid.New()})
+	if _, ok := err.(exporttypes.ControlMeasurementError); !ok {
+		t.Errorf("Expected control measurement to be excluded - got %v", err)
+	}
+
+	// A control reading out of range is still a control measurement, not an invalid one
+	outOfRange := m
+	outOfRange.Measurement.Value = measurement.NumericValue(80)
+	_, err = ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), outOfRange, repository.MeasurementExportState{ID: uuid.New()})
+	if _, ok := err.(exporttypes.ControlMeasurementError); !ok {
+		t.Errorf("Expected control measurement out of range to be excluded - got %v", err)
+	}
+
+	// Catalogs can flag control measurements instead
+	catalog, err := exporttypes.ParseCatalog([]byte(`types:
+  - name: bloodsugar
+    npu: NPU22089
+    unit: mmol/L
+    analysistext: P(kB)—Glucose; stofk. = ? mmol/L
+    decimals: 1
+    circumstances:
+      control: flag
+      classification: control
+`), exporttypes.DefaultDeviceRegistry())
+	if err != nil {
+		t.Fatalf("Error parsing catalog %v", err)
+	}
+	reports, err := ReportFromMeasurement(catalog.ExportTypes(exporttypes.BACKEND_OIOXDS), m, repository.MeasurementExportState{ID: uuid.New()})
+	if err != nil {
+		t.Fatalf("Error converting measurement %v", err)
+	}
+	if reports[0].MeasuringDataClassification != "control" {
+		t.Errorf("Expected control measurement to be flagged - got '%s'", reports[0].MeasuringDataClassification)
+	}
+}
+
+func TestReportResultEncoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        measurement.Measurement
+		encoding []string
+		operator []string
+		result   []string
+	}{
+		{"Numeric", measurement.Measurement{Type: exporttypes.TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(80.5)}}, []string{RESULT_ENCODING_NUMERIC}, []string{""}, []string{"80.5"}},
+		{"Below device range", measurement.Measurement{Type: exporttypes.TYPE_NAME_BLOODSUGAR, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("<1.1")}}, []string{RESULT_ENCODING_NUMERIC}, []string{exporttypes.RESULT_OPERATOR_LESS_THAN}, []string{"1.1"}},
+		{"Enumerated", measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_NITRITE, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("Pos.")}}, []string{RESULT_ENCODING_NUMERIC}, []string{""}, []string{"1"}},
+		{"Urine combi", measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_COMBI, Measurement: measurement.MeasurementValue{Value: measurement.ValueOf(map[string]interface{}{"protein": "+1", "glucose": "Neg."})}}, []string{RESULT_ENCODING_NUMERIC, RESULT_ENCODING_NUMERIC}, []string{"", ""}, []string{"1", "0"}},
+		{"Blood pressure", measurement.Measurement{Type: exporttypes.TYPE_NAME_BLOOD_PRESSURE, Measurement: measurement.MeasurementValue{Systolic: 120, Diastolic: 80}}, []string{RESULT_ENCODING_NUMERIC, RESULT_ENCODING_NUMERIC}, []string{"", ""}, []string{"120", "80"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), tt.m, repository.MeasurementExportState{ID: uuid.New()})
+			if err != nil {
+				t.Fatalf("Error converting measurement %v", err)
+			}
+			if len(reports) != len(tt.result) {
+				t.Fatalf("Expected %d reports got %d", len(tt.result), len(reports))
+			}
+			for i, r := range reports {
+				if r.ResultEncodingIdentifier != tt.encoding[i] || r.ResultOperatorIdentifier != tt.operator[i] || r.ResultText != tt.result[i] {
+					t.Errorf("Expected %s '%s' %s got %s '%s' %s", tt.encoding[i], tt.operator[i], tt.result[i], r.ResultEncodingIdentifier, r.ResultOperatorIdentifier, r.ResultText)
+				}
+			}
+		})
+	}
+}
+
+func TestReportFromContinuousBloodSugar(t *testing.T) {
+	inputdata, err := ioutil.ReadFile("testdata/continuous_blood_sugar.json")
+	if err != nil {
+		t.Fatalf("Error reading file %v", err)
+	}
+	var m measurement.Measurement
+	if err := json.Unmarshal(inputdata, &m); err != nil {
+		t.Fatalf("Error converting measurement - %v", err)
+	}
+
+	mr := repository.MeasurementExportState{ID: uuid.New()}
+	reports, err := ReportFromMeasurement(exporttypes.GetKihdbExportTypes(), m, mr)
+	if err != nil {
+		t.Fatalf("Error converting measurement - %v", err)
+	}
+
+	expected := []struct {
+		classification string
+		value          string
+		duration       string
+		created        string
+	}{
+		{MEASURING_DATA_SUMMARY, "6.7", "PT1H30M", "2020-06-04T10:00:00+02:00"},
+		{MEASURING_DATA_INTERVAL, "6.1", "PT1H", "2020-06-04T10:00:00+02:00"},
+		{MEASURING_DATA_INTERVAL, "7.6", "PT1H", "2020-06-04T11:00:00+02:00"},
+	}
+	if len(reports) != len(expected) {
+		t.Fatalf("Expected %d reports got %d", len(expected), len(reports))
+	}
+
+	for i, e := range expected {
+		r := reports[i]
+		if r.MeasuringDataClassification != e.classification || r.ResultText != e.value || r.MeasurementDuration != e.duration || r.CreatedDateTime != e.created {
+			t.Errorf("Expected %+v got %s %s %s %s", e, r.MeasuringDataClassification, r.ResultText, r.MeasurementDuration, r.CreatedDateTime)
+		}
+		if r.IupacIdentifier != exporttypes.NPU_CODE_BLOODSUGAR || r.ResultUnitText != "mmol/L" {
+			t.Errorf("Unexpected npu %s unit %s", r.IupacIdentifier, r.ResultUnitText)
+		}
+	}
+
+	if reports[0].UuidIdentifier != mr.ID.String() || reports[1].UuidIdentifier == reports[2].UuidIdentifier {
+		t.Errorf("Expected summary to use the measurement id and unique interval ids")
+	}
+
+	again, _ := ReportFromMeasurement(exporttypes.GetKihdbExportTypes(), m, mr)
+	if again[1].UuidIdentifier != reports[1].UuidIdentifier {
+		t.Errorf("Expected stable interval ids")
+	}
+
+	m.Measurement.Series = nil
+	if _, err := ReportFromMeasurement(exporttypes.GetKihdbExportTypes(), m, mr); err == nil {
+		t.Errorf("Expected error for empty series")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d        time.Duration
+		expected string
+	}{
+		{0, "PT0S"},
+		{90 * time.Second, "PT1M30S"},
+		{time.Hour, "PT1H"},
+		{25*time.Hour + 5*time.Minute, "PT25H5M"},
+	}
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.expected {
+			t.Errorf("Expected %s got %s", tt.expected, got)
+		}
+	}
+}
+
+// The enumerations and ranges of the exported types must accept the values OTH sends, as seen in the fixtures.
+// Fixtures that are meant to be invalid are kept in testdata/invalid
+func TestFixturesAreValid(t *testing.T) {
+	types := exporttypes.GetOioXdsExportTypes()
+
+	files, err := filepath.Glob("testdata/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Error reading %s - %v", file, err)
+		}
+
+		var response measurement.MeasurementResponse
+		if err := json.Unmarshal(data, &response); err != nil || len(response.Results) == 0 {
+			var m measurement.Measurement
+			if err := json.Unmarshal(data, &m); err != nil || len(m.Type) == 0 {
+				continue
+			}
+			response.Results = []measurement.Measurement{m}
+		}
+
+		for i, m := range response.Results {
+			exportType, ok := types[m.Type]
+			if !ok || !exportType.IsToBeExported() {
+				continue
+			}
+			m, err := exporttypes.NormalizeUnit(exportType, m)
+			if err != nil {
+				continue
+			}
+			if err := exporttypes.Validate(exportType, m); err != nil {
+				t.Errorf("%s #%d (%s) is invalid - %v", file, i, m.Type, err)
+			}
+		}
+	}
+}
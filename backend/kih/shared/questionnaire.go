@@ -0,0 +1,96 @@
+package shared
+
+import (
+	"fmt"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+	"github.com/google/uuid"
+)
+
+// AnswerNotNumericError is returned for answers to questions mapped as numeric which are not numbers
+type AnswerNotNumericError struct {
+	Question string
+	Answer   interface{}
+}
+
+func (e AnswerNotNumericError) Error() string {
+	return fmt.Sprintf("Answer to question %s is not numeric - %v", e.Question, e.Answer)
+}
+
+// FindQuestionMapping returns the mapping for a question in the named questionnaire
+func FindQuestionMapping(mappings []app.QuestionMapping, questionnaire, question string) (app.QuestionMapping, bool) {
+	for _, mapping := range mappings {
+		if mapping.Question != question {
+			continue
+		}
+		if len(mapping.Questionnaire) > 0 && mapping.Questionnaire != questionnaire {
+			continue
+		}
+		return mapping, true
+	}
+	return app.QuestionMapping{}, false
+}
+
+// HasMappedAnswers checks whether any of the answers in the questionnaire result is mapped
+func HasMappedAnswers(mappings []app.QuestionMapping, q measurement.QuestionnaireResult) bool {
+	for _, answer := range q.Results {
+		if _, ok := FindQuestionMapping(mappings, q.Name, answer.QuestionID); ok && answer.Answer != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Map the mapped answers of a questionnaire result to Laboratory Report structures - one report per answer
+func ReportsFromQuestionnaireResult(mappings []app.QuestionMapping, q measurement.QuestionnaireResult, qr repository.QuestionnaireExportState) ([]LaboratoryReportExtended, error) {
+	var reports []LaboratoryReportExtended
+
+	for _, answer := range q.Results {
+		mapping, ok := FindQuestionMapping(mappings, q.Name, answer.QuestionID)
+		if !ok || answer.Answer == nil {
+			log.Debug("Skipping unmapped question ", answer.QuestionID)
+			continue
+		}
+
+		alphaNumeric := mapping.Encoding == RESULT_ENCODING_ALPHANUMERIC
+		value := measurement.ValueOf(answer.Answer)
+		if !alphaNumeric && !value.IsNumeric() {
+			return reports, AnswerNotNumericError{Question: answer.QuestionID, Answer: answer.Answer}
+		}
+
+		exportType := exporttypes.NewQuestionnaireType(mapping.NpuCode, mapping.AnalysisText, mapping.Unit, mapping.Decimals, alphaNumeric)
+
+		// The answer is handled as a typed measurement
+		m := measurement.Measurement{Timestamp: q.Timestamp, Type: answer.QuestionID}
+		m.Measurement.Value = value
+
+		// Each answer gets its own stable report id derived from the questionnaire result
+		mr := repository.MeasurementExportState{ID: uuid.NewSHA1(qr.ID, []byte(answer.QuestionID))}
+
+		r := LaboratoryReportExtended{}
+		performBaseMapping(exportType, &r, m, mr)
+		performGenericMapping(&r)
+		r.MeasurementTransferredBy = MEASUREMENT_TRANSFERED_BY_TYPED
+
+		r.AnalysisText = exportType.GetAnalysisText()
+		r.ResultUnitText = exportType.GetResultUnitText()
+		r.ResultText = exportType.GetResultText(m)
+		if alphaNumeric {
+			r.ResultEncodingIdentifier = RESULT_ENCODING_ALPHANUMERIC
+		} else {
+			r.ResultEncodingIdentifier = RESULT_ENCODING_NUMERIC
+		}
+
+		reports = append(reports, r)
+	}
+
+	if len(reports) == 0 {
+		return reports, fmt.Errorf("No mapped answers in questionnaire result %s", q.Links.QuestionnaireResult)
+	}
+
+	log.Debug("Returning - # of reports ", len(reports))
+	return reports, nil
+}
@@ -0,0 +1,94 @@
+package shared
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+	"github.com/google/uuid"
+)
+
+var questionMappings = []app.QuestionMapping{
+	{Question: "dyspnoea", NpuCode: "MCS88201", AnalysisText: "Pt—Dyspnø; vurd.", Encoding: RESULT_ENCODING_ALPHANUMERIC},
+	{Questionnaire: "Hjertesvigt", Question: "wellbeing", NpuCode: "MCS88202", AnalysisText: "Pt—Velbefindende; skala", Encoding: RESULT_ENCODING_NUMERIC},
+	{Questionnaire: "KOL", Question: "edema", NpuCode: "MCS88203", AnalysisText: "Pt—Ødem; vurd.", Encoding: RESULT_ENCODING_ALPHANUMERIC},
+	{Question: "comment", NpuCode: "MCS88204", AnalysisText: "Kommentar", Encoding: RESULT_ENCODING_ALPHANUMERIC},
+}
+
+func questionnaireResultFromFile(t *testing.T, f string) measurement.QuestionnaireResult {
+	var q measurement.QuestionnaireResult
+	data, err := ioutil.ReadFile(f)
+	if err != nil {
+		t.Fatalf("Error reading input file - %v", err)
+	}
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatalf("Error parsing input file - %v", err)
+	}
+	return q
+}
+
+func TestReportsFromQuestionnaireResult(t *testing.T) {
+	q := questionnaireResultFromFile(t, "testdata/questionnaire_result.json")
+	qr := repository.QuestionnaireExportState{ID: uuid.New(), QuestionnaireResult: q.Links.QuestionnaireResult}
+
+	if !HasMappedAnswers(questionMappings, q) {
+		t.Fatal("Questionnaire result should have mapped answers")
+	}
+
+	reports, err := ReportsFromQuestionnaireResult(questionMappings, q, qr)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+
+	// edema is mapped for another questionnaire and comment is unanswered
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 reports - got %d", len(reports))
+	}
+
+	expected := []struct {
+		npu, result, encoding string
+	}{
+		{"MCS88201", "Lidt bedre", RESULT_ENCODING_ALPHANUMERIC},
+		{"MCS88202", "7", RESULT_ENCODING_NUMERIC},
+	}
+	for i, e := range expected {
+		r := reports[i]
+		if r.IupacIdentifier != e.npu || r.ResultText != e.result || r.ResultEncodingIdentifier != e.encoding {
+			t.Errorf("Expected %+v - got %s/%s/%s", e, r.IupacIdentifier, r.ResultText, r.ResultEncodingIdentifier)
+		}
+		if r.MeasurementTransferredBy != MEASUREMENT_TRANSFERED_BY_TYPED {
+			t.Errorf("Expected typed answer - got %s", r.MeasurementTransferredBy)
+		}
+		if r.CreatedDateTime != "2019-11-11T14:40:12Z" {
+			t.Errorf("Unexpected timestamp %s", r.CreatedDateTime)
+		}
+	}
+
+	if reports[0].UuidIdentifier == reports[1].UuidIdentifier {
+		t.Error("Reports should have separate ids")
+	}
+	again, _ := ReportsFromQuestionnaireResult(questionMappings, q, qr)
+	if again[0].UuidIdentifier != reports[0].UuidIdentifier {
+		t.Error("Report ids should be stable across conversions")
+	}
+}
+
+func TestReportsFromQuestionnaireResultErrors(t *testing.T) {
+	q := questionnaireResultFromFile(t, "testdata/questionnaire_result.json")
+	qr := repository.QuestionnaireExportState{ID: uuid.New()}
+
+	if _, err := ReportsFromQuestionnaireResult([]app.QuestionMapping{}, q, qr); err == nil {
+		t.Error("Expected error when no answers are mapped")
+	}
+	if HasMappedAnswers([]app.QuestionMapping{}, q) {
+		t.Error("Expected no mapped answers")
+	}
+
+	numeric := []app.QuestionMapping{{Question: "dyspnoea", NpuCode: "MCS88201", Encoding: RESULT_ENCODING_NUMERIC}}
+	if _, err := ReportsFromQuestionnaireResult(numeric, q, qr); err == nil {
+		t.Error("Expected error when text answer is mapped as numeric")
+	}
+}
@@ -0,0 +1,28 @@
+package shared
+
+import (
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// DEFAULT_SCHEDULE_WINDOW is used when no window is configured
+const DEFAULT_SCHEDULE_WINDOW = 2 * time.Hour
+
+// ApplySchedules marks the reports of a measurement as scheduled when it is taken within window of a slot in the
+// questionnaire schedules of the patient, and unscheduled otherwise
+func ApplySchedules(m measurement.Measurement, reports []LaboratoryReportExtended, schedules measurement.QuestionnaireSchedules, window time.Duration) {
+	if window <= 0 {
+		window = DEFAULT_SCHEDULE_WINDOW
+	}
+
+	scheduled := MEAUREMENT_UNSCHEDULED_TYPE
+	if schedules.IsScheduled(m.Timestamp, window) {
+		scheduled = MEAUREMENT_SCHEDULED_TYPE
+	}
+	log.Debugf("Measurement at %s is %s", m.Timestamp, scheduled)
+
+	for i := range reports {
+		reports[i].MeasurementScheduled = scheduled
+	}
+}
@@ -0,0 +1,70 @@
+package shared
+
+import (
+	"testing"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+	"github.com/google/uuid"
+)
+
+func TestApplySchedules(t *testing.T) {
+	schedules := measurement.QuestionnaireSchedules{Results: []measurement.QuestionnaireSchedule{
+		{QuestionnaireName: "Blodtryk", Schedule: measurement.Schedule{Type: measurement.SCHEDULE_WEEKDAYS, Weekdays: []string{"MONDAY"}, TimesOfDay: []measurement.TimeOfDay{{Hour: 10}}}},
+	}}
+	monday := time.Date(2020, time.May, 25, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		window    time.Duration
+		expected  string
+	}{
+		{"In slot", monday.Add(10*time.Hour + 30*time.Minute), 0, MEAUREMENT_SCHEDULED_TYPE},
+		{"Default window", monday.Add(13 * time.Hour), 0, MEAUREMENT_UNSCHEDULED_TYPE},
+		{"Configured window", monday.Add(13 * time.Hour), 4 * time.Hour, MEAUREMENT_SCHEDULED_TYPE},
+		{"Other day", monday.Add(34 * time.Hour), 0, MEAUREMENT_UNSCHEDULED_TYPE},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := measurement.Measurement{Timestamp: tt.timestamp, Type: exporttypes.TYPE_NAME_BLOOD_PRESSURE, Measurement: measurement.MeasurementValue{Systolic: 120, Diastolic: 80}}
+			reports, err := ReportFromMeasurement(exporttypes.GetOioXdsExportTypes(), m, repository.MeasurementExportState{ID: uuid.New()})
+			if err != nil {
+				t.Fatalf("Error converting - %v", err)
+			}
+
+			ApplySchedules(m, reports, schedules, tt.window)
+
+			for _, r := range reports {
+				if r.MeasurementScheduled != tt.expected {
+					t.Errorf("Expected %s got %s", tt.expected, r.MeasurementScheduled)
+				}
+			}
+		})
+	}
+}
+
+func TestReportLocationFromOrigin(t *testing.T) {
+	m := measurement.Measurement{Type: exporttypes.TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(80.2)}}
+	exportedTypes := exporttypes.GetOioXdsExportTypes()
+
+	reports, err := ReportFromMeasurement(exportedTypes, m, repository.MeasurementExportState{ID: uuid.New()})
+	if err != nil {
+		t.Fatalf("Error converting - %v", err)
+	}
+	if reports[0].MeasurementLocation != MEASUREMENT_LOCATION_TYPE || reports[0].MeasurementScheduled != MEAUREMENT_SCHEDULED_TYPE {
+		t.Errorf("Expected defaults got %s/%s", reports[0].MeasurementLocation, reports[0].MeasurementScheduled)
+	}
+
+	m.Origin.Location = "other"
+	reports, err = ReportFromMeasurement(exportedTypes, m, repository.MeasurementExportState{ID: uuid.New()})
+	if err != nil {
+		t.Fatalf("Error converting - %v", err)
+	}
+	if reports[0].MeasurementLocation != "other" {
+		t.Errorf("Expected location from origin got %s", reports[0].MeasurementLocation)
+	}
+}
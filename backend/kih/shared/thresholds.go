@@ -0,0 +1,119 @@
+package shared
+
+import (
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// Values for ResultAbnormalIdentifier
+const (
+	RESULT_ABNORMAL_LOW    = "low"
+	RESULT_ABNORMAL_HIGH   = "high"
+	RESULT_ABNORMAL_NORMAL = "normal"
+)
+
+// ApplyThresholds sets the reference range and abnormal flag on the reports of a measurement from the patients thresholds.
+// Only numeric simple types and blood pressure are handled
+func ApplyThresholds(exportedTypes map[string]exporttypes.MeasurementType, m measurement.Measurement, reports []LaboratoryReportExtended, thresholds measurement.PatientThresholds) {
+	threshold, ok := thresholds.ForType(m.Type)
+	if !ok {
+		log.Debug("No thresholds for ", m.Type)
+		return
+	}
+
+	exportType := exportedTypes[m.Type]
+	m, err := exporttypes.NormalizeUnit(exportType, m)
+	if err != nil {
+		log.Debug("Skipping thresholds - ", err)
+		return
+	}
+	threshold, err = convertThreshold(exportType, m.Type, threshold)
+	if err != nil {
+		log.Debug("Skipping thresholds - ", err)
+		return
+	}
+
+	switch t := exportType.(type) {
+	case exporttypes.SimpleType:
+		value, err := m.Measurement.Value.Float()
+		if err != nil || t.IsAlphaNumeric() || len(reports) != 1 {
+			return
+		}
+		applyRange(&reports[0], threshold.ThresholdValues, value, func(v float64) string {
+			return t.GetResultText(measurement.Measurement{Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(v)}})
+		})
+
+	case exporttypes.CompositeType:
+		// Clinician has thresholds for the components of a blood pressure
+		limits := map[string]*measurement.ThresholdValues{
+			measurement.COMPONENT_SYSTOLIC:  threshold.Systolic,
+			measurement.COMPONENT_DIASTOLIC: threshold.Diastolic,
+		}
+		measured := t.GetMeasured(m)
+		if len(reports) != len(measured) {
+			return
+		}
+		for i, c := range measured {
+			limit := limits[c.GetName()]
+			value, err := c.GetValue(m).Float()
+			if limit == nil || err != nil {
+				continue
+			}
+			component := c
+			applyRange(&reports[i], *limit, value, func(v float64) string {
+				values := map[string]measurement.Value{component.GetName(): measurement.NumericValue(v)}
+				return component.GetType().GetResultText(measurement.Measurement{Measurement: measurement.MeasurementValue{Value: measurement.CompositeValue(values)}})
+			})
+		}
+	}
+}
+
+// convertThreshold converts the limits to the canonical unit of the type
+func convertThreshold(exportType exporttypes.MeasurementType, measurementType string, threshold measurement.PatientThreshold) (measurement.PatientThreshold, error) {
+	var err error
+	convert := func(limits *measurement.ThresholdValues) *measurement.ThresholdValues {
+		if limits == nil {
+			return nil
+		}
+		converted := *limits
+		for _, limit := range []**float64{&converted.AlertHigh, &converted.WarningHigh, &converted.WarningLow, &converted.AlertLow} {
+			if *limit == nil || err != nil {
+				continue
+			}
+			value, convErr := exporttypes.ConvertValue(exportType, measurementType, threshold.Unit, **limit)
+			if convErr != nil {
+				err = convErr
+				continue
+			}
+			*limit = &value
+		}
+		return &converted
+	}
+
+	threshold.ThresholdValues = *convert(&threshold.ThresholdValues)
+	threshold.Systolic = convert(threshold.Systolic)
+	threshold.Diastolic = convert(threshold.Diastolic)
+	return threshold, err
+}
+
+// applyRange sets min and max in the same layout as the result, and flags the value against them
+func applyRange(r *LaboratoryReportExtended, limits measurement.ThresholdValues, value float64, layout func(float64) string) {
+	min, max := limits.Range()
+	if min == nil && max == nil {
+		return
+	}
+
+	r.ResultAbnormalIdentifier = RESULT_ABNORMAL_NORMAL
+	if min != nil {
+		r.ResultMinimumText = layout(*min)
+		if value < *min {
+			r.ResultAbnormalIdentifier = RESULT_ABNORMAL_LOW
+		}
+	}
+	if max != nil {
+		r.ResultMaximumText = layout(*max)
+		if value > *max {
+			r.ResultAbnormalIdentifier = RESULT_ABNORMAL_HIGH
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package shared
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+	"github.com/google/uuid"
+)
+
+const thresholdsJson = `{
+  "thresholds": [
+    {"type": "weight", "unit": "kg", "alertHigh": 90, "warningHigh": 85, "warningLow": 70, "alertLow": 65},
+    {"type": "saturation", "unit": "%", "alertLow": 88},
+    {"type": "blood_pressure", "unit": "mmHg",
+     "systolic": {"warningHigh": 140, "warningLow": 100},
+     "diastolic": {"alertHigh": 95, "alertLow": 60}},
+    {"type": "nitrite_in_urine", "alertHigh": 1}
+  ]
+}`
+
+func TestApplyThresholds(t *testing.T) {
+	var thresholds measurement.PatientThresholds
+	if err := json.Unmarshal([]byte(thresholdsJson), &thresholds); err != nil {
+		t.Fatalf("Error parsing thresholds - %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		m        measurement.Measurement
+		expected [][3]string
+	}{
+		{"Normal weight", measurement.Measurement{Type: exporttypes.TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(80.2)}}, [][3]string{{"70.0", "85.0", RESULT_ABNORMAL_NORMAL}}},
+		{"High weight", measurement.Measurement{Type: exporttypes.TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(86.0)}}, [][3]string{{"70.0", "85.0", RESULT_ABNORMAL_HIGH}}},
+		{"Low weight", measurement.Measurement{Type: exporttypes.TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("68")}}, [][3]string{{"70.0", "85.0", RESULT_ABNORMAL_LOW}}},
+		{"Weight in pounds", measurement.Measurement{Type: exporttypes.TYPE_NAME_WEIGHT, Measurement: measurement.MeasurementValue{Unit: "lbs", Value: measurement.NumericValue(198.4)}}, [][3]string{{"70.0", "85.0", RESULT_ABNORMAL_HIGH}}},
+		{"Low saturation only min", measurement.Measurement{Type: exporttypes.TYPE_NAME_SATURATION, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(85)}}, [][3]string{{"0.88", "", RESULT_ABNORMAL_LOW}}},
+		{"Blood pressure", measurement.Measurement{Type: exporttypes.TYPE_NAME_BLOOD_PRESSURE, Measurement: measurement.MeasurementValue{Systolic: 150, Diastolic: 80}}, [][3]string{{"100", "140", RESULT_ABNORMAL_HIGH}, {"60", "95", RESULT_ABNORMAL_NORMAL}}},
+		{"No thresholds for type", measurement.Measurement{Type: exporttypes.TYPE_NAME_PULSE, Measurement: measurement.MeasurementValue{Value: measurement.NumericValue(200)}}, [][3]string{{"", "", ""}}},
+		{"Enumerated not flagged", measurement.Measurement{Type: exporttypes.TYPE_NAME_URINE_NITRITE, Measurement: measurement.MeasurementValue{Value: measurement.EnumeratedValue("Pos.")}}, [][3]string{{"", "", ""}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exportedTypes := exporttypes.GetOioXdsExportTypes()
+			reports, err := ReportFromMeasurement(exportedTypes, tt.m, repository.MeasurementExportState{ID: uuid.New()})
+			if err != nil {
+				t.Fatalf("Error converting - %v", err)
+			}
+
+			ApplyThresholds(exportedTypes, tt.m, reports, thresholds)
+
+			if len(reports) != len(tt.expected) {
+				t.Fatalf("Expected %d reports got %d", len(tt.expected), len(reports))
+			}
+			for i, e := range tt.expected {
+				r := reports[i]
+				if r.ResultMinimumText != e[0] || r.ResultMaximumText != e[1] || r.ResultAbnormalIdentifier != e[2] {
+					t.Errorf("Expected %v got [%s %s %s]", e, r.ResultMinimumText, r.ResultMaximumText, r.ResultAbnormalIdentifier)
+				}
+			}
+		})
+	}
+}
@@ -59,6 +59,13 @@ type LaboratoryReportExtended struct {
 	MeasuringCircumstances        string               `xml:"urn:oio:medcom:chronicdataset:1.0.1 MeasuringCircumstances,omitempty" json:"MeasuringCircumstances,omitempty"`
 }
 
+// Attachment is a file attached to the exported document, e.g. an ECG waveform
+type Attachment struct {
+	Filename string `json:"Filename"`
+	MimeType string `json:"MimeType"`
+	Data     []byte `json:"Data"`
+}
+
 type SelfMonitoredSample struct {
 	LaboratoryReportExtendedCollection struct {
 		LaboratoryReportExtended []LaboratoryReportExtended `xml:"urn:oio:medcom:chronicdataset:1.0.1 LaboratoryReportExtended"`
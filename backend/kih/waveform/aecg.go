@@ -0,0 +1,142 @@
+package waveform
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// HL7 code systems used in aECG
+const (
+	HL7_ACT_CODE        = "2.16.840.1.113883.5.4"
+	HL7_MDC             = "2.16.840.1.113883.6.24"
+	HL7_CPT4            = "2.16.840.1.113883.6.12"
+	HL7_TIME_FORMAT     = "20060102150405.000"
+	AECG_UNIT_MICROVOLT = "uV"
+)
+
+type aecgCode struct {
+	Code           string `xml:"code,attr"`
+	CodeSystem     string `xml:"codeSystem,attr"`
+	CodeSystemName string `xml:"codeSystemName,attr,omitempty"`
+}
+
+type aecgValue struct {
+	Value string `xml:"value,attr"`
+	Unit  string `xml:"unit,attr,omitempty"`
+}
+
+type aecgInterval struct {
+	Low  aecgValue `xml:"low"`
+	High aecgValue `xml:"high"`
+}
+
+type aecgSequenceValue struct {
+	Type      string     `xml:"xsi:type,attr"`
+	Head      *aecgValue `xml:"head,omitempty"`
+	Increment *aecgValue `xml:"increment,omitempty"`
+	Origin    *aecgValue `xml:"origin,omitempty"`
+	Scale     *aecgValue `xml:"scale,omitempty"`
+	Digits    string     `xml:"digits,omitempty"`
+}
+
+type aecgSequence struct {
+	Code  aecgCode          `xml:"sequence>code"`
+	Value aecgSequenceValue `xml:"sequence>value"`
+}
+
+type annotatedECG struct {
+	XMLName       xml.Name     `xml:"AnnotatedECG"`
+	Xmlns         string       `xml:"xmlns,attr"`
+	XmlnsXsi      string       `xml:"xmlns:xsi,attr"`
+	ID            aecgValue    `xml:"id"`
+	Code          aecgCode     `xml:"code"`
+	EffectiveTime aecgInterval `xml:"effectiveTime"`
+	Series        struct {
+		Code          aecgCode       `xml:"code"`
+		EffectiveTime aecgInterval   `xml:"effectiveTime"`
+		Sequences     []aecgSequence `xml:"component>sequenceSet>component"`
+	} `xml:"component>series"`
+}
+
+// leadCode maps a lead name, e.g. II or aVR, to the MDC code
+func leadCode(name string) string {
+	return fmt.Sprintf("MDC_ECG_LEAD_%s", strings.ToUpper(name))
+}
+
+// toMicrovolt converts the samples to whole microvolts
+func toMicrovolt(c measurement.WaveformChannel) (string, error) {
+	var factor float64
+	switch strings.ToLower(c.Unit) {
+	case "uv", "µv":
+		factor = 1
+	case "mv":
+		factor = 1000
+	case "v":
+		factor = 1000000
+	default:
+		return "", fmt.Errorf("Unsupported unit %s for lead %s", c.Unit, c.Name)
+	}
+
+	digits := make([]string, len(c.Values))
+	for i, v := range c.Values {
+		digits[i] = strconv.FormatFloat(math.Round(v*factor), 'f', 0, 64)
+	}
+	return strings.Join(digits, " "), nil
+}
+
+// encodeAECG lays out the waveform as HL7 aECG rhythm series - one sequence per lead
+func encodeAECG(m measurement.Measurement, w measurement.Waveform, id uuid.UUID) ([]byte, error) {
+	start := w.Start
+	if start.IsZero() {
+		start = m.Timestamp
+	}
+	interval := aecgInterval{
+		Low:  aecgValue{Value: start.Format(HL7_TIME_FORMAT)},
+		High: aecgValue{Value: start.Add(w.Duration()).Format(HL7_TIME_FORMAT)},
+	}
+
+	ecg := annotatedECG{Xmlns: "urn:hl7-org:v3", XmlnsXsi: "http://www.w3.org/2001/XMLSchema-instance"}
+	ecg.ID = aecgValue{Value: id.String()}
+	ecg.Code = aecgCode{Code: "93000", CodeSystem: HL7_CPT4, CodeSystemName: "CPT-4"}
+	ecg.EffectiveTime = interval
+	ecg.Series.Code = aecgCode{Code: "RHYTHM", CodeSystem: HL7_ACT_CODE}
+	ecg.Series.EffectiveTime = interval
+
+	ecg.Series.Sequences = append(ecg.Series.Sequences, aecgSequence{
+		Code: aecgCode{Code: "TIME_ABSOLUTE", CodeSystem: HL7_ACT_CODE},
+		Value: aecgSequenceValue{
+			Type:      "GLIST_TS",
+			Head:      &aecgValue{Value: start.Format(HL7_TIME_FORMAT)},
+			Increment: &aecgValue{Value: strconv.FormatFloat(1/w.SampleRate, 'f', -1, 64), Unit: "s"},
+		},
+	})
+
+	for _, c := range w.Channels {
+		digits, err := toMicrovolt(c)
+		if err != nil {
+			return []byte{}, err
+		}
+		ecg.Series.Sequences = append(ecg.Series.Sequences, aecgSequence{
+			Code: aecgCode{Code: leadCode(c.Name), CodeSystem: HL7_MDC, CodeSystemName: "MDC"},
+			Value: aecgSequenceValue{
+				Type:   "SLIST_PQ",
+				Origin: &aecgValue{Value: "0", Unit: AECG_UNIT_MICROVOLT},
+				Scale:  &aecgValue{Value: "1", Unit: AECG_UNIT_MICROVOLT},
+				Digits: digits,
+			},
+		})
+	}
+
+	data, err := xml.MarshalIndent(ecg, "", "  ")
+	if err != nil {
+		return []byte{}, errors.Wrap(err, "Error encoding aECG")
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,50 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/pkg/errors"
+)
+
+// encodeCTG lays out the channels as CSV - see the package documentation for the format
+func encodeCTG(w measurement.Waveform) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	header := []string{"time"}
+	for _, c := range w.Channels {
+		header = append(header, fmt.Sprintf("%s (%s)", c.Name, c.Unit))
+	}
+	if err := writer.Write(header); err != nil {
+		return []byte{}, errors.Wrap(err, "Error writing CTG")
+	}
+
+	for i := 0; i < w.Samples(); i++ {
+		row := []string{timeOffset(i, w.SampleRate)}
+		for _, c := range w.Channels {
+			if i < len(c.Values) {
+				row = append(row, strconv.FormatFloat(c.Values[i], 'f', -1, 64))
+			} else {
+				row = append(row, "")
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			return []byte{}, errors.Wrap(err, "Error writing CTG")
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return []byte{}, errors.Wrap(err, "Error writing CTG")
+	}
+	return buf.Bytes(), nil
+}
+
+// timeOffset lays out the offset of sample i in seconds
+func timeOffset(i int, sampleRate float64) string {
+	return strconv.FormatFloat(float64(i)/sampleRate, 'f', 3, 64)
+}
@@ -0,0 +1,79 @@
+// Package waveform packages ECG and CTG waveforms as document attachments.
+//
+// ECG is encoded as HL7 aECG XML. CTG is encoded as CSV with a header line. The first column, time, is the offset from
+// the start of the recording in seconds. Each channel follows in its own column named "<channel> (<unit>)", e.g. fhr (1/min)
+package waveform
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/shared"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/google/uuid"
+)
+
+// Name of the fetal heart rate channel in CTG waveforms
+const CTG_FETAL_HEART_RATE_CHANNEL = "fhr"
+
+// Encode packages the waveform in the given format
+func Encode(format string, m measurement.Measurement, w measurement.Waveform, id uuid.UUID) (shared.Attachment, error) {
+	if w.SampleRate <= 0 || w.Samples() == 0 {
+		return shared.Attachment{}, fmt.Errorf("Waveform for %s has no samples", m.Links.Measurement)
+	}
+
+	switch format {
+	case exporttypes.WAVEFORM_FORMAT_AECG:
+		data, err := encodeAECG(m, w, id)
+		if err != nil {
+			return shared.Attachment{}, err
+		}
+		return shared.Attachment{Filename: fmt.Sprintf("ecg-%s.xml", id), MimeType: "text/xml", Data: data}, nil
+	case exporttypes.WAVEFORM_FORMAT_CTG_CSV:
+		data, err := encodeCTG(w)
+		if err != nil {
+			return shared.Attachment{}, err
+		}
+		return shared.Attachment{Filename: fmt.Sprintf("ctg-%s.csv", id), MimeType: "text/csv", Data: data}, nil
+	default:
+		return shared.Attachment{}, fmt.Errorf("Unknown waveform format %s", format)
+	}
+}
+
+// HeartRate returns the heart rate reported by the device, or the mean fetal heart rate of a CTG. Samples of 0 are signal loss and left out
+func HeartRate(w measurement.Waveform) (float64, bool) {
+	if w.HeartRate > 0 {
+		return w.HeartRate, true
+	}
+
+	channel, ok := w.Channel(CTG_FETAL_HEART_RATE_CHANNEL)
+	if !ok {
+		return 0, false
+	}
+
+	var sum float64
+	var count int
+	for _, v := range channel.Values {
+		if v > 0 {
+			sum += v
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return math.Round(sum / float64(count)), true
+}
+
+// Summarise sets heart rate and duration on the measurement from the waveform, unless clinician already provided them
+func Summarise(m *measurement.Measurement, w measurement.Waveform) {
+	if m.Measurement.Value.IsEmpty() {
+		if hr, ok := HeartRate(w); ok {
+			m.Measurement.Value = measurement.NumericValue(hr)
+		}
+	}
+	if m.Measurement.Duration == 0 {
+		m.Measurement.Duration = w.Duration().Seconds()
+	}
+}
@@ -0,0 +1,125 @@
+package waveform
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/google/uuid"
+)
+
+func readWaveform(t *testing.T, file string) measurement.Waveform {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Error reading file %v", err)
+	}
+	var w measurement.Waveform
+	if err := json.Unmarshal(data, &w); err != nil {
+		t.Fatalf("Error parsing waveform %v", err)
+	}
+	return w
+}
+
+func TestEncodeAECG(t *testing.T) {
+	w := readWaveform(t, "testdata/ecg_waveform.json")
+	id := uuid.New()
+
+	attachment, err := Encode(exporttypes.WAVEFORM_FORMAT_AECG, measurement.Measurement{Type: exporttypes.TYPE_NAME_ECG}, w, id)
+	if err != nil {
+		t.Fatalf("Error encoding - %v", err)
+	}
+	if attachment.MimeType != "text/xml" || !strings.HasSuffix(attachment.Filename, ".xml") {
+		t.Errorf("Unexpected attachment %s %s", attachment.Filename, attachment.MimeType)
+	}
+
+	var doc annotatedECG
+	if err := xml.Unmarshal(attachment.Data, &doc); err != nil {
+		t.Fatalf("Error parsing aECG - %v", err)
+	}
+	if doc.ID.Value != id.String() {
+		t.Errorf("Expected id %s got %s", id, doc.ID.Value)
+	}
+	if doc.EffectiveTime.High.Value != "20200604100002.000" {
+		t.Errorf("Expected two seconds of recording, got %s", doc.EffectiveTime.High.Value)
+	}
+
+	expected := []struct {
+		code   string
+		digits string
+	}{
+		{"TIME_ABSOLUTE", ""},
+		{"MDC_ECG_LEAD_I", "100 250 1200 -300 50 100 200 0"},
+		{"MDC_ECG_LEAD_AVR", "-50 -120 -600 150 -20 -50 -100 0"},
+	}
+	if len(doc.Series.Sequences) != len(expected) {
+		t.Fatalf("Expected %d sequences got %d", len(expected), len(doc.Series.Sequences))
+	}
+	for i, e := range expected {
+		s := doc.Series.Sequences[i]
+		if s.Code.Code != e.code || s.Value.Digits != e.digits {
+			t.Errorf("Expected %s '%s' got %s '%s'", e.code, e.digits, s.Code.Code, s.Value.Digits)
+		}
+	}
+	if !strings.Contains(string(attachment.Data), `xsi:type="SLIST_PQ"`) {
+		t.Errorf("Expected typed sequence values")
+	}
+
+	w.Channels[0].Unit = "mmHg"
+	if _, err := Encode(exporttypes.WAVEFORM_FORMAT_AECG, measurement.Measurement{}, w, id); err == nil {
+		t.Errorf("Expected error for unsupported unit")
+	}
+}
+
+func TestEncodeCTG(t *testing.T) {
+	w := measurement.Waveform{SampleRate: 2, Channels: []measurement.WaveformChannel{
+		{Name: "fhr", Unit: "1/min", Values: []float64{140, 0, 150, 145}},
+		{Name: "uc", Unit: "mmHg", Values: []float64{10, 12, 30}},
+	}}
+
+	attachment, err := Encode(exporttypes.WAVEFORM_FORMAT_CTG_CSV, measurement.Measurement{}, w, uuid.New())
+	if err != nil {
+		t.Fatalf("Error encoding - %v", err)
+	}
+
+	expected := "time,fhr (1/min),uc (mmHg)\n0.000,140,10\n0.500,0,12\n1.000,150,30\n1.500,145,\n"
+	if string(attachment.Data) != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, attachment.Data)
+	}
+	if attachment.MimeType != "text/csv" {
+		t.Errorf("Unexpected mime type %s", attachment.MimeType)
+	}
+
+	if hr, ok := HeartRate(w); !ok || hr != 145 {
+		t.Errorf("Expected mean fetal heart rate 145 got %f", hr)
+	}
+
+	m := measurement.Measurement{}
+	Summarise(&m, w)
+	if m.Measurement.Value.String() != "145" || m.Measurement.Duration != 2 {
+		t.Errorf("Unexpected summary %v %f", m.Measurement.Value, m.Measurement.Duration)
+	}
+
+	if _, err := Encode(exporttypes.WAVEFORM_FORMAT_CTG_CSV, measurement.Measurement{}, measurement.Waveform{SampleRate: 2}, uuid.New()); err == nil {
+		t.Errorf("Expected error for empty waveform")
+	}
+}
+
+func TestSummariseKeepsClinicianValues(t *testing.T) {
+	w := measurement.Waveform{SampleRate: 1, HeartRate: 80, Channels: []measurement.WaveformChannel{{Name: "II", Unit: "mV", Values: make([]float64, 10)}}}
+	if w.Duration() != 10*time.Second {
+		t.Errorf("Expected 10s got %s", w.Duration())
+	}
+
+	m := measurement.Measurement{}
+	m.Measurement.Value = measurement.NumericValue(75)
+	m.Measurement.Duration = 30
+	Summarise(&m, w)
+	if m.Measurement.Value.String() != "75" || m.Measurement.Duration != 30 {
+		t.Errorf("Expected clinician values to be kept, got %v %f", m.Measurement.Value, m.Measurement.Duration)
+	}
+}
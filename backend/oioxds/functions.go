@@ -1,7 +1,6 @@
 package oioxds
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,17 +12,17 @@ import (
 	"github.com/KvalitetsIT/kih-telecare-exporter/app"
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/shared"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/waveform"
 	"github.com/KvalitetsIT/kih-telecare-exporter/internal"
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
 
-	"github.com/akyoto/cache"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 )
 
 // Initialize the OIO XDS exporter backend
-func InitExporter(appConfig *app.Config, api measurement.MeasurementApi) OioXdsExporter {
+func InitExporter(appConfig *app.Config, api measurement.MeasurementApi) (OioXdsExporter, error) {
 	pkg := app.GetPackage(reflect.TypeOf(OioXdsExporter{}).PkgPath())
 	log = app.NewLogger(appConfig.GetLoggerLevel(pkg))
 	log.Debug("OIO XDS ", pkg, " -  loglevel", appConfig.GetLoggerLevel(pkg))
@@ -32,19 +31,11 @@ func InitExporter(appConfig *app.Config, api measurement.MeasurementApi) OioXdsE
 
 	exportURL := appConfig.Export.OIOXDSExport.XdsGenerator.URL
 	healthCheckURL := appConfig.Export.OIOXDSExport.XdsGenerator.HealthCheck
-	c := cache.New(1 * time.Hour)
 
 	log.Info("Export URL: ", exportURL, " - health check URL: ", healthCheckURL)
 
-	var httpClient http.Client
 	if appConfig.Export.OIOXDSExport.SkipSslVerify {
-		log.Debug("Setting TLS verify to true")
-		httpClient = http.Client{}
-		httpClient.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
-	} else {
-		httpClient = http.Client{}
+		log.Warn("export.oioxds.skipSSLVerify is not applied - the certificate of the XDS generator is verified")
 	}
 
 	// Setup XDS generator
@@ -52,11 +43,46 @@ func InitExporter(appConfig *app.Config, api measurement.MeasurementApi) OioXdsE
 	// Remember to setup the logger
 	shared.Init(appConfig)
 
-	exporterBackend := OioXdsExporter{c: c, api: api, config: appConfig}
+	exporterBackend := OioXdsExporter{api: api, config: appConfig, client: newClient()}
 	exporterBackend.healthCheckURL = config.Export.OIOXDSExport.XdsGenerator.HealthCheck
 	exporterBackend.exportURL = config.Export.OIOXDSExport.XdsGenerator.URL
-	exporterBackend.exportedTypes = exporttypes.GetOioXdsExportTypes()
-	return exporterBackend
+
+	registry, err := exporttypes.InitDeviceRegistry(appConfig.Export.Devices)
+	if err != nil {
+		return exporterBackend, err
+	}
+	if len(appConfig.Export.Devices) > 0 {
+		log.Info("Using device registry ", appConfig.Export.Devices)
+	}
+	catalog, err := exporttypes.InitCatalog(appConfig.Export.Catalog, registry)
+	if err != nil {
+		return exporterBackend, err
+	}
+	if len(appConfig.Export.Catalog) > 0 {
+		log.Info("Using measurement type catalog ", appConfig.Export.Catalog)
+	}
+	if name := appConfig.Export.OIOXDSExport.Profile; len(name) > 0 {
+		// Viper lowercases the keys of export.profiles, so the name is matched in lower case
+		profile, ok := appConfig.Export.Profiles[strings.ToLower(name)]
+		if !ok {
+			return exporterBackend, fmt.Errorf("Unknown measurement type profile %s", name)
+		}
+		exporterBackend.exportedTypes, err = catalog.ProfileTypes(exporttypes.BACKEND_OIOXDS, profile)
+		if err != nil {
+			return exporterBackend, errors.Wrap(err, fmt.Sprintf("Error in measurement type profile %s", name))
+		}
+		log.Info("Using measurement type profile ", name)
+	} else {
+		exporterBackend.exportedTypes = catalog.ExportTypes(exporttypes.BACKEND_OIOXDS)
+	}
+	if appConfig.Export.Waveforms.Enabled {
+		exporterBackend.exportedTypes[exporttypes.TYPE_NAME_ECG] = exporttypes.NewEcg()
+		if len(appConfig.Export.Waveforms.CTGNpuCode) > 0 {
+			exporterBackend.exportedTypes[exporttypes.TYPE_NAME_CTG] = exporttypes.NewCtg(appConfig.Export.Waveforms.CTGNpuCode, appConfig.Export.Waveforms.CTGAnalysisText)
+		}
+	}
+	exporterBackend.questionMappings = appConfig.Export.Questionnaires.Mapping
+	return exporterBackend, nil
 
 }
 
@@ -139,37 +165,57 @@ func (exprt OioXdsExporter) ConvertMeasurement(m measurement.Measurement, mr rep
 	s.CreatedByText = config.Export.CreatedBy
 
 	var reports []shared.LaboratoryReportExtended
+	var attachments []shared.Attachment
+
+	// Waveforms are fetched and attached. Their summary values are exported as reports
+	if wt, ok := exprt.exportedTypes[m.Type].(exporttypes.WaveformType); ok {
+		attachment, err := exprt.attachWaveform(wt, &m, mr)
+		if err != nil {
+			return "", err
+		}
+		attachments = append(attachments, attachment)
+	}
 
 	reports, err := shared.ReportFromMeasurement(exprt.exportedTypes, m, mr)
 	if err != nil {
 		return "", errors.Wrap(err, fmt.Sprintf("Error parseing measurement - %v", err))
 	}
 
-	s.LaboratoryReports = reports
+	// Patient lookups are cached by the measurement api
+	log.Debug("Fetching patient data")
+	patient, err := exprt.api.FetchPatient(mr.Patient)
+	if err != nil {
+		log.Errorf("Error retrieving patient information - %v", err)
+		return "", errors.Wrap(err, "Error retriving information")
+	}
 
-	var patient measurement.PatientResult
-	p, found := exprt.c.Get(mr.Patient)
-	if !found {
-		log.Debug("Fetching patient data")
-		var err error
-		patient, err = exprt.api.FetchPatient(mr.Patient)
+	// Thresholds only add information to the reports, so the measurement is exported without them if they cannot be fetched
+	if len(patient.Links.PatientThresholds) > 0 {
+		thresholds, err := exprt.api.FetchPatientThresholds(patient.Links.PatientThresholds)
 		if err != nil {
-			log.Errorf("Error retrieving patient information - %v", err)
-			return "", errors.Wrap(err, "Error retriving information")
+			log.Warnf("Error retrieving thresholds for %s - %v", mr.Patient, err)
+		} else {
+			shared.ApplyThresholds(exprt.exportedTypes, m, reports, thresholds)
 		}
+	}
 
-		log.Debug("Add information to Cache")
-		exprt.c.Set(mr.Patient, patient, 1*time.Hour)
-	} else {
-		log.Debug("Found patient in cache")
-		patient = p.(measurement.PatientResult)
+	// Without the schedules the measurement keeps the default of being scheduled
+	if config.Export.Schedules.Enabled && len(patient.Links.QuestionnaireSchedules) > 0 {
+		schedules, err := exprt.api.FetchQuestionnaireSchedules(patient.Links.QuestionnaireSchedules)
+		if err != nil {
+			log.Warnf("Error retrieving questionnaire schedules for %s - %v", mr.Patient, err)
+		} else {
+			shared.ApplySchedules(m, reports, schedules, config.Export.Schedules.Window)
+		}
 	}
 
+	s.LaboratoryReports = reports
+
 	//s.CreatedByText = config.Export.KIHExport.CreatedBy
 
 	//var reports []shared.LaboratoryReportExtended
 
-	xdsGeneratorRequest, err := convertXdsGeneratorRequest(mr.ID, s, patient)
+	xdsGeneratorRequest, err := convertXdsGeneratorRequest(mr.ID, s, patient, attachments...)
 	if err != nil {
 		return "", errors.Wrap(err, "Error creating XDS generator request")
 	}
@@ -179,6 +225,60 @@ func (exprt OioXdsExporter) ConvertMeasurement(m measurement.Measurement, mr rep
 	return string(xdsGeneratorRequest), nil
 }
 
+// attachWaveform fetches the waveform of an ECG or CTG and packages it as an attachment
+func (exprt OioXdsExporter) attachWaveform(wt exporttypes.WaveformType, m *measurement.Measurement, mr repository.MeasurementExportState) (shared.Attachment, error) {
+	if len(m.Links.Waveform) == 0 {
+		return shared.Attachment{}, fmt.Errorf("No waveform referenced by %s", m.Links.Measurement)
+	}
+
+	w, err := exprt.api.FetchWaveform(m.Links.Waveform)
+	if err != nil {
+		return shared.Attachment{}, errors.Wrap(err, "Error retrieving waveform")
+	}
+
+	waveform.Summarise(m, w)
+	attachment, err := waveform.Encode(wt.GetFormat(), *m, w, mr.ID)
+	if err != nil {
+		return shared.Attachment{}, errors.Wrap(err, "Error encoding waveform")
+	}
+	return attachment, nil
+}
+
+// Checks whether a questionnaire result has any answers to export
+func (exprt OioXdsExporter) ShouldExportQuestionnaireResult(q measurement.QuestionnaireResult) bool {
+	return shared.HasMappedAnswers(exprt.questionMappings, q)
+}
+
+// ConvertQuestionnaireResult converts the mapped answers of a questionnaire result into a XDS generator request
+func (exprt OioXdsExporter) ConvertQuestionnaireResult(q measurement.QuestionnaireResult, qr repository.QuestionnaireExportState) (string, error) {
+	startTime := time.Now()
+	log.Debug("Starting conversion of ", q)
+
+	s := SelfMonitoredSample{}
+	s.CreatedByText = config.Export.CreatedBy
+
+	reports, err := shared.ReportsFromQuestionnaireResult(exprt.questionMappings, q, qr)
+	if err != nil {
+		return "", errors.Wrap(err, "Error parsing questionnaire result")
+	}
+	s.LaboratoryReports = reports
+
+	patient, err := exprt.api.FetchPatient(qr.Patient)
+	if err != nil {
+		log.Errorf("Error retrieving patient information - %v", err)
+		return "", errors.Wrap(err, "Error retriving information")
+	}
+
+	xdsGeneratorRequest, err := convertXdsGeneratorRequest(qr.ID, s, patient)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating XDS generator request")
+	}
+
+	log.Debug("type=conversion uuid= ", qr.ID.String(), " tt=", time.Since(startTime), " done")
+
+	return string(xdsGeneratorRequest), nil
+}
+
 // handles mapping ot OTH patient to KIH Citizen
 func mapCitizenToPatient(citizen *Citizen, patient measurement.PatientResult) {
 	if len(patient.FirstName) > 0 {
@@ -211,9 +311,10 @@ func mapCitizenToPatient(citizen *Citizen, patient measurement.PatientResult) {
 }
 
 // converts to XDS generator format and converts to []byte for posting to backend
-func convertXdsGeneratorRequest(uuid uuid.UUID, s SelfMonitoredSample, patient measurement.PatientResult) ([]byte, error) {
+func convertXdsGeneratorRequest(uuid uuid.UUID, s SelfMonitoredSample, patient measurement.PatientResult, attachments ...shared.Attachment) ([]byte, error) {
 	xdsGeneratorRequest := XdsGeneratorRequest{}
 	xdsGeneratorRequest.DocumentUuid = uuid
+	xdsGeneratorRequest.Attachments = attachments
 
 	log.Debugf("Person: %+v", patient)
 
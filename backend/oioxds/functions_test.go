@@ -1,12 +1,20 @@
 package oioxds
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/internal"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
 
@@ -47,3 +55,80 @@ func TestHeaders(t *testing.T) {
 		log.Printf("sym=%-5q blob:%q\n", sym, blob)
 	}
 }
+
+func TestConvertWaveformMeasurement(t *testing.T) {
+	appConfig := &app.Config{Level: "warn", Logger: logrus.New()}
+	appConfig.Export.Waveforms.Enabled = true
+
+	w := measurement.Waveform{SampleRate: 2, HeartRate: 64, Channels: []measurement.WaveformChannel{{Name: "II", Unit: "mV", Values: []float64{0.1, 0.5, 1.1, 0.2}}}}
+	api := internal.TestInjectorApi{Patient: measurement.PatientResult{UniqueID: "2512484916", FirstName: "Nancy"}, Waveform: w}
+	exporter, err := InitExporter(appConfig, api)
+	if err != nil {
+		t.Fatalf("Error setting up exporter - %v", err)
+	}
+
+	m := measurement.Measurement{Timestamp: time.Now(), Type: exporttypes.TYPE_NAME_ECG}
+	m.Links.Measurement = "http://clinician/api/patients/1/measurements/1"
+	m.Links.Waveform = "http://clinician/api/patients/1/measurements/1/waveform"
+
+	if !exporter.ShouldExport(m) {
+		t.Fatalf("Expected ECG to be exported")
+	}
+
+	converted, err := exporter.ConvertMeasurement(m, repository.MeasurementExportState{ID: uuid.New(), Patient: "http://clinician/api/patients/1"})
+	if err != nil {
+		t.Fatalf("Error converting - %v", err)
+	}
+
+	var request XdsGeneratorRequest
+	if err := json.Unmarshal([]byte(converted), &request); err != nil {
+		t.Fatalf("Error parsing request - %v", err)
+	}
+
+	if len(request.Attachments) != 1 || request.Attachments[0].MimeType != "text/xml" {
+		t.Fatalf("Expected aECG attachment got %v", request.Attachments)
+	}
+	reports := request.SelfMonitoringCollection[0].SelfMonitoringSamples[0].SelfMonitoringSample.LaboratoryReports
+	if len(reports) != 1 || reports[0].ResultText != "64" || reports[0].MeasurementDuration != "PT2S" {
+		t.Errorf("Unexpected reports %+v", reports)
+	}
+
+	m.Links.Waveform = ""
+	if _, err := exporter.ConvertMeasurement(m, repository.MeasurementExportState{ID: uuid.New()}); err == nil {
+		t.Errorf("Expected error without waveform")
+	}
+
+	m.Type = exporttypes.TYPE_NAME_CTG
+	if exporter.ShouldExport(m) {
+		t.Errorf("Expected CTG not to be exported without a code")
+	}
+}
+
+func TestInitExporterProfile(t *testing.T) {
+	appConfig := &app.Config{Level: "warn", Logger: logrus.New()}
+	appConfig.Export.Profiles = map[string]app.TypeProfile{
+		"vitals": {Types: []string{exporttypes.TYPE_NAME_PULSE, exporttypes.TYPE_NAME_SATURATION}},
+	}
+	appConfig.Export.OIOXDSExport.Profile = "vitals"
+
+	exporter, err := InitExporter(appConfig, internal.TestInjectorApi{})
+	if err != nil {
+		t.Fatalf("Error setting up exporter - %v", err)
+	}
+	if len(exporter.GetExportTypes()) != 2 {
+		t.Errorf("Expected the types of the profile - got %d", len(exporter.GetExportTypes()))
+	}
+	if exporter.ShouldExport(measurement.Measurement{Type: exporttypes.TYPE_NAME_WEIGHT}) {
+		t.Errorf("Expected weight not to be exported")
+	}
+
+	appConfig.Export.OIOXDSExport.Profile = "Vitals"
+	if _, err := InitExporter(appConfig, internal.TestInjectorApi{}); err != nil {
+		t.Errorf("Expected profile names to ignore case - %v", err)
+	}
+
+	appConfig.Export.OIOXDSExport.Profile = "unknown"
+	if _, err := InitExporter(appConfig, internal.TestInjectorApi{}); err == nil {
+		t.Errorf("Expected error for unknown profile")
+	}
+}
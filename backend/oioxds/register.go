@@ -0,0 +1,44 @@
+package oioxds
+
+import (
+	"net/http"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/registry"
+	"github.com/KvalitetsIT/kih-telecare-exporter/internal"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+func init() {
+	registry.Register(registry.Backend{
+		Name:        exporttypes.BACKEND_OIOXDS,
+		Description: "Exports PHMR documents to an XDS repository through the XDS generator",
+		Catalog:     exporttypes.BACKEND_OIOXDS,
+		New: func(config *app.Config, api measurement.MeasurementApi) (registry.ExportBackend, error) {
+			return InitExporter(config, api)
+		},
+		Settings: []registry.Setting{
+			{Key: "export.oioxds.xdsgenerator.url", Description: "URL the documents are posted to", Required: true, Value: func(c *app.Config) string { return c.Export.OIOXDSExport.XdsGenerator.URL }},
+			{Key: "export.oioxds.xdsgenerator.healthcheck", Description: "URL of the XDS generator health check", Value: func(c *app.Config) string { return c.Export.OIOXDSExport.XdsGenerator.HealthCheck }},
+			{Key: "export.oioxds.profile", Description: "Measurement type profile exported", Value: func(c *app.Config) string { return c.Export.OIOXDSExport.Profile }},
+		},
+		Endpoint: func(config *app.Config) string {
+			return config.Export.OIOXDSExport.XdsGenerator.URL
+		},
+		HealthCheck: func(config *app.Config) error {
+			return internal.PerformHealthCheck(newClient(), http.MethodGet, http.StatusOK, config.Export.OIOXDSExport.XdsGenerator.HealthCheck)
+		},
+		Flags: []registry.Flag{
+			{Name: "xdsgen", Usage: "URL for xds generator", Default: "http://localhost:9010/api/createphmr", Apply: func(c *app.Config, value string) {
+				c.Export.OIOXDSExport.XdsGenerator.URL = value
+			}},
+		},
+	})
+}
+
+// newClient returns the client used against the XDS generator. The certificate is always verified,
+// export.oioxds.skipSSLVerify has never been applied
+func newClient() http.Client {
+	return http.Client{}
+}
@@ -9,7 +9,6 @@ import (
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/shared"
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
-	"github.com/akyoto/cache"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
@@ -48,6 +47,7 @@ type Citizen struct {
 type XdsGeneratorRequest struct {
 	DocumentUuid             uuid.UUID                  `json:"DocumentUUID,omitempty"`
 	SelfMonitoringCollection []SelfMonitoringCollection `json:"SelfMonitoringCollection"`
+	Attachments              []shared.Attachment        `json:"Attachments,omitempty"`
 }
 
 type SelfMonitoringSamples struct {
@@ -65,14 +65,14 @@ type SelfMonitoringCollection struct {
 }
 
 type OioXdsExporter struct {
-	c              *cache.Cache
-	client         http.Client
-	skipSOSI       bool
-	config         *app.Config
-	healthCheckURL string
-	exportURL      string
-	exportedTypes  map[string]exporttypes.MeasurementType
-	api            measurement.MeasurementApi
+	client           http.Client
+	skipSOSI         bool
+	config           *app.Config
+	healthCheckURL   string
+	exportURL        string
+	exportedTypes    map[string]exporttypes.MeasurementType
+	questionMappings []app.QuestionMapping
+	api              measurement.MeasurementApi
 }
 
 var log *logrus.Logger
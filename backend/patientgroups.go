@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+// patientGroupFilter decides whether a patient is excluded from export based on its patient groups
+type patientGroupFilter struct {
+	allow map[string]bool
+	deny  map[string]bool
+}
+
+func newPatientGroupFilter(groups app.PatientGroupConfig) patientGroupFilter {
+	filter := patientGroupFilter{allow: make(map[string]bool), deny: make(map[string]bool)}
+	for _, g := range groups.Allow {
+		filter.allow[g] = true
+	}
+	for _, g := range groups.Deny {
+		filter.deny[g] = true
+	}
+	return filter
+}
+
+// isActive returns true if any groups are configured
+func (f patientGroupFilter) isActive() bool {
+	return len(f.allow) > 0 || len(f.deny) > 0
+}
+
+// isExcluded returns true if the patient is in a denied group, or in none of the allowed groups
+func (f patientGroupFilter) isExcluded(patient measurement.PatientResult) bool {
+	allowed := len(f.allow) == 0
+	for _, g := range patient.PatientGroups {
+		if f.deny[g.Name] || f.deny[g.Links.PatientGroup] {
+			return true
+		}
+		if f.allow[g.Name] || f.allow[g.Links.PatientGroup] {
+			allowed = true
+		}
+	}
+	return !allowed
+}
@@ -0,0 +1,151 @@
+package backend
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+)
+
+func TestPatientGroupFilter(t *testing.T) {
+	heart, err := mockApi{}.FetchPatient("http://clinician:8080/clinician/api/patients/13")
+	if err != nil {
+		t.Fatalf("Error reading patient %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		groups   app.PatientGroupConfig
+		patient  measurement.PatientResult
+		active   bool
+		excluded bool
+	}{
+		{"No filter", app.PatientGroupConfig{}, heart, false, false},
+		{"Allowed by name", app.PatientGroupConfig{Allow: []string{"KOL", "Hjertepatient"}}, heart, true, false},
+		{"Allowed by link", app.PatientGroupConfig{Allow: []string{"http://clinician:8080/clinician/api/patientgroups/3"}}, heart, true, false},
+		{"Not in allowed group", app.PatientGroupConfig{Allow: []string{"KOL"}}, heart, true, true},
+		{"No groups with allow list", app.PatientGroupConfig{Allow: []string{"KOL"}}, measurement.PatientResult{}, true, true},
+		{"Denied", app.PatientGroupConfig{Deny: []string{"Hjertepatient"}}, heart, true, true},
+		{"Deny wins over allow", app.PatientGroupConfig{Allow: []string{"Hjertepatient"}, Deny: []string{"http://clinician:8080/clinician/api/patientgroups/3"}}, heart, true, true},
+		{"Not denied", app.PatientGroupConfig{Deny: []string{"KOL"}}, heart, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := newPatientGroupFilter(tt.groups)
+			if filter.isActive() != tt.active {
+				t.Errorf("Expected active %v", tt.active)
+			}
+			if filter.isExcluded(tt.patient) != tt.excluded {
+				t.Errorf("Expected excluded %v", tt.excluded)
+			}
+		})
+	}
+}
+
+func TestHandleMeasurementExcludedPatientGroup(t *testing.T) {
+	db, conn, repo, err := setupTestDatabase()
+	if err != nil {
+		t.Fatal("Error setting up DB")
+	}
+	defer func() {
+		repo.Close()
+		conn.Close()
+		db.Close()
+	}()
+
+	application, err := app.InitConfig()
+	if err != nil {
+		t.Errorf("error instantiating %+v", err)
+	}
+	application.Logger = log
+	application.Export.Backend = "oioxds"
+	application.Export.OIOXDSExport.XdsGenerator.URL = "http://localhost:9010/api/createphmr"
+	application.Export.PatientGroups.Allow = []string{"KOL"}
+
+	exprtr, err := InitExporter(application, mockApi{}, repo)
+	if err != nil {
+		t.Fatalf("error instantiating %+v", err)
+	}
+
+	mm, err := measurementFromFile("weight.json")
+	if err != nil {
+		t.Fatalf("Error reading measurement from file - %v", err)
+	}
+	state, err := repo.FindOrCreateMeasurement(MeasurementToMeasurementType(mm))
+	if err != nil {
+		t.Fatalf("Error getting measurement from repository - %v", err)
+	}
+
+	res, exported, failed, rejected, _, err := exprtr.HandleMeasurement(mm, state)
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if exported != 0 || failed != 0 || rejected != 1 {
+		t.Errorf("Expected measurement to be rejected - got %d/%d/%d", exported, failed, rejected)
+	}
+	if res.Measurement.Status != repository.NO_EXPORT || res.Measurement.Reason != repository.REASON_PATIENT_GROUP_EXCLUDED {
+		t.Errorf("Expected no-export with reason - got %s '%s'", repository.StatusToText(res.Measurement.Status), res.Measurement.Reason)
+	}
+	if reasons := repo.GetReasons(repository.NO_EXPORT); reasons[repository.REASON_PATIENT_GROUP_EXCLUDED] != 1 {
+		t.Errorf("Expected reason in statistics - got %v", reasons)
+	}
+}
+
+func TestExportQuestionnaireResultsExcludedPatientGroup(t *testing.T) {
+	db, conn, repo, err := setupTestDatabase()
+	if err != nil {
+		t.Fatal("Error setting up DB")
+	}
+	defer func() {
+		repo.Close()
+		conn.Close()
+		db.Close()
+	}()
+
+	application, err := app.InitConfig()
+	if err != nil {
+		t.Errorf("error instantiating %+v", err)
+	}
+	application.Logger = log
+	application.Export.Backend = "oioxds"
+	application.Export.OIOXDSExport.XdsGenerator.URL = "http://localhost:9010/api/createphmr"
+	application.ClinicianConfig.BatchSize = 100
+	application.Export.PatientGroups.Deny = []string{"Hjertepatient"}
+	application.Export.Questionnaires.Mapping = []app.QuestionMapping{
+		{Question: "wellbeing", NpuCode: "MCS88202", Encoding: "numeric"},
+	}
+
+	var q measurement.QuestionnaireResult
+	data, err := ioutil.ReadFile("kih/shared/testdata/questionnaire_result.json")
+	if err != nil {
+		t.Fatalf("Error reading input file - %v", err)
+	}
+	if err := json.Unmarshal(data, &q); err != nil {
+		t.Fatalf("Error parsing input file - %v", err)
+	}
+
+	mockApi := mockApi{}
+	mockApi.questionnaires.Results = []measurement.QuestionnaireResult{q}
+	mockApi.questionnaires.Total = 1
+
+	exprtr, err := InitExporter(application, mockApi, repo)
+	if err != nil {
+		t.Fatalf("error instantiating %+v", err)
+	}
+
+	res, exported, failed, rejected, err := exprtr.ExportQuestionnaireResults(time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if len(res) != 1 || exported != 0 || failed != 0 || rejected != 1 {
+		t.Fatalf("Expected questionnaire result to be rejected - got %d/%d/%d", exported, failed, rejected)
+	}
+	if state := res[0].QuestionnaireResult; state.Status != repository.NO_EXPORT || state.Reason != repository.REASON_PATIENT_GROUP_EXCLUDED {
+		t.Errorf("Expected no-export with reason - got %s '%s'", repository.StatusToText(state.Status), state.Reason)
+	}
+}
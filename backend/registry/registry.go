@@ -0,0 +1,156 @@
+// Package registry holds the export backends. Each backend package registers itself by name when it is imported, so
+// the exporter and the commands resolve backends without knowing them
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+)
+
+// ExportBackend converts and exports measurements and questionnaire results to a destination
+type ExportBackend interface {
+	ConvertMeasurement(m measurement.Measurement, mr repository.MeasurementExportState) (string, error)
+	ExportMeasurement(string) (string, error)
+	ShouldExport(m measurement.Measurement) bool
+	ConvertQuestionnaireResult(q measurement.QuestionnaireResult, qr repository.QuestionnaireExportState) (string, error)
+	ShouldExportQuestionnaireResult(q measurement.QuestionnaireResult) bool
+	GetExportTypes() map[string]exporttypes.MeasurementType
+	CheckHealth() error
+}
+
+// Setting is a configuration key used by a backend
+type Setting struct {
+	Key         string
+	Description string
+	Required    bool
+	// Value returns the configured value
+	Value func(config *app.Config) string
+}
+
+// Flag is a command line override of a setting, used by testinject to point the backend at a test destination
+type Flag struct {
+	Name    string
+	Usage   string
+	Default string
+	// Apply sets the value in the configuration
+	Apply func(config *app.Config, value string)
+}
+
+// Backend is the registration of an export backend
+type Backend struct {
+	Name        string
+	Description string
+	// Catalog is the backend the measurement type catalog defines the types under. Empty means the shared kihdb
+	// definitions
+	Catalog string
+	// New sets up the backend from the configuration
+	New func(config *app.Config, api measurement.MeasurementApi) (ExportBackend, error)
+	// Settings is the configuration schema of the backend
+	Settings []Setting
+	// Endpoint returns the destination of the exports, for the status overview
+	Endpoint func(config *app.Config) string
+	// HealthCheck checks the destination of the exports without setting up the backend
+	HealthCheck func(config *app.Config) error
+	// Flags are the overrides testinject offers for the backend
+	Flags []Flag
+}
+
+var (
+	mu       sync.RWMutex
+	backends = make(map[string]Backend)
+)
+
+// Register adds a backend. It panics when the registration is incomplete or the name is taken, as backends register
+// themselves when their package is initialized
+func Register(b Backend) {
+	if len(b.Name) == 0 || b.New == nil {
+		panic("registry: backend needs a name and a constructor")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := backends[b.Name]; ok {
+		panic(fmt.Sprintf("registry: backend %s registered twice", b.Name))
+	}
+	backends[b.Name] = b
+}
+
+// Lookup returns the backend registered by the name
+func Lookup(name string) (Backend, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	b, ok := backends[name]
+	return b, ok
+}
+
+// Backends returns the registered backends sorted by name
+func Backends() []Backend {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	var result []Backend
+	for _, b := range backends {
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// Names returns the names of the registered backends, sorted and comma separated
+func Names() string {
+	var names []string
+	for _, b := range Backends() {
+		names = append(names, b.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// CatalogKey returns the backend the catalog defines the types of the backend under
+func (b Backend) CatalogKey() string {
+	if len(b.Catalog) == 0 {
+		return exporttypes.BACKEND_KIHDB
+	}
+	return b.Catalog
+}
+
+// Validate checks that the required settings of the backend are configured
+func (b Backend) Validate(config *app.Config) error {
+	var missing []string
+	for _, s := range b.Settings {
+		if s.Required && s.Value != nil && len(s.Value(config)) == 0 {
+			missing = append(missing, s.Key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("Backend %s is missing configuration of %s", b.Name, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Resolve sets up the named backend after validating its configuration
+func Resolve(name string, config *app.Config, api measurement.MeasurementApi) (ExportBackend, error) {
+	b, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("Unsupported backend %s - supported backends: %s", name, Names())
+	}
+	if err := b.Validate(config); err != nil {
+		return nil, err
+	}
+	return b.New(config, api)
+}
+
+// Endpoint returns the destination of the configured backend
+func Endpoint(config *app.Config) string {
+	b, ok := Lookup(config.Export.Backend)
+	if !ok || b.Endpoint == nil {
+		return "Unknown"
+	}
+	return b.Endpoint(config)
+}
@@ -0,0 +1,76 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+func TestRegistry(t *testing.T) {
+	created := 0
+	Register(Backend{
+		Name: "test",
+		New: func(config *app.Config, api measurement.MeasurementApi) (ExportBackend, error) {
+			created++
+			return nil, nil
+		},
+		Settings: []Setting{
+			{Key: "export.test.url", Required: true, Value: func(c *app.Config) string { return c.Export.CreatedBy }},
+		},
+		Endpoint: func(config *app.Config) string { return "http://test" },
+	})
+
+	if _, ok := Lookup("test"); !ok {
+		t.Fatalf("Expected test backend to be registered")
+	}
+	if !strings.Contains(Names(), "test") {
+		t.Errorf("Expected test in names got %s", Names())
+	}
+
+	config := &app.Config{}
+	if _, err := Resolve("unknown", config, nil); err == nil {
+		t.Errorf("Expected error for unknown backend")
+	}
+	if _, err := Resolve("test", config, nil); err == nil || !strings.Contains(err.Error(), "export.test.url") {
+		t.Errorf("Expected missing configuration got %v", err)
+	}
+	if created != 0 {
+		t.Errorf("Expected backend not to be created without configuration")
+	}
+
+	config.Export.CreatedBy = "configured"
+	if _, err := Resolve("test", config, nil); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if created != 1 {
+		t.Errorf("Expected backend to be created")
+	}
+
+	config.Export.Backend = "test"
+	if endpoint := Endpoint(config); endpoint != "http://test" {
+		t.Errorf("Expected endpoint of test backend got %s", endpoint)
+	}
+	config.Export.Backend = "unknown"
+	if endpoint := Endpoint(config); endpoint != "Unknown" {
+		t.Errorf("Expected unknown endpoint got %s", endpoint)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected registering twice to panic")
+		}
+	}()
+	Register(Backend{Name: "test", New: func(config *app.Config, api measurement.MeasurementApi) (ExportBackend, error) { return nil, nil }})
+}
+
+func TestCatalogKey(t *testing.T) {
+	if key := (Backend{Name: "fhir"}).CatalogKey(); key != exporttypes.BACKEND_KIHDB {
+		t.Errorf("Expected the shared kihdb definitions got %s", key)
+	}
+	if key := (Backend{Name: "xds", Catalog: exporttypes.BACKEND_OIOXDS}).CatalogKey(); key != exporttypes.BACKEND_OIOXDS {
+		t.Errorf("Expected oioxds got %s", key)
+	}
+}
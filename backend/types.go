@@ -1,21 +1,27 @@
 package backend
 
 import (
+	"time"
+
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
 )
 
 type ExportResult struct {
-	Success     bool
-	Measurement repository.MeasurementExportState
+	Success             bool
+	Measurement         repository.MeasurementExportState
+	QuestionnaireResult *repository.QuestionnaireExportState `json:",omitempty"`
 }
 
 // Exporter interface to denote
 type Exporter interface {
 	ExportMeasurements() ([]ExportResult, error)
 	ShouldExport(m measurement.Measurement) bool
-	HandleMeasurement(measurement measurement.Measurement, m repository.MeasurementExportState) (ExportResult, int, int, int, error)
+	// HandleMeasurement exports the measurement unless it is rejected. Returns number of exported, failed, rejected and invalid
+	HandleMeasurement(measurement measurement.Measurement, m repository.MeasurementExportState) (ExportResult, int, int, int, int, error)
 	ExportMeasurement(measurement measurement.Measurement, m repository.MeasurementExportState) (ExportResult, error)
+	// ExportQuestionnaireResults exports questionnaire results since the timestamp. Returns number of exported, failed and rejected
+	ExportQuestionnaireResults(since time.Time) ([]ExportResult, int, int, int, error)
 	MarkPermanentFailed() error
 	CheckHealth() error
 }
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/registry"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	// Export backends register themselves in the registry
+	_ "github.com/KvalitetsIT/kih-telecare-exporter/backend/oioxds"
+)
+
+var checkBackends bool
+
+func init() {
+	backendsCmd.Flags().BoolVar(&checkBackends, "check", false, "Check configuration and health of the backends")
+	rootCmd.AddCommand(backendsCmd)
+}
+
+var backendsCmd = &cobra.Command{
+	Use:   "backends",
+	Short: "List the export backends and their configuration",
+	Run: func(cmd *cobra.Command, args []string) {
+		application, err := app.InitConfig()
+		if err != nil {
+			logrus.Fatal("Error initializing exporter ", err)
+		}
+
+		for _, b := range registry.Backends() {
+			configured := ""
+			if b.Name == application.Export.Backend {
+				configured = " (configured)"
+			}
+			fmt.Printf("%s%s - %s\n", b.Name, configured, b.Description)
+			fmt.Printf("  catalog: %s\n", b.CatalogKey())
+
+			for _, s := range b.Settings {
+				required := ""
+				if s.Required {
+					required = " (required)"
+				}
+				value := ""
+				if s.Value != nil {
+					value = s.Value(application)
+				}
+				fmt.Printf("  %s%s: %q - %s\n", s.Key, required, value, s.Description)
+			}
+
+			if checkBackends {
+				fmt.Printf("  configuration: %s\n", checkResult(b.Validate(application)))
+				if b.HealthCheck != nil {
+					fmt.Printf("  health: %s\n", checkResult(b.HealthCheck(application)))
+				}
+			}
+		}
+	},
+}
+
+func checkResult(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return "ok"
+}
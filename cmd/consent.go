@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var consentCPR, consentPatient, consentComment string
+
+func init() {
+	consentAddCmd.Flags().StringVar(&consentCPR, "cpr", "", "CPR of the citizen")
+	consentAddCmd.Flags().StringVar(&consentPatient, "patient", "", "Clinician link to the patient")
+	consentAddCmd.Flags().StringVar(&consentComment, "comment", "", "Comment, e.g. how consent was withdrawn")
+
+	consentCmd.AddCommand(consentListCmd, consentAddCmd, consentRemoveCmd, consentImportCmd)
+	rootCmd.AddCommand(consentCmd)
+}
+
+var consentCmd = &cobra.Command{
+	Use:   "consent",
+	Short: "Manage citizens who have withdrawn consent to export",
+}
+
+var consentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List registered consent entries",
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := setupConsentRepository()
+		defer repo.Close()
+
+		entries, err := repo.FindConsents()
+		if err != nil {
+			log.Fatal("Error retrieving consent ", err)
+		}
+		for _, c := range entries {
+			fmt.Printf("%s\t%s\t%s\t%s\n", c.ID, c.CPR, c.Patient, c.Comment)
+		}
+	},
+}
+
+var consentAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Register withdrawn consent by cpr or patient link",
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := setupConsentRepository()
+		defer repo.Close()
+
+		c, err := repo.CreateConsent(repository.ConsentEntry{CPR: consentCPR, Patient: consentPatient, Comment: consentComment})
+		if err != nil {
+			log.Fatal("Error registering consent ", err)
+		}
+		fmt.Println("Registered", c.ID)
+	},
+}
+
+var consentRemoveCmd = &cobra.Command{
+	Use:   "remove <id>",
+	Short: "Remove a consent entry",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		repo := setupConsentRepository()
+		defer repo.Close()
+
+		if err := repo.DeleteConsent(args[0]); err != nil {
+			log.Fatal("Error removing consent ", err)
+		}
+		fmt.Println("Removed", args[0])
+	},
+}
+
+var consentImportCmd = &cobra.Command{
+	Use:   "import <file.csv>",
+	Short: "Import consent entries from CSV with the columns cpr, patient and comment",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		if err != nil {
+			logrus.Fatal("Error opening file ", err)
+		}
+		defer f.Close()
+
+		entries, err := repository.ParseConsentCSV(f)
+		if err != nil {
+			logrus.Fatal("Error parsing file ", err)
+		}
+
+		repo := setupConsentRepository()
+		defer repo.Close()
+
+		if _, err := repo.CreateConsents(entries); err != nil {
+			log.Fatal("Error registering consent - no entries imported ", err)
+		}
+		fmt.Println("Imported", len(entries), "entries")
+	},
+}
+
+func setupConsentRepository() repository.Repository {
+	application, err := app.InitConfig()
+	if err != nil {
+		logrus.Fatal("Error initializing exporter ", err)
+	}
+
+	pkg := app.GetPackage(reflect.TypeOf(empty{}).PkgPath())
+	log = app.NewLogger(application.GetLoggerLevel(pkg))
+
+	conn, err := repository.OpenDatabase(application)
+	if err != nil {
+		log.Fatal("Error connecting to database ", err)
+	}
+
+	repo, err := repository.InitRepository(application, conn)
+	if err != nil {
+		log.Fatal("Error initializing repository ", err)
+	}
+	return repo
+}
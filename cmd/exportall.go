@@ -9,7 +9,6 @@ import (
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend"
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
-	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -34,15 +33,9 @@ var exportCmd = &cobra.Command{
 		pkg := app.GetPackage(reflect.TypeOf(empty{}).PkgPath())
 		log = app.NewLogger(application.GetLoggerLevel(pkg))
 
-		dbstr, err := application.CreateDatabaseURL()
-		log.Debug("DB ", dbstr)
+		conn, err := repository.OpenDatabase(application)
 		if err != nil {
-			log.Fatal("Error parsing db url: ", err)
-		}
-
-		conn, err := sqlx.Open("mysql", dbstr)
-		if err != nil {
-			panic(err)
+			log.Fatal("Error opening database: ", err)
 		}
 		conn.SetMaxOpenConns(10)
 
@@ -53,6 +46,7 @@ var exportCmd = &cobra.Command{
 		if err != nil {
 			log.Fatal("Error initializing exporter ", err)
 		}
+		api = measurement.InitPatientCache(application, api, repo)
 
 		exprtr, err := backend.InitExporter(application, api, repo)
 		if err != nil {
@@ -81,6 +75,7 @@ func reExportAll(application *app.Config, api measurement.MeasurementApi, repo r
 	exports := []backend.ExportResult{}
 
 	rejected := 0
+	invalid := 0
 	exported := 0
 	failed := 0
 	iteration := 0
@@ -118,14 +113,19 @@ func reExportAll(application *app.Config, api measurement.MeasurementApi, repo r
 				log.Info("M, ", m, " is flagged as no-export")
 				handled++
 				continue
+			case repository.INVALID:
+				log.Info("M, ", m, " is flagged as invalid")
+				handled++
+				continue
 			case repository.FAILED:
 				log.Debug("M, ", m, " is already flaggged failed")
 				handled++
 				continue
 			default:
-				export, ex, fai, re, _ := e.HandleMeasurement(measurement, m)
+				export, ex, fai, re, inv, _ := e.HandleMeasurement(measurement, m)
 				exports = append(exports, export)
 				rejected += re
+				invalid += inv
 				exported += ex
 				failed += fai
 
@@ -134,6 +134,18 @@ func reExportAll(application *app.Config, api measurement.MeasurementApi, repo r
 		iteration++
 	}
 
+	if application.Export.Questionnaires.Enabled {
+		qExports, ex, fai, re, err := e.ExportQuestionnaireResults(startTime.Lastrun)
+		if err != nil {
+			log.Errorf("Error exporting questionnaire results - %v", err)
+			fai++
+		}
+		exports = append(exports, qExports...)
+		rejected += re
+		exported += ex
+		failed += fai
+	}
+
 	if failed > 0 {
 		startTime.Status = repository.FAILED
 	} else {
@@ -156,11 +168,11 @@ func reExportAll(application *app.Config, api measurement.MeasurementApi, repo r
 
 	fmt.Println("Storted run", run)
 	log.Info(
-		fmt.Sprintf("type=exportall uuid=%s completed=%s starttime=%s iterations=%d tt=%d total=%d exported=%d rejected=%d failed=%d wasexported=%d",
+		fmt.Sprintf("type=exportall uuid=%s completed=%s starttime=%s iterations=%d tt=%d total=%d exported=%d rejected=%d invalid=%d failed=%d wasexported=%d",
 			startTime.Id.String(), time.Now().Format(time.RFC3339),
 			startTime.Lastrun.Format(time.RFC3339),
 			iteration, time.Since(start).Milliseconds(),
-			exported+failed+rejected, exported, rejected, failed, handled))
+			exported+failed+rejected+invalid, exported, rejected, invalid, failed, handled))
 
 	return exports, nil
 }
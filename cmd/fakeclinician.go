@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/internal/fakeclinician"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var fakeOptions fakeclinician.Options
+var fakePort int
+
+func init() {
+	rootCmd.AddCommand(fakeClinicianCmd)
+	fakeClinicianCmd.Flags().IntVarP(&fakePort, "port", "p", 8370, "Port to listen on")
+	fakeClinicianCmd.Flags().StringVar(&fakeOptions.BaseURL, "url", "", "Base URL used in links (default http://localhost:<port>)")
+	fakeClinicianCmd.Flags().Int64Var(&fakeOptions.Seed, "seed", 1, "Seed for the generated data")
+	fakeClinicianCmd.Flags().IntVar(&fakeOptions.Patients, "patients", 10, "Number of patients")
+	fakeClinicianCmd.Flags().IntVar(&fakeOptions.Measurements, "measurements", 100, "Number of measurements")
+	fakeClinicianCmd.Flags().IntVar(&fakeOptions.Days, "days", 7, "Number of days the measurements are spread over")
+	fakeClinicianCmd.Flags().StringSliceVar(&fakeOptions.Types, "types", nil, fmt.Sprintf("Measurement types to generate (default all: %v)", fakeclinician.SupportedTypes()))
+	fakeClinicianCmd.Flags().StringSliceVar(&fakeOptions.Devices, "devices", nil, "Devices as manufacturer/model (default a known device per type)")
+	fakeClinicianCmd.Flags().Float64Var(&fakeOptions.ErrorRate, "error-rate", 0, "Fraction of requests answered with an internal server error")
+}
+
+var fakeClinicianCmd = &cobra.Command{
+	Use:   "fakeclinician",
+	Short: "Serves synthetic patients and measurements in the clinician API format",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(fakeOptions.BaseURL) == 0 {
+			fakeOptions.BaseURL = fmt.Sprintf("http://localhost:%d", fakePort)
+		}
+		fakeOptions.Now = time.Now()
+
+		server, err := fakeclinician.New(fakeOptions)
+		if err != nil {
+			logrus.Fatal("Error generating data ", err)
+		}
+
+		logrus.Infof("Serving fake clinician API on %s", fakeOptions.BaseURL)
+		if err := http.ListenAndServe(fmt.Sprintf(":%d", fakePort), server.Router()); err != nil {
+			logrus.Fatal("Error creating HTTP endpoint ", err)
+		}
+	},
+}
@@ -1,15 +1,11 @@
 package cmd
 
 import (
-	"database/sql"
 	"fmt"
 	"os"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/app"
-	_ "github.com/go-sql-driver/mysql"
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/mysql"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -30,29 +26,23 @@ var migrateCmd = &cobra.Command{
 
 		log = application.Logger
 		log.Info("starting")
-		dburl, err := application.CreateDatabaseURL()
+		dbType, err := application.DatabaseType()
 		if err != nil {
-			log.Fatalln("Error retrieving database credentials", err)
+			log.Fatalln("Error retrieving database type", err)
 		}
 
-		db, err := sql.Open("mysql", dburl)
+		conn, err := repository.OpenDatabase(application)
 		if err != nil {
 			log.Fatal("Error connection to database ", err)
 		}
-		if err := db.Ping(); err != nil {
+		if err := conn.Ping(); err != nil {
 			log.Fatal("Error communicating with database ", err)
 		} else {
 			log.Debug("DB pinged successfully")
 		}
 
-		driver, _ := mysql.WithInstance(db, &mysql.Config{})
-
-		log.Debug("D", driver)
-		m, err := migrate.NewWithDatabaseInstance(
-			"file://migrations",
-			"mysql",
-			driver,
-		)
+		log.Debugf("Migrating %s database", dbType)
+		m, err := repository.NewMigrate(conn.DB, dbType, "migrations")
 		if err != nil {
 			log.Fatal("Error getting migrations", err)
 		}
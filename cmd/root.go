@@ -64,4 +64,6 @@ func initConfig() {
 	viper.SetDefault("export.kih.version", 1)
 	viper.SetDefault("export.retrydays", 15)
 	viper.SetDefault("export.start", "2019-06-01")
+	viper.SetDefault("clinician.cache.ttl", "1h")
+	viper.SetDefault("clinician.cache.size", 1000)
 }
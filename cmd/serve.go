@@ -10,7 +10,6 @@ import (
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
 	"github.com/KvalitetsIT/kih-telecare-exporter/resources"
-	"github.com/jmoiron/sqlx"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -40,15 +39,9 @@ var serveCmd = &cobra.Command{
 		pkg := app.GetPackage(reflect.TypeOf(empty{}).PkgPath())
 		log = app.NewLogger(application.GetLoggerLevel(pkg))
 
-		dbstr, err := application.CreateDatabaseURL()
-		log.Debug("DB ", dbstr)
+		conn, err := repository.OpenDatabase(application)
 		if err != nil {
-			log.Fatal("Error parsing db url: ", err)
-		}
-
-		conn, err := sqlx.Open("mysql", dbstr)
-		if err != nil {
-			panic(err)
+			log.Fatal("Error opening database: ", err)
 		}
 		conn.SetMaxOpenConns(10)
 
@@ -61,6 +54,7 @@ var serveCmd = &cobra.Command{
 		if err != nil {
 			log.Fatal("Error initializing exporter ", err)
 		}
+		api = measurement.InitPatientCache(application, api, repo)
 
 		exprtr, err := backend.InitExporter(application, api, repo)
 		if err != nil {
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"os"
 	"reflect"
 	"strings"
 	"time"
@@ -12,7 +11,7 @@ import (
 	"github.com/KvalitetsIT/kih-telecare-exporter/app"
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend"
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend/kih/exporttypes"
-	"github.com/KvalitetsIT/kih-telecare-exporter/backend/oioxds"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/registry"
 	"github.com/KvalitetsIT/kih-telecare-exporter/internal"
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
@@ -34,15 +33,16 @@ var (
 	setnow        bool
 	date          string
 	kihurl        string
-	xdsgenerator  string
 	xdsserver     string
+	// backendFlags holds the values of the flags the backends register, by flag name
+	backendFlags = make(map[string]*string)
 )
 
 func init() {
 	rootCmd.AddCommand(testInjectCmd)
 	// Default for when reports is to be started from
 	viper.SetDefault("clinician.batchsize", 100)
-	testInjectCmd.Flags().StringVarP(&backendImpl, "backend", "b", "kih", "-b indicates with exporter backend to use. Supported backends: kih,oioxds")
+	testInjectCmd.Flags().StringVarP(&backendImpl, "backend", "b", "", fmt.Sprintf("-b indicates with exporter backend to use, defaults to the configured backend. Supported backends: %s", registry.Names()))
 	testInjectCmd.Flags().StringVarP(&patient, "patient", "p", "", "-p is a path to JSON file with patient information")
 	testInjectCmd.Flags().StringVarP(&file, "file", "f", "", "-f is a path to JSON file measurent data to be sent")
 	testInjectCmd.Flags().StringVarP(&source, "source", "s", "", "-s is a path to directory with JSON files with measurent data to be sent")
@@ -56,9 +56,17 @@ func init() {
 	testInjectCmd.Flags().StringVarP(&kihurl, "kihurl", "", "https://kihdb-devel.oth.io/services/monitoringDataset", "Sets URL for KIHDB endpoint (https://kihdb-devel.oth.io/services/monitoringDataset)")
 
 	// XDS Flags
-	testInjectCmd.Flags().StringVarP(&xdsgenerator, "xdsgen", "", "http://localhost:9010/api/createphmr", "URL for xds generator")
 	testInjectCmd.Flags().StringVarP(&xdsserver, "xdsrepo", "", "", "URL for xds Server")
 
+	// Backend flags, e.g. the URL of the XDS generator
+	for _, b := range registry.Backends() {
+		for _, f := range b.Flags {
+			if testInjectCmd.Flags().Lookup(f.Name) == nil {
+				backendFlags[f.Name] = testInjectCmd.Flags().String(f.Name, f.Default, f.Usage)
+			}
+		}
+	}
+
 	if err := testInjectCmd.MarkFlagRequired("patient"); err != nil {
 		logrus.Fatalf("error setting up flags %v", err)
 	}
@@ -68,18 +76,20 @@ func init() {
 }
 
 func setupExporterBackend(application *app.Config, dummyApi measurement.MeasurementApi) backend.ExportBackend {
-	var exporter backend.ExportBackend
-
-	switch backendImpl {
-	case "oioxds":
-		log.Warnf("Using OIO XDS Backend")
-		log.Debugf("Use SOSI? %v", usesosi)
-		application.Export.OIOXDSExport.XdsGenerator.URL = xdsgenerator
-
-		exporter = oioxds.InitExporter(application, dummyApi)
-	default:
-		log.Warnf("Unsupported backend %s", backendImpl)
-		os.Exit(1)
+	if len(backendImpl) == 0 {
+		backendImpl = application.Export.Backend
+	}
+	log.Warnf("Using backend %s", backendImpl)
+	log.Debugf("Use SOSI? %v", usesosi)
+	if b, ok := registry.Lookup(backendImpl); ok {
+		for _, f := range b.Flags {
+			f.Apply(application, *backendFlags[f.Name])
+		}
+	}
+
+	exporter, err := registry.Resolve(backendImpl, application, dummyApi)
+	if err != nil {
+		log.Fatalf("Error setting up exporter - %v", err)
 	}
 
 	return exporter
@@ -125,11 +135,13 @@ func exporterMeasurements(application *app.Config, expr backend.ExportBackend, m
 		if "blood_pressure" == v.Type {
 			exportType := types[v.Type]
 			fmt.Printf("Actual Value: %f/%f\n", v.Measurement.Systolic, v.Measurement.Diastolic)
-			t := exportType.(exporttypes.BloodPressureType)
+			t := exportType.(exporttypes.CompositeType)
+			systolic, _ := t.GetComponent(measurement.COMPONENT_SYSTOLIC)
+			diastolic, _ := t.GetComponent(measurement.COMPONENT_DIASTOLIC)
 
-			fmt.Printf("Sent Value..: %v/%v\n", t.GetSystolic().GetResultText(v), t.GetDiastolic().GetResultText(v))
+			fmt.Printf("Sent Value..: %v/%v\n", systolic.GetType().GetResultText(v), diastolic.GetType().GetResultText(v))
 		} else {
-			fmt.Printf("Actual Value: %f\n", v.Measurement.Value)
+			fmt.Printf("Actual Value: %s\n", v.Measurement.Value)
 			fmt.Printf("Sent Value..: %v\n", types[v.Type].GetResultText(v))
 		}
 		fmt.Printf("Unit........: %v\n", v.Measurement.Unit)
@@ -7,7 +7,10 @@ import (
 )
 
 type TestInjectorApi struct {
-	Patient measurement.PatientResult
+	Patient    measurement.PatientResult
+	Waveform   measurement.Waveform
+	Thresholds measurement.PatientThresholds
+	Schedules  measurement.QuestionnaireSchedules
 }
 
 func (r TestInjectorApi) CheckHealth() error {
@@ -19,6 +22,21 @@ func (r TestInjectorApi) FetchMeasurements(since time.Time, offset int) (measure
 func (r TestInjectorApi) FetchMeasurement(m string) (measurement.Measurement, error) {
 	return measurement.Measurement{}, nil
 }
+func (r TestInjectorApi) FetchQuestionnaireResults(since time.Time, offset int) (measurement.QuestionnaireResultResponse, error) {
+	return measurement.QuestionnaireResultResponse{}, nil
+}
+func (r TestInjectorApi) FetchQuestionnaireResult(q string) (measurement.QuestionnaireResult, error) {
+	return measurement.QuestionnaireResult{}, nil
+}
+func (r TestInjectorApi) FetchPatientThresholds(t string) (measurement.PatientThresholds, error) {
+	return r.Thresholds, nil
+}
+func (r TestInjectorApi) FetchQuestionnaireSchedules(s string) (measurement.QuestionnaireSchedules, error) {
+	return r.Schedules, nil
+}
+func (r TestInjectorApi) FetchWaveform(w string) (measurement.Waveform, error) {
+	return r.Waveform, nil
+}
 func (r TestInjectorApi) FetchPatient(person string) (measurement.PatientResult, error) {
 	return r.Patient, nil
 }
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
@@ -17,8 +18,11 @@ func (r DummyRepo) UpdateExport(lr repository.RunStatus) error {
 	return nil
 }
 
-func (r DummyRepo) GetTotals() (int, int, int, int) {
-	return 0, 0, 0, 0
+func (r DummyRepo) GetTotals() (int, int, int, int, int) {
+	return 0, 0, 0, 0, 0
+}
+func (r DummyRepo) GetReasons(status int) map[string]int {
+	return map[string]int{}
 }
 func (r DummyRepo) GetRuns() (time.Time, int, int, int, int) {
 	return time.Now(), 0, 0, 0, 0
@@ -40,5 +44,32 @@ func (r DummyRepo) FindMeasurementsByStatus(status int) ([]repository.Measuremen
 	return []repository.MeasurementExportState{}, nil
 
 }
-func (r DummyRepo) CheckRepository() error { return nil }
-func (r DummyRepo) Close() error           { return nil }
+func (r DummyRepo) FindOrCreateQuestionnaireResult(q repository.QuestionnaireExportState) (repository.QuestionnaireExportState, error) {
+	return repository.QuestionnaireExportState{}, nil
+}
+func (r DummyRepo) UpdateQuestionnaireResult(q repository.QuestionnaireExportState) (repository.QuestionnaireExportState, error) {
+	return repository.QuestionnaireExportState{}, nil
+}
+func (r DummyRepo) FindQuestionnaireResultsByStatus(status int) ([]repository.QuestionnaireExportState, error) {
+	return []repository.QuestionnaireExportState{}, nil
+}
+func (r DummyRepo) CreateConsent(c repository.ConsentEntry) (repository.ConsentEntry, error) {
+	return c, nil
+}
+func (r DummyRepo) CreateConsents(entries []repository.ConsentEntry) ([]repository.ConsentEntry, error) {
+	return entries, nil
+}
+func (r DummyRepo) DeleteConsent(id string) error { return nil }
+func (r DummyRepo) FindConsents() ([]repository.ConsentEntry, error) {
+	return []repository.ConsentEntry{}, nil
+}
+func (r DummyRepo) FindConsent(patient, cpr string) ([]repository.ConsentEntry, error) {
+	return []repository.ConsentEntry{}, nil
+}
+func (r DummyRepo) HasConsentByCPR() (bool, error) { return false, nil }
+func (r DummyRepo) FindCachedPatient(link string) ([]byte, time.Time, error) {
+	return []byte{}, time.Time{}, fmt.Errorf("Patient %s not cached", link)
+}
+func (r DummyRepo) StoreCachedPatient(link string, data []byte) error { return nil }
+func (r DummyRepo) CheckRepository() error                            { return nil }
+func (r DummyRepo) Close() error                                      { return nil }
@@ -0,0 +1,339 @@
+// Package fakeclinician serves synthetic patients and measurements in the same shape as the clinician API
+package fakeclinician
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// Options controls the generated data
+type Options struct {
+	// Seed makes the generated data reproducible
+	Seed int64
+	// BaseURL is used when generating links, e.g. http://localhost:8370
+	BaseURL string
+	// Patients is the number of patients generated
+	Patients int
+	// Measurements is the total number of measurements generated
+	Measurements int
+	// Days the measurements are spread over, counting back from Now
+	Days int
+	// Now is the time of the latest measurement. Defaults to the current time
+	Now time.Time
+	// Types of measurements generated. Defaults to all supported types
+	Types []string
+	// Devices given as manufacturer/model. When empty each type uses a known device
+	Devices []string
+	// ErrorRate is the fraction of requests answered with an internal server error
+	ErrorRate float64
+}
+
+type generator func(r *rand.Rand) measurement.MeasurementValue
+
+type device struct {
+	manufacturer, model string
+}
+
+// generators for the supported measurement types
+var generators = map[string]generator{
+	"weight": func(r *rand.Rand) measurement.MeasurementValue {
+		return measurement.MeasurementValue{Unit: "kg", Value: measurement.NumericValue(round(50+r.Float64()*70, 1))}
+	},
+	"blood_pressure": func(r *rand.Rand) measurement.MeasurementValue {
+		return measurement.MeasurementValue{Unit: "mmHg", Systolic: float32(100 + r.Intn(60)), Diastolic: float32(60 + r.Intn(40))}
+	},
+	"pulse": func(r *rand.Rand) measurement.MeasurementValue {
+		return measurement.MeasurementValue{Unit: "BPM", Value: measurement.NumericValue(float64(50 + r.Intn(60)))}
+	},
+	"saturation": func(r *rand.Rand) measurement.MeasurementValue {
+		return measurement.MeasurementValue{Unit: "%", Value: measurement.NumericValue(float64(88 + r.Intn(13)))}
+	},
+	"temperature": func(r *rand.Rand) measurement.MeasurementValue {
+		return measurement.MeasurementValue{Unit: "°C", Value: measurement.NumericValue(round(36+r.Float64()*3.5, 1))}
+	},
+	"crp": func(r *rand.Rand) measurement.MeasurementValue {
+		return measurement.MeasurementValue{Unit: "mg/L", Value: measurement.NumericValue(float64(r.Intn(60)))}
+	},
+	"bloodsugar": func(r *rand.Rand) measurement.MeasurementValue {
+		return measurement.MeasurementValue{Unit: "mmol/L", Value: measurement.NumericValue(round(4+r.Float64()*8, 1))}
+	},
+}
+
+// defaultDevices are taken from the MedCom device whitelist
+var defaultDevices = map[string]device{
+	"weight":         {"A&D Medical", "UC-351PlusBT-Ci Bluetooth"},
+	"blood_pressure": {"A&D Medical", "UA-767PlusBT-Ci Bluetooth"},
+	"pulse":          {"A&D Medical", "UA-767PlusBT-Ci Bluetooth"},
+	"saturation":     {"Nonin", "3230 Bluetooth Smart Pulse Oximeter"},
+	"temperature":    {"A&D Medical", "UT-302PlusBT Bluetooth"},
+}
+
+var patientGroups = []string{"Hjertesvigt", "KOL", "Diabetes"}
+
+// SupportedTypes returns the measurement types that can be generated
+func SupportedTypes() []string {
+	var types []string
+	for t := range generators {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
+
+// Server holds the generated data and serves it
+type Server struct {
+	opts         Options
+	patients     map[string]measurement.PatientResult
+	measurements []measurement.Measurement
+	byLink       map[string]measurement.Measurement
+
+	mu     sync.Mutex
+	errors *rand.Rand
+}
+
+// New generates patients and measurements from the options
+func New(opts Options) (*Server, error) {
+	if opts.Patients <= 0 {
+		return nil, fmt.Errorf("At least one patient is required")
+	}
+	if opts.Days <= 0 {
+		opts.Days = 1
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if len(opts.Types) == 0 {
+		opts.Types = SupportedTypes()
+	}
+	opts.BaseURL = strings.TrimSuffix(opts.BaseURL, "/")
+
+	for _, t := range opts.Types {
+		if _, ok := generators[t]; !ok {
+			return nil, fmt.Errorf("Unsupported measurement type %s - supported types are %s", t, strings.Join(SupportedTypes(), ","))
+		}
+	}
+
+	var devices []device
+	for _, d := range opts.Devices {
+		parts := strings.SplitN(d, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid device %s - must be manufacturer/model", d)
+		}
+		devices = append(devices, device{manufacturer: parts[0], model: parts[1]})
+	}
+
+	s := &Server{
+		opts:     opts,
+		patients: make(map[string]measurement.PatientResult),
+		byLink:   make(map[string]measurement.Measurement),
+		errors:   rand.New(rand.NewSource(opts.Seed)),
+	}
+
+	r := rand.New(rand.NewSource(opts.Seed))
+	for i := 1; i <= opts.Patients; i++ {
+		p := s.generatePatient(r, i)
+		s.patients[strconv.Itoa(i)] = p
+	}
+
+	span := time.Duration(opts.Days) * 24 * time.Hour
+	for i := 1; i <= opts.Measurements; i++ {
+		patient := 1 + r.Intn(opts.Patients)
+		t := opts.Types[r.Intn(len(opts.Types))]
+
+		m := measurement.Measurement{
+			Timestamp:   opts.Now.Add(-time.Duration(r.Int63n(int64(span)))).Truncate(time.Second),
+			Type:        t,
+			Measurement: generators[t](r),
+		}
+		m.Links.Patient = fmt.Sprintf("%s/patients/%d", opts.BaseURL, patient)
+		m.Links.Measurement = fmt.Sprintf("%s/measurements/%d", m.Links.Patient, i)
+
+		d, ok := defaultDevices[t]
+		if len(devices) > 0 {
+			d, ok = devices[r.Intn(len(devices))], true
+		}
+		if ok {
+			m.Origin.DeviceMeasurement = measurement.DeviceMeasurement{
+				ConnectionType: "bluetooth_gatt",
+				Manufacturer:   d.manufacturer,
+				Model:          d.model,
+				PrimaryDeviceIdentifier: measurement.PrimaryDeviceIdentifier{
+					MacAddress: fmt.Sprintf("00:09:1F:%02X:%02X:%02X", r.Intn(256), r.Intn(256), r.Intn(256)),
+				},
+			}
+		} else {
+			m.Origin.ManualMeasurement.EnteredBy = "citizen"
+		}
+
+		s.measurements = append(s.measurements, m)
+		s.byLink[m.Links.Measurement] = m
+	}
+
+	sort.SliceStable(s.measurements, func(i, j int) bool {
+		return s.measurements[i].Timestamp.Before(s.measurements[j].Timestamp)
+	})
+
+	return s, nil
+}
+
+func (s *Server) generatePatient(r *rand.Rand, id int) measurement.PatientResult {
+	birth := time.Date(1930+r.Intn(70), time.Month(1+r.Intn(12)), 1+r.Intn(28), 0, 0, 0, 0, time.UTC)
+	sex := "female"
+	serial := 2 * r.Intn(5000)
+	if r.Intn(2) == 1 {
+		sex = "male"
+		serial++
+	}
+
+	p := measurement.PatientResult{
+		CreatedDate: s.opts.Now.AddDate(-1, 0, 0).Format(time.RFC3339),
+		UniqueID:    fmt.Sprintf("%s%04d", birth.Format("020106"), serial),
+		Username:    fmt.Sprintf("patient%d", id),
+		FirstName:   "Test",
+		LastName:    fmt.Sprintf("Patient %d", id),
+		DateOfBirth: birth.Format("02-01-2006"),
+		Sex:         sex,
+		Status:      "active",
+	}
+	p.Links.Self = fmt.Sprintf("%s/patients/%d", s.opts.BaseURL, id)
+	p.Links.Measurements = fmt.Sprintf("%s/measurement-types", p.Links.Self)
+
+	group := 1 + r.Intn(len(patientGroups))
+	p.PatientGroups = make([]struct {
+		Name  string `json:"name"`
+		Links struct {
+			PatientGroup string `json:"patientGroup"`
+		} `json:"links"`
+	}, 1)
+	p.PatientGroups[0].Name = patientGroups[group-1]
+	p.PatientGroups[0].Links.PatientGroup = fmt.Sprintf("%s/patientgroups/%d", s.opts.BaseURL, group)
+
+	return p
+}
+
+// Router returns the handler serving the clinician API
+func (s *Server) Router() http.Handler {
+	r := chi.NewRouter()
+
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(s.injectErrors)
+		r.Get("/measurements", s.measurementsHandler)
+		r.Get("/questionnaire_results", s.questionnaireResultsHandler)
+		r.Get("/patients/{patient}", s.patientHandler)
+		r.Get("/patients/{patient}/measurements/{measurement}", s.measurementHandler)
+	})
+
+	return r
+}
+
+func (s *Server) injectErrors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		fail := s.opts.ErrorRate > 0 && s.errors.Float64() < s.opts.ErrorRate
+		s.mu.Unlock()
+
+		if fail {
+			http.Error(w, "Injected error", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func queryInt(r *http.Request, name string, def int) int {
+	v, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+func (s *Server) measurementsHandler(w http.ResponseWriter, r *http.Request) {
+	offset := queryInt(r, "offset", 0)
+	max := queryInt(r, "max", 100)
+
+	var from time.Time
+	if v := r.URL.Query().Get("from"); len(v) > 0 {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid from %s", v), http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+
+	matches := []measurement.Measurement{}
+	for _, m := range s.measurements {
+		if !m.Timestamp.Before(from) {
+			matches = append(matches, m)
+		}
+	}
+
+	res := measurement.MeasurementResponse{Results: []measurement.Measurement{}, Total: len(matches), Max: max, Offset: offset}
+	if offset < len(matches) {
+		end := offset + max
+		if end > len(matches) {
+			end = len(matches)
+		}
+		res.Results = matches[offset:end]
+	}
+
+	page := func(offset int) string {
+		q := r.URL.Query()
+		q.Set("offset", strconv.Itoa(offset))
+		return fmt.Sprintf("%s/measurements?%s", s.opts.BaseURL, q.Encode())
+	}
+	res.Links.Self = page(offset)
+	if offset+max < res.Total {
+		res.Links.Next = page(offset + max)
+	}
+	if offset > 0 {
+		res.Links.Previous = page(offset - max)
+	}
+
+	render.JSON(w, r, res)
+}
+
+// questionnaireResultsHandler always returns an empty page, so questionnaire export can be enabled
+func (s *Server) questionnaireResultsHandler(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, measurement.QuestionnaireResultResponse{Results: []measurement.QuestionnaireResult{}, Max: queryInt(r, "max", 100), Offset: queryInt(r, "offset", 0)})
+}
+
+func (s *Server) patientHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := s.patients[chi.URLParam(r, "patient")]
+	if !ok {
+		http.Error(w, "Patient not found", http.StatusNotFound)
+		return
+	}
+	render.JSON(w, r, p)
+}
+
+func (s *Server) measurementHandler(w http.ResponseWriter, r *http.Request) {
+	link := fmt.Sprintf("%s/patients/%s/measurements/%s", s.opts.BaseURL, chi.URLParam(r, "patient"), chi.URLParam(r, "measurement"))
+	m, ok := s.byLink[link]
+	if !ok {
+		http.Error(w, "Measurement not found", http.StatusNotFound)
+		return
+	}
+	render.JSON(w, r, m)
+}
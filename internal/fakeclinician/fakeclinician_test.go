@@ -0,0 +1,106 @@
+package fakeclinician
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+)
+
+var now = time.Date(2020, 6, 4, 12, 0, 0, 0, time.UTC)
+
+func TestGenerateIsReproducible(t *testing.T) {
+	a, err := New(Options{Seed: 42, Patients: 3, Measurements: 20, Now: now})
+	if err != nil {
+		t.Fatalf("Error generating - %v", err)
+	}
+	b, _ := New(Options{Seed: 42, Patients: 3, Measurements: 20, Now: now})
+	c, _ := New(Options{Seed: 43, Patients: 3, Measurements: 20, Now: now})
+
+	if !reflect.DeepEqual(a.measurements, b.measurements) || !reflect.DeepEqual(a.patients, b.patients) {
+		t.Errorf("Expected the same data for the same seed")
+	}
+	if reflect.DeepEqual(a.measurements, c.measurements) {
+		t.Errorf("Expected different data for different seeds")
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	tests := []Options{
+		{Patients: 0, Measurements: 1},
+		{Patients: 1, Measurements: 1, Types: []string{"unknown"}},
+		{Patients: 1, Measurements: 1, Devices: []string{"no model"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := New(tt); err == nil {
+			t.Errorf("Expected error for %+v", tt)
+		}
+	}
+}
+
+func TestServeThroughClinicianApi(t *testing.T) {
+	var server *Server
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.Router().ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	server, err := New(Options{Seed: 1, BaseURL: ts.URL, Patients: 2, Measurements: 25, Now: now, Types: []string{"weight", "pulse"}, Devices: []string{"A&D Medical/UC-351PlusBT-Ci Bluetooth"}})
+	if err != nil {
+		t.Fatalf("Error generating - %v", err)
+	}
+
+	config := &app.Config{Level: "warn"}
+	config.ClinicianConfig.URL = ts.URL
+	config.ClinicianConfig.BatchSize = 10
+	api, err := measurement.InitMeasurementApi(config)
+	if err != nil {
+		t.Fatalf("Error creating api - %v", err)
+	}
+
+	if err := api.CheckHealth(); err != nil {
+		t.Errorf("Expected healthy fake - %v", err)
+	}
+
+	var fetched []measurement.Measurement
+	res := measurement.MeasurementResponse{}
+	for offset := 0; offset == 0 || offset < res.Total; offset += 10 {
+		res, err = api.FetchMeasurements(now.AddDate(0, 0, -2), offset)
+		if err != nil {
+			t.Fatalf("Error fetching measurements - %v", err)
+		}
+		fetched = append(fetched, res.Results...)
+	}
+	if len(fetched) != 25 {
+		t.Fatalf("Expected 25 measurements got %d", len(fetched))
+	}
+
+	for _, m := range fetched[:3] {
+		if m.Type != "weight" && m.Type != "pulse" {
+			t.Errorf("Unexpected type %s", m.Type)
+		}
+		if m.Origin.DeviceMeasurement.Model != "UC-351PlusBT-Ci Bluetooth" {
+			t.Errorf("Unexpected device %v", m.Origin)
+		}
+
+		single, err := api.FetchMeasurement(m.Links.Measurement)
+		if err != nil || !single.Timestamp.Equal(m.Timestamp) {
+			t.Errorf("Error fetching %s - %v", m.Links.Measurement, err)
+		}
+
+		patient, err := api.FetchPatient(m.Links.Patient)
+		if err != nil || len(patient.UniqueID) != 10 {
+			t.Errorf("Unexpected patient %v - %v", patient, err)
+		}
+	}
+
+	server.opts.ErrorRate = 1
+	if _, err := api.FetchMeasurements(now, 0); err == nil {
+		t.Errorf("Expected injected error")
+	}
+}
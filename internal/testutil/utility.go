@@ -6,9 +6,13 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"time"
 
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
@@ -31,45 +35,38 @@ func SetupTestSQLDatabase(dsn string) (*sql.DB, *sqlx.DB, error) {
 	return db, conn, nil
 }
 
+// PrepareDatabase drops the tables of the SQLite test database and applies the SQLite migrations
 func PrepareDatabase(db *sql.DB) error {
-	createQueryMeasurements := `
-    DROP TABLE IF EXISTS measurements;
-    CREATE TABLE IF NOT EXISTS measurements (
-  id TEXT UNIQUE NOT NULL,
-  measurement TEXT UNIQUE NOT NULL PRIMARY KEY,
-  patient TEXT,
-  status int,
-  backend_status int,
-  backend_reply text,
-  created_at datetime,
-  updated_at datetime);`
-
 	if db == nil {
 		return fmt.Errorf("DB instance is nil")
 	}
 
-	_, err := db.Exec(createQueryMeasurements)
+	// The migrations are not closed, as that closes the database
+	m, err := repository.NewMigrate(db, app.DATABASE_TYPE_SQLITE, migrationsDir())
 	if err != nil {
-		return errors.Wrap(err, "Error bootstrapping db / measurements")
+		return errors.Wrap(err, "Error getting migrations")
+	}
+	if err := m.Drop(); err != nil {
+		return errors.Wrap(err, "Error dropping tables")
 	}
 
-	createQueryRUnstatus := `
-DROP TABLE IF EXISTS runstatus;
-CREATE TABLE IF NOT EXISTS runstatus (
-  id text UNIQUE NOT NULL PRIMARY KEY,
-  lastrun datetime,
-  status int,
-  created_at datetime,
-  updated_at datetime);`
-
-	_, err = db.Exec(createQueryRUnstatus)
+	m, err = repository.NewMigrate(db, app.DATABASE_TYPE_SQLITE, migrationsDir())
 	if err != nil {
-		return errors.Wrap(err, "Error bootstrapping db / runstatus")
+		return errors.Wrap(err, "Error getting migrations")
+	}
+	if err := m.Up(); err != nil {
+		return errors.Wrap(err, "Error migrating database")
 	}
 
 	return nil
 }
 
+// migrationsDir returns the migrations of the repository, independent of the package running the tests
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
+
 func MeasurementFromFile(f string) (measurement.Measurement, error) {
 	var mm measurement.Measurement
 	weight_file, err := ioutil.ReadFile(f)
@@ -125,6 +122,26 @@ func (ma mockApi) FetchMeasurement(mea string) (measurement.Measurement, error)
 	return ma.measurements.Results[index], nil
 }
 
+func (ma mockApi) FetchQuestionnaireResults(since time.Time, offset int) (measurement.QuestionnaireResultResponse, error) {
+	return measurement.QuestionnaireResultResponse{}, nil
+}
+
+func (ma mockApi) FetchQuestionnaireResult(q string) (measurement.QuestionnaireResult, error) {
+	return measurement.QuestionnaireResult{}, fmt.Errorf("No questionnaire result found for %s", q)
+}
+
+func (ma mockApi) FetchPatientThresholds(t string) (measurement.PatientThresholds, error) {
+	return measurement.PatientThresholds{}, fmt.Errorf("No thresholds found for %s", t)
+}
+
+func (ma mockApi) FetchQuestionnaireSchedules(s string) (measurement.QuestionnaireSchedules, error) {
+	return measurement.QuestionnaireSchedules{}, fmt.Errorf("No schedules found for %s", s)
+}
+
+func (ma mockApi) FetchWaveform(w string) (measurement.Waveform, error) {
+	return measurement.Waveform{}, fmt.Errorf("No waveform found for %s", w)
+}
+
 func InitMockApi() (measurement.MeasurementApi, error) {
 	ma := mockApi{}
 
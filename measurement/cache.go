@@ -0,0 +1,254 @@
+package measurement
+
+import (
+	"container/list"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+)
+
+const (
+	DEFAULT_PATIENT_CACHE_TTL  = 1 * time.Hour
+	DEFAULT_PATIENT_CACHE_SIZE = 1000
+)
+
+// PatientStore is the optional persistent second tier of the patient cache
+type PatientStore interface {
+	FindCachedPatient(link string) ([]byte, time.Time, error)
+	StoreCachedPatient(link string, data []byte) error
+}
+
+// CacheStatistics holds the counters reported by the patient cache
+type CacheStatistics struct {
+	Size           int
+	MaxSize        int
+	Hits           uint64
+	Misses         uint64
+	PersistentHits uint64
+	Evictions      uint64
+}
+
+// CacheReporter is implemented by MeasurementApi decorators which caches data
+type CacheReporter interface {
+	CacheStatistics() CacheStatistics
+}
+
+type lruEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// lruEntries is a bounded map of expiring entries. When full the least recently used entry is evicted
+type lruEntries struct {
+	maxSize int
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+func newLRUEntries(maxSize int) *lruEntries {
+	return &lruEntries{maxSize: maxSize, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// get returns the entry and marks it as recently used. Expired entries are removed
+func (l *lruEntries) get(key string, now time.Time) (interface{}, bool) {
+	element, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := element.Value.(*lruEntry)
+	if now.After(entry.expires) {
+		l.lru.Remove(element)
+		delete(l.entries, key)
+		return nil, false
+	}
+
+	l.lru.MoveToFront(element)
+	return entry.value, true
+}
+
+// put adds or replaces the entry and returns the number of entries evicted to make room for it
+func (l *lruEntries) put(key string, value interface{}, expires time.Time) int {
+	if element, ok := l.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value = value
+		entry.expires = expires
+		l.lru.MoveToFront(element)
+		return 0
+	}
+
+	l.entries[key] = l.lru.PushFront(&lruEntry{key: key, value: value, expires: expires})
+
+	evicted := 0
+	for l.lru.Len() > l.maxSize {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.entries, oldest.Value.(*lruEntry).key)
+		evicted++
+	}
+	return evicted
+}
+
+func (l *lruEntries) len() int {
+	return l.lru.Len()
+}
+
+// patientCache decorates a MeasurementApi with a bounded LRU cache around FetchPatient, FetchPatientThresholds and
+// FetchQuestionnaireSchedules
+type patientCache struct {
+	MeasurementApi
+	ttl           time.Duration
+	persistentTTL time.Duration
+	maxSize       int
+	store         PatientStore
+
+	mu         sync.Mutex
+	patients   *lruEntries
+	thresholds *lruEntries
+	schedules  *lruEntries
+	stats      CacheStatistics
+	now        func() time.Time
+}
+
+// InitPatientCache wraps api in a patient cache configured from the clinician settings. The store is only used when persistence is enabled
+func InitPatientCache(appConfig *app.Config, api MeasurementApi, store PatientStore) MeasurementApi {
+	if log == nil {
+		pkg := app.GetPackage(reflect.TypeOf(Measurement{}).PkgPath())
+		log = app.NewLogger(appConfig.GetLoggerLevel(pkg))
+	}
+
+	cacheConfig := appConfig.ClinicianConfig.Cache
+	if !cacheConfig.Persistent {
+		store = nil
+	}
+
+	log.Debugf("Setting up patient cache - ttl: %s size: %d persistent: %v", cacheConfig.TTL, cacheConfig.Size, store != nil)
+	return newPatientCache(api, cacheConfig.TTL, cacheConfig.PersistentTTL, cacheConfig.Size, store)
+}
+
+func newPatientCache(api MeasurementApi, ttl, persistentTTL time.Duration, maxSize int, store PatientStore) *patientCache {
+	if ttl <= 0 {
+		ttl = DEFAULT_PATIENT_CACHE_TTL
+	}
+	if persistentTTL <= 0 {
+		persistentTTL = ttl
+	}
+	if maxSize <= 0 {
+		maxSize = DEFAULT_PATIENT_CACHE_SIZE
+	}
+
+	return &patientCache{
+		MeasurementApi: api,
+		ttl:            ttl,
+		persistentTTL:  persistentTTL,
+		maxSize:        maxSize,
+		store:          store,
+		patients:       newLRUEntries(maxSize),
+		thresholds:     newLRUEntries(maxSize),
+		schedules:      newLRUEntries(maxSize),
+		now:            time.Now,
+	}
+}
+
+// FetchPatient returns the patient from the cache, the persistent store or the wrapped api - in that order
+func (c *patientCache) FetchPatient(person string) (PatientResult, error) {
+	if patient, found := c.lookup(person); found {
+		return patient, nil
+	}
+
+	if patient, found := c.lookupStore(person); found {
+		c.add(person, patient)
+		return patient, nil
+	}
+
+	patient, err := c.MeasurementApi.FetchPatient(person)
+	if err != nil {
+		return patient, err
+	}
+
+	c.add(person, patient)
+	c.persist(person, patient)
+
+	return patient, nil
+}
+
+// CacheStatistics returns a snapshot of the cache counters
+func (c *patientCache) CacheStatistics() CacheStatistics {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.patients.len()
+	stats.MaxSize = c.maxSize
+	return stats
+}
+
+func (c *patientCache) lookup(person string) (PatientResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	patient, ok := c.patients.get(person, c.now())
+	if !ok {
+		c.stats.Misses++
+		return PatientResult{}, false
+	}
+
+	c.stats.Hits++
+	return patient.(PatientResult), true
+}
+
+func (c *patientCache) add(person string, patient PatientResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Evictions += uint64(c.patients.put(person, patient, c.now().Add(c.ttl)))
+}
+
+func (c *patientCache) lookupStore(person string) (PatientResult, bool) {
+	var patient PatientResult
+	if c.store == nil {
+		return patient, false
+	}
+
+	data, storedAt, err := c.store.FindCachedPatient(person)
+	if err != nil {
+		log.Debugf("Patient not found in persistent cache - %v", err)
+		return patient, false
+	}
+
+	if c.now().Sub(storedAt) > c.persistentTTL {
+		log.Debug("Patient expired in persistent cache - ", person)
+		return patient, false
+	}
+
+	if err := json.Unmarshal(data, &patient); err != nil {
+		log.Errorf("Error decoding cached patient - %v", err)
+		return patient, false
+	}
+
+	c.mu.Lock()
+	c.stats.PersistentHits++
+	c.mu.Unlock()
+
+	return patient, true
+}
+
+func (c *patientCache) persist(person string, patient PatientResult) {
+	if c.store == nil {
+		return
+	}
+
+	data, err := json.Marshal(patient)
+	if err != nil {
+		log.Errorf("Error encoding patient for persistent cache - %v", err)
+		return
+	}
+
+	if err := c.store.StoreCachedPatient(person, data); err != nil {
+		log.Errorf("Error storing patient in persistent cache - %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package measurement
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	log = logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+}
+
+type countingApi struct {
+	MeasurementApi
+	calls map[string]int
+}
+
+func (c countingApi) FetchPatient(person string) (PatientResult, error) {
+	c.calls[person]++
+	if person == "unknown" {
+		return PatientResult{}, fmt.Errorf("Person not found")
+	}
+	return PatientResult{UniqueID: person, FirstName: "Test"}, nil
+}
+
+type memoryStore struct {
+	patients map[string][]byte
+	stored   map[string]time.Time
+}
+
+func (s memoryStore) FindCachedPatient(link string) ([]byte, time.Time, error) {
+	data, ok := s.patients[link]
+	if !ok {
+		return data, time.Time{}, fmt.Errorf("Patient %s not cached", link)
+	}
+	return data, s.stored[link], nil
+}
+
+func (s memoryStore) StoreCachedPatient(link string, data []byte) error {
+	s.patients[link] = data
+	s.stored[link] = time.Now()
+	return nil
+}
+
+func TestPatientCacheHitsAndMisses(t *testing.T) {
+	api := countingApi{calls: make(map[string]int)}
+	cache := newPatientCache(api, time.Hour, 0, 10, nil)
+
+	for i := 0; i < 3; i++ {
+		patient, err := cache.FetchPatient("p1")
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if patient.UniqueID != "p1" {
+			t.Errorf("Expected p1 got %s", patient.UniqueID)
+		}
+	}
+
+	if api.calls["p1"] != 1 {
+		t.Errorf("Expected 1 call to api - got %d", api.calls["p1"])
+	}
+
+	stats := cache.CacheStatistics()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss - got %+v", stats)
+	}
+	if stats.Size != 1 || stats.MaxSize != 10 {
+		t.Errorf("Expected size 1 of 10 - got %+v", stats)
+	}
+
+	if _, err := cache.FetchPatient("unknown"); err == nil {
+		t.Error("Expected error for unknown patient")
+	}
+	if cache.CacheStatistics().Size != 1 {
+		t.Error("Failed lookups should not be cached")
+	}
+}
+
+func TestPatientCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	api := countingApi{calls: make(map[string]int)}
+	cache := newPatientCache(api, time.Hour, 0, 2, nil)
+
+	cache.FetchPatient("p1") // nolint
+	cache.FetchPatient("p2") // nolint
+	cache.FetchPatient("p1") // nolint - p2 is now least recently used
+	cache.FetchPatient("p3") // nolint
+
+	stats := cache.CacheStatistics()
+	if stats.Size != 2 || stats.Evictions != 1 {
+		t.Errorf("Expected size 2 with 1 eviction - got %+v", stats)
+	}
+
+	cache.FetchPatient("p1") // nolint
+	if api.calls["p1"] != 1 {
+		t.Errorf("p1 should still be cached - api called %d times", api.calls["p1"])
+	}
+	cache.FetchPatient("p2") // nolint
+	if api.calls["p2"] != 2 {
+		t.Errorf("p2 should have been evicted - api called %d times", api.calls["p2"])
+	}
+}
+
+func TestPatientCacheExpires(t *testing.T) {
+	api := countingApi{calls: make(map[string]int)}
+	cache := newPatientCache(api, time.Minute, 0, 10, nil)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.FetchPatient("p1") // nolint
+	now = now.Add(2 * time.Minute)
+	cache.FetchPatient("p1") // nolint
+
+	if api.calls["p1"] != 2 {
+		t.Errorf("Expected expired entry to be refetched - api called %d times", api.calls["p1"])
+	}
+}
+
+func TestPatientCachePersistentTier(t *testing.T) {
+	store := memoryStore{patients: make(map[string][]byte), stored: make(map[string]time.Time)}
+	api := countingApi{calls: make(map[string]int)}
+
+	first := newPatientCache(api, time.Hour, 0, 10, store)
+	first.FetchPatient("p1") // nolint
+	if _, ok := store.patients["p1"]; !ok {
+		t.Fatal("Patient should be stored in persistent tier")
+	}
+
+	// Simulates a restart
+	second := newPatientCache(api, time.Hour, 0, 10, store)
+	patient, err := second.FetchPatient("p1")
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	if patient.UniqueID != "p1" {
+		t.Errorf("Expected p1 got %s", patient.UniqueID)
+	}
+	if api.calls["p1"] != 1 {
+		t.Errorf("Expected patient to be served from persistent tier - api called %d times", api.calls["p1"])
+	}
+	if second.CacheStatistics().PersistentHits != 1 {
+		t.Errorf("Expected 1 persistent hit - got %+v", second.CacheStatistics())
+	}
+
+	// Expired in persistent tier
+	store.stored["p1"] = time.Now().Add(-2 * time.Hour)
+	third := newPatientCache(api, time.Hour, 0, 10, store)
+	third.FetchPatient("p1") // nolint
+	if api.calls["p1"] != 2 {
+		t.Errorf("Expected expired persistent entry to be refetched - api called %d times", api.calls["p1"])
+	}
+}
+
+func (c countingApi) FetchPatientThresholds(thresholds string) (PatientThresholds, error) {
+	c.calls[thresholds]++
+	return PatientThresholds{Thresholds: []PatientThreshold{{Type: "weight"}}}, nil
+}
+
+func TestThresholdsAreCached(t *testing.T) {
+	api := countingApi{calls: make(map[string]int)}
+	cache := newPatientCache(api, time.Hour, 0, 1, nil)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		res, err := cache.FetchPatientThresholds("t1")
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if _, ok := res.ForType("weight"); !ok {
+			t.Errorf("Expected weight threshold")
+		}
+	}
+	if api.calls["t1"] != 1 {
+		t.Errorf("Expected 1 call got %d", api.calls["t1"])
+	}
+
+	// Size is shared with the patients - t1 is dropped
+	cache.FetchPatientThresholds("t2") // nolint
+	cache.FetchPatientThresholds("t1") // nolint
+	if api.calls["t1"] != 2 {
+		t.Errorf("Expected t1 to be evicted, got %d calls", api.calls["t1"])
+	}
+
+	now = now.Add(2 * time.Hour)
+	cache.FetchPatientThresholds("t1") // nolint
+	if api.calls["t1"] != 3 {
+		t.Errorf("Expected t1 to expire, got %d calls", api.calls["t1"])
+	}
+}
+
+func (c countingApi) FetchQuestionnaireSchedules(schedules string) (QuestionnaireSchedules, error) {
+	c.calls[schedules]++
+	return QuestionnaireSchedules{Results: []QuestionnaireSchedule{{QuestionnaireName: "Vægt"}}}, nil
+}
+
+func TestSchedulesAreCached(t *testing.T) {
+	api := countingApi{calls: make(map[string]int)}
+	cache := newPatientCache(api, time.Hour, 0, 2, nil)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		res, err := cache.FetchQuestionnaireSchedules("s1")
+		if err != nil {
+			t.Fatalf("Unexpected error %v", err)
+		}
+		if len(res.Results) != 1 {
+			t.Errorf("Expected 1 schedule got %d", len(res.Results))
+		}
+	}
+	if api.calls["s1"] != 1 {
+		t.Errorf("Expected 1 call got %d", api.calls["s1"])
+	}
+
+	// s1 is used after s2, so s2 is the least recently used when full
+	cache.FetchQuestionnaireSchedules("s2") // nolint
+	cache.FetchQuestionnaireSchedules("s1") // nolint
+	cache.FetchQuestionnaireSchedules("s3") // nolint
+	cache.FetchQuestionnaireSchedules("s1") // nolint
+	cache.FetchQuestionnaireSchedules("s2") // nolint
+	if api.calls["s1"] != 1 || api.calls["s2"] != 2 {
+		t.Errorf("Expected s2 to be evicted, got %d/%d calls", api.calls["s1"], api.calls["s2"])
+	}
+
+	now = now.Add(2 * time.Hour)
+	cache.FetchQuestionnaireSchedules("s1") // nolint
+	if api.calls["s1"] != 2 {
+		t.Errorf("Expected s1 to expire, got %d calls", api.calls["s1"])
+	}
+}
@@ -17,6 +17,15 @@ type MeasurementApi interface {
 	FetchMeasurements(since time.Time, offset int) (MeasurementResponse, error)
 	FetchMeasurement(measurement string) (Measurement, error)
 	FetchPatient(person string) (PatientResult, error)
+	// FetchPatientThresholds retrieves the alarm thresholds from the patients thresholds link
+	FetchPatientThresholds(thresholds string) (PatientThresholds, error)
+	// FetchQuestionnaireSchedules retrieves the questionnaire schedules from the patients schedules link
+	FetchQuestionnaireSchedules(schedules string) (QuestionnaireSchedules, error)
+	// FetchQuestionnaireResults takes a timestamp from which to retrieve completed questionnaires
+	FetchQuestionnaireResults(since time.Time, offset int) (QuestionnaireResultResponse, error)
+	FetchQuestionnaireResult(questionnaireResult string) (QuestionnaireResult, error)
+	// FetchWaveform retrieves the waveform referenced by an ECG or CTG measurement
+	FetchWaveform(waveform string) (Waveform, error)
 	CheckHealth() error
 }
 
@@ -41,6 +50,11 @@ func InitMeasurementApi(appConfig *app.Config) (MeasurementApi, error) {
 
 	var api MeasurementApi
 
+	if len(config.ClinicianConfig.Replay) > 0 {
+		log.Infof("Replaying clinician responses from %s", config.ClinicianConfig.Replay)
+		return InitReplayApi(config.ClinicianConfig.Replay)
+	}
+
 	impl := clinicianApi{}
 	impl.batchSize = config.ClinicianConfig.BatchSize
 	impl.key = config.Authentication.Key
@@ -48,5 +62,10 @@ func InitMeasurementApi(appConfig *app.Config) (MeasurementApi, error) {
 	impl.apiUrl = config.ClinicianConfig.URL
 
 	api = impl
+
+	if len(config.ClinicianConfig.Record) > 0 {
+		log.Infof("Recording clinician responses to %s", config.ClinicianConfig.Record)
+		return InitRecordingApi(api, config.ClinicianConfig.Record)
+	}
 	return api, nil
 }
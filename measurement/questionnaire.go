@@ -0,0 +1,115 @@
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// QuestionnaireAnswer denotes a single answered question in a questionnaire result
+type QuestionnaireAnswer struct {
+	QuestionID string      `json:"questionId"`
+	Question   string      `json:"question"`
+	Type       string      `json:"type"`
+	Answer     interface{} `json:"answer"`
+}
+
+// QuestionnaireResult denotes a completed questionnaire from the REST API
+type QuestionnaireResult struct {
+	Name      string                `json:"name"`
+	Version   string                `json:"version"`
+	Timestamp time.Time             `json:"timestamp"`
+	Results   []QuestionnaireAnswer `json:"results"`
+	Links     struct {
+		QuestionnaireResult string `json:"questionnaireResult"`
+		Questionnaire       string `json:"questionnaire"`
+		Patient             string `json:"patient"`
+	} `json:"links"`
+}
+
+func (q QuestionnaireResult) String() string {
+	return fmt.Sprintf("[%s] q: %s (%s) - answers: %d patient: %s", q.Timestamp.Format(time.RFC822), q.Name, q.Version, len(q.Results), q.Links.Patient)
+}
+
+type QuestionnaireResultResponse struct {
+	Results []QuestionnaireResult `json:"results"`
+	Total   int                   `json:"total"`
+	Max     int                   `json:"max"`
+	Offset  int                   `json:"offset"`
+	Links   struct {
+		Self     string `json:"self"`
+		Next     string `json:"next"`
+		Previous string `json:"previous"`
+	} `json:"links"`
+}
+
+func fetchQuestionnaireResults(location string, since time.Time, offset int, max int) (QuestionnaireResultResponse, error) {
+	log.Debug("Since: ", since, " Offset: ", offset, " Batches: ", max)
+	var result QuestionnaireResultResponse
+
+	v := url.Values{}
+	v.Set("from", since.Format(time.RFC3339))
+
+	requestUrl := fmt.Sprintf("%s/questionnaire_results?%s&offset=%d&max=%d", location, v.Encode(), offset, max)
+	if err := getResource(requestUrl, &result); err != nil {
+		return result, err
+	}
+
+	log.Debug("Total: ", result.Total, " Max ", result.Max, "Next ", result.Links.Next)
+
+	return result, nil
+}
+
+// FetchQuestionnaireResults retrieves questionnaire results completed since the timestamp
+func (m clinicianApi) FetchQuestionnaireResults(since time.Time, offset int) (QuestionnaireResultResponse, error) {
+	// substract 2 hours - same as for measurements
+	ts := since.Add(-2 * time.Hour)
+
+	return fetchQuestionnaireResults(m.apiUrl, ts, offset, m.batchSize)
+}
+
+// FetchQuestionnaireResult retrieves a single questionnaire result
+func (m clinicianApi) FetchQuestionnaireResult(questionnaireResult string) (QuestionnaireResult, error) {
+	var result QuestionnaireResult
+	if err := getResource(questionnaireResult, &result); err != nil {
+		return result, err
+	}
+	log.Debug(fmt.Sprintf("Retrieved - %s", result))
+
+	return result, nil
+}
+
+// getResource performs an authorized GET against the api and decodes the JSON reply into result
+func getResource(requestUrl string, result interface{}) error {
+	log.Debug(requestUrl)
+	req, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error creating request")
+	}
+	addAuthorizationHeader(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "Error querying API")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode > 299 {
+		return fmt.Errorf("Error accessing API - server responded: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "Decoding body")
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return errors.Wrap(err, "Error converting body to type")
+	}
+	return nil
+}
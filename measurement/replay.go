@@ -0,0 +1,275 @@
+package measurement
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Directories used for the recordings
+const (
+	RECORDING_MEASUREMENTS          = "measurements"
+	RECORDING_MEASUREMENT           = "measurement"
+	RECORDING_PATIENTS              = "patients"
+	RECORDING_QUESTIONNAIRE_RESULTS = "questionnaire_results"
+	RECORDING_QUESTIONNAIRE_RESULT  = "questionnaire_result"
+	RECORDING_WAVEFORMS             = "waveforms"
+	RECORDING_THRESHOLDS            = "thresholds"
+	RECORDING_SCHEDULES             = "schedules"
+	RECORDING_SCRUBBED_CPR_MODULUS  = 10000000000
+)
+
+var cprPattern = regexp.MustCompile(`\b(\d{6})-?(\d{4})\b`)
+
+// isCPR checks that the first six digits are a valid date, so other ten digit numbers, e.g. ids or timestamps, are kept
+func isCPR(cpr []byte) bool {
+	_, err := time.Parse("020106", string(cpr[:6]))
+	return err == nil
+}
+
+// cprScrubber replaces CPR numbers with pseudonyms. The pseudonyms are keyed with a random key per recording, which is
+// never stored, so they are stable within the recording but cannot be reversed by hashing all CPR numbers
+type cprScrubber struct {
+	key []byte
+}
+
+func newCPRScrubber() (cprScrubber, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return cprScrubber{}, errors.Wrap(err, "Error creating pseudonym key")
+	}
+	return cprScrubber{key: key}, nil
+}
+
+// scrub replaces everything looking like a CPR number with its pseudonym
+func (s cprScrubber) scrub(data []byte) []byte {
+	return cprPattern.ReplaceAllFunc(data, func(match []byte) []byte {
+		cpr := cprPattern.ReplaceAll(match, []byte("$1$2"))
+		if !isCPR(cpr) {
+			return match
+		}
+		mac := hmac.New(sha256.New, s.key)
+		mac.Write(cpr) // nolint
+		sum := mac.Sum(nil)
+		var n uint64
+		for _, b := range sum[:8] {
+			n = n<<8 | uint64(b)
+		}
+		return []byte(fmt.Sprintf("%010d", n%RECORDING_SCRUBBED_CPR_MODULUS))
+	})
+}
+
+func recordingKey(link string) string {
+	sum := sha1.Sum([]byte(link))
+	return hex.EncodeToString(sum[:])
+}
+
+func recordingPath(dir, kind, key string) string {
+	return filepath.Join(dir, kind, fmt.Sprintf("%s.json", key))
+}
+
+// recordingApi decorates a MeasurementApi and writes every response to a directory
+type recordingApi struct {
+	MeasurementApi
+	dir      string
+	scrubber cprScrubber
+}
+
+// InitRecordingApi wraps api so all responses are recorded with CPR numbers scrubbed
+func InitRecordingApi(api MeasurementApi, dir string) (MeasurementApi, error) {
+	for _, kind := range []string{RECORDING_MEASUREMENTS, RECORDING_MEASUREMENT, RECORDING_PATIENTS, RECORDING_QUESTIONNAIRE_RESULTS, RECORDING_QUESTIONNAIRE_RESULT, RECORDING_WAVEFORMS, RECORDING_THRESHOLDS, RECORDING_SCHEDULES} {
+		if err := os.MkdirAll(filepath.Join(dir, kind), 0755); err != nil {
+			return api, errors.Wrap(err, "Error creating recording directory")
+		}
+	}
+	scrubber, err := newCPRScrubber()
+	if err != nil {
+		return api, err
+	}
+	return recordingApi{MeasurementApi: api, dir: dir, scrubber: scrubber}, nil
+}
+
+func (r recordingApi) record(kind, key string, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		log.Errorf("Error encoding recording - %v", err)
+		return
+	}
+
+	if err := ioutil.WriteFile(recordingPath(r.dir, kind, key), r.scrubber.scrub(data), 0644); err != nil {
+		log.Errorf("Error writing recording - %v", err)
+	}
+}
+
+func (r recordingApi) FetchMeasurements(since time.Time, offset int) (MeasurementResponse, error) {
+	res, err := r.MeasurementApi.FetchMeasurements(since, offset)
+	if err == nil {
+		r.record(RECORDING_MEASUREMENTS, strconv.Itoa(offset), res)
+	}
+	return res, err
+}
+
+func (r recordingApi) FetchMeasurement(measurement string) (Measurement, error) {
+	res, err := r.MeasurementApi.FetchMeasurement(measurement)
+	if err == nil {
+		r.record(RECORDING_MEASUREMENT, recordingKey(measurement), res)
+	}
+	return res, err
+}
+
+func (r recordingApi) FetchPatient(person string) (PatientResult, error) {
+	res, err := r.MeasurementApi.FetchPatient(person)
+	if err == nil {
+		r.record(RECORDING_PATIENTS, recordingKey(person), res)
+	}
+	return res, err
+}
+
+func (r recordingApi) FetchQuestionnaireResults(since time.Time, offset int) (QuestionnaireResultResponse, error) {
+	res, err := r.MeasurementApi.FetchQuestionnaireResults(since, offset)
+	if err == nil {
+		r.record(RECORDING_QUESTIONNAIRE_RESULTS, strconv.Itoa(offset), res)
+	}
+	return res, err
+}
+
+func (r recordingApi) FetchQuestionnaireResult(questionnaireResult string) (QuestionnaireResult, error) {
+	res, err := r.MeasurementApi.FetchQuestionnaireResult(questionnaireResult)
+	if err == nil {
+		r.record(RECORDING_QUESTIONNAIRE_RESULT, recordingKey(questionnaireResult), res)
+	}
+	return res, err
+}
+
+func (r recordingApi) FetchWaveform(waveform string) (Waveform, error) {
+	res, err := r.MeasurementApi.FetchWaveform(waveform)
+	if err == nil {
+		r.record(RECORDING_WAVEFORMS, recordingKey(waveform), res)
+	}
+	return res, err
+}
+
+func (r recordingApi) FetchPatientThresholds(thresholds string) (PatientThresholds, error) {
+	res, err := r.MeasurementApi.FetchPatientThresholds(thresholds)
+	if err == nil {
+		r.record(RECORDING_THRESHOLDS, recordingKey(thresholds), res)
+	}
+	return res, err
+}
+
+func (r recordingApi) FetchQuestionnaireSchedules(schedules string) (QuestionnaireSchedules, error) {
+	res, err := r.MeasurementApi.FetchQuestionnaireSchedules(schedules)
+	if err == nil {
+		r.record(RECORDING_SCHEDULES, recordingKey(schedules), res)
+	}
+	return res, err
+}
+
+// replayApi serves responses from a recording made by the recording api
+type replayApi struct {
+	dir string
+}
+
+// InitReplayApi creates a MeasurementApi serving the recordings in dir
+func InitReplayApi(dir string) (MeasurementApi, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, errors.Wrap(err, "Error opening recording")
+	}
+	return replayApi{dir: dir}, nil
+}
+
+func (r replayApi) String() string {
+	return fmt.Sprintf("Replaying: %s", r.dir)
+}
+
+func (r replayApi) load(kind, key string, value interface{}) error {
+	data, err := ioutil.ReadFile(recordingPath(r.dir, kind, key))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return errors.Wrap(err, "Error decoding recording")
+	}
+	return nil
+}
+
+// FetchMeasurements returns the recorded page for the offset. Missing pages are empty, which ends the pagination
+func (r replayApi) FetchMeasurements(since time.Time, offset int) (MeasurementResponse, error) {
+	var res MeasurementResponse
+	if err := r.load(RECORDING_MEASUREMENTS, strconv.Itoa(offset), &res); err != nil && !os.IsNotExist(err) {
+		return res, err
+	}
+	return res, nil
+}
+
+func (r replayApi) FetchMeasurement(measurement string) (Measurement, error) {
+	var res Measurement
+	if err := r.load(RECORDING_MEASUREMENT, recordingKey(measurement), &res); err != nil {
+		return res, errors.Wrap(err, fmt.Sprintf("Measurement %s not recorded", measurement))
+	}
+	return res, nil
+}
+
+func (r replayApi) FetchPatient(person string) (PatientResult, error) {
+	var res PatientResult
+	if err := r.load(RECORDING_PATIENTS, recordingKey(person), &res); err != nil {
+		return res, errors.Wrap(err, fmt.Sprintf("Patient %s not recorded", person))
+	}
+	return res, nil
+}
+
+func (r replayApi) FetchQuestionnaireResults(since time.Time, offset int) (QuestionnaireResultResponse, error) {
+	var res QuestionnaireResultResponse
+	if err := r.load(RECORDING_QUESTIONNAIRE_RESULTS, strconv.Itoa(offset), &res); err != nil && !os.IsNotExist(err) {
+		return res, err
+	}
+	return res, nil
+}
+
+func (r replayApi) FetchQuestionnaireResult(questionnaireResult string) (QuestionnaireResult, error) {
+	var res QuestionnaireResult
+	if err := r.load(RECORDING_QUESTIONNAIRE_RESULT, recordingKey(questionnaireResult), &res); err != nil {
+		return res, errors.Wrap(err, fmt.Sprintf("Questionnaire result %s not recorded", questionnaireResult))
+	}
+	return res, nil
+}
+
+func (r replayApi) FetchWaveform(waveform string) (Waveform, error) {
+	var res Waveform
+	if err := r.load(RECORDING_WAVEFORMS, recordingKey(waveform), &res); err != nil {
+		return res, errors.Wrap(err, fmt.Sprintf("Waveform %s not recorded", waveform))
+	}
+	return res, nil
+}
+
+func (r replayApi) FetchPatientThresholds(thresholds string) (PatientThresholds, error) {
+	var res PatientThresholds
+	if err := r.load(RECORDING_THRESHOLDS, recordingKey(thresholds), &res); err != nil {
+		return res, errors.Wrap(err, fmt.Sprintf("Thresholds %s not recorded", thresholds))
+	}
+	return res, nil
+}
+
+func (r replayApi) FetchQuestionnaireSchedules(schedules string) (QuestionnaireSchedules, error) {
+	var res QuestionnaireSchedules
+	if err := r.load(RECORDING_SCHEDULES, recordingKey(schedules), &res); err != nil {
+		return res, errors.Wrap(err, fmt.Sprintf("Schedules %s not recorded", schedules))
+	}
+	return res, nil
+}
+
+func (r replayApi) CheckHealth() error {
+	return nil
+}
@@ -0,0 +1,113 @@
+package measurement
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type recordedApi struct {
+	MeasurementApi
+}
+
+func (recordedApi) FetchMeasurements(since time.Time, offset int) (MeasurementResponse, error) {
+	res := MeasurementResponse{Total: 1, Max: 1000, Offset: offset}
+	if offset == 0 {
+		m := Measurement{Timestamp: time.Now(), Type: "weight"}
+		m.Links.Measurement = "https://clinician/api/patients/14/measurements/397"
+		m.Links.Patient = "https://clinician/api/patients/14"
+		res.Results = append(res.Results, m)
+	}
+	return res, nil
+}
+
+func (recordedApi) FetchPatient(person string) (PatientResult, error) {
+	return PatientResult{UniqueID: "2512484916", FirstName: "Nancy", Comment: "Previous cpr 251248-4916"}, nil
+}
+
+func TestScrubCPR(t *testing.T) {
+	scrubber, err := newCPRScrubber()
+	if err != nil {
+		t.Fatal(err)
+	}
+	scrubbed := scrubber.scrub([]byte(`{"uniqueId":"2512484916","comment":"251248-4916","timestamp":1582642720000,"id":1582642720}`))
+
+	if bytes.Contains(scrubbed, []byte("2512484916")) || bytes.Contains(scrubbed, []byte("251248-4916")) {
+		t.Errorf("CPR not scrubbed - %s", scrubbed)
+	}
+	if !bytes.Contains(scrubbed, []byte("1582642720000")) || !bytes.Contains(scrubbed, []byte("1582642720")) {
+		t.Errorf("Timestamp and id should not be scrubbed - %s", scrubbed)
+	}
+	if !bytes.Equal(scrubber.scrub([]byte("2512484916")), scrubber.scrub([]byte("251248-4916"))) {
+		t.Errorf("Expected the same pseudonym for the same cpr")
+	}
+
+	// Pseudonyms are keyed per recording
+	other, err := newCPRScrubber()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(scrubber.scrub([]byte("2512484916")), other.scrub([]byte("2512484916"))) {
+		t.Errorf("Expected different pseudonyms across recordings")
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "recording")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorder, err := InitRecordingApi(recordedApi{}, dir)
+	if err != nil {
+		t.Fatalf("Error creating recorder - %v", err)
+	}
+
+	recorded, err := recorder.FetchMeasurements(time.Now(), 0)
+	if err != nil {
+		t.Fatalf("Error fetching measurements - %v", err)
+	}
+	if _, err := recorder.FetchPatient(recorded.Results[0].Links.Patient); err != nil {
+		t.Fatalf("Error fetching patient - %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, RECORDING_PATIENTS, recordingKey(recorded.Results[0].Links.Patient)+".json"))
+	if err != nil {
+		t.Fatalf("Patient not recorded - %v", err)
+	}
+	if bytes.Contains(data, []byte("2512484916")) {
+		t.Errorf("Recorded patient contains cpr - %s", data)
+	}
+
+	replay, err := InitReplayApi(dir)
+	if err != nil {
+		t.Fatalf("Error creating replay - %v", err)
+	}
+
+	res, err := replay.FetchMeasurements(time.Now(), 0)
+	if err != nil {
+		t.Fatalf("Error replaying measurements - %v", err)
+	}
+	if len(res.Results) != 1 || res.Results[0].Links.Measurement != recorded.Results[0].Links.Measurement {
+		t.Errorf("Expected recorded measurement got %v", res.Results)
+	}
+
+	patient, err := replay.FetchPatient(res.Results[0].Links.Patient)
+	if err != nil {
+		t.Fatalf("Error replaying patient - %v", err)
+	}
+	if patient.FirstName != "Nancy" || patient.UniqueID != string(recorder.(recordingApi).scrubber.scrub([]byte("2512484916"))) {
+		t.Errorf("Unexpected patient %v", patient)
+	}
+
+	if res, err := replay.FetchMeasurements(time.Now(), 1000); err != nil || len(res.Results) != 0 {
+		t.Errorf("Expected empty page after the recording, got %v - %v", res, err)
+	}
+	if _, err := replay.FetchMeasurement("https://clinician/api/patients/14/measurements/1"); err == nil {
+		t.Errorf("Expected error for measurement not recorded")
+	}
+}
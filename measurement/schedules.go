@@ -0,0 +1,147 @@
+package measurement
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Types of questionnaire schedules in OTH
+const (
+	SCHEDULE_UNSCHEDULED   = "UNSCHEDULED"
+	SCHEDULE_WEEKDAYS      = "WEEKDAYS"
+	SCHEDULE_WEEKDAYS_ONCE = "WEEKDAYS_ONCE"
+	SCHEDULE_MONTHLY       = "MONTHLY"
+	SCHEDULE_EVERY_NTH_DAY = "EVERY_NTH_DAY"
+	SCHEDULE_SPECIFIC      = "SPECIFIC"
+)
+
+// TimeOfDay is a time in a schedule, in the local time of the measurement
+type TimeOfDay struct {
+	Hour   int `json:"hour"`
+	Minute int `json:"minute"`
+}
+
+// Schedule holds when a questionnaire is to be answered. Which fields are used depends on the type
+type Schedule struct {
+	Type       string      `json:"type"`
+	TimesOfDay []TimeOfDay `json:"timesOfDay,omitempty"`
+	// Weekdays are english names of the days, e.g. MONDAY
+	Weekdays    []string `json:"weekdays,omitempty"`
+	DaysInMonth []int    `json:"daysInMonth,omitempty"`
+	// DayInterval and StartingDate are used by schedules every nth day
+	DayInterval  int    `json:"dayInterval,omitempty"`
+	StartingDate string `json:"startingDate,omitempty"`
+	// SpecificDates are the dates of specific schedules, formatted as 2006-01-02
+	SpecificDates []string `json:"specificDates,omitempty"`
+}
+
+// QuestionnaireSchedule is the schedule of a questionnaire assigned to a patient
+type QuestionnaireSchedule struct {
+	QuestionnaireName string   `json:"questionnaireName"`
+	Schedule          Schedule `json:"schedule"`
+}
+
+// QuestionnaireSchedules is the reply from the patient questionnaire schedules link
+type QuestionnaireSchedules struct {
+	Results []QuestionnaireSchedule `json:"results"`
+}
+
+// IsScheduled reports whether the time is within window of a slot of one of the schedules
+func (q QuestionnaireSchedules) IsScheduled(t time.Time, window time.Duration) bool {
+	for _, s := range q.Results {
+		if s.Schedule.isScheduled(t, window) {
+			return true
+		}
+	}
+	return false
+}
+
+// isScheduled checks the slots of the day of t and the days around it, as the window may cross midnight
+func (s Schedule) isScheduled(t time.Time, window time.Duration) bool {
+	for offset := -1; offset <= 1; offset++ {
+		day := t.AddDate(0, 0, offset)
+		if !s.appliesOn(day) {
+			continue
+		}
+		for _, tod := range s.TimesOfDay {
+			slot := time.Date(day.Year(), day.Month(), day.Day(), tod.Hour, tod.Minute, 0, 0, t.Location())
+			diff := t.Sub(slot)
+			if diff < 0 {
+				diff = -diff
+			}
+			if diff <= window {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appliesOn reports whether the schedule has slots on the day
+func (s Schedule) appliesOn(day time.Time) bool {
+	switch s.Type {
+	case SCHEDULE_WEEKDAYS, SCHEDULE_WEEKDAYS_ONCE:
+		for _, weekday := range s.Weekdays {
+			if strings.EqualFold(weekday, day.Weekday().String()) {
+				return true
+			}
+		}
+	case SCHEDULE_MONTHLY:
+		for _, d := range s.DaysInMonth {
+			if d == day.Day() {
+				return true
+			}
+		}
+	case SCHEDULE_EVERY_NTH_DAY:
+		start, err := time.ParseInLocation("2006-01-02", s.StartingDate, day.Location())
+		if err != nil || s.DayInterval <= 0 {
+			return false
+		}
+		date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+		if date.Before(start) {
+			return false
+		}
+		days := int(date.Sub(start).Hours()+12) / 24
+		return days%s.DayInterval == 0
+	case SCHEDULE_SPECIFIC:
+		for _, d := range s.SpecificDates {
+			if d == day.Format("2006-01-02") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FetchQuestionnaireSchedules retrieves the questionnaire schedules of a patient
+func (m clinicianApi) FetchQuestionnaireSchedules(schedules string) (QuestionnaireSchedules, error) {
+	var result QuestionnaireSchedules
+	if err := getResource(schedules, &result); err != nil {
+		return result, err
+	}
+	log.Debug(fmt.Sprintf("Retrieved %d questionnaire schedules", len(result.Results)))
+
+	return result, nil
+}
+
+// FetchQuestionnaireSchedules returns the schedules from the cache or the wrapped api. Schedules share ttl and size with the patients
+func (c *patientCache) FetchQuestionnaireSchedules(schedules string) (QuestionnaireSchedules, error) {
+	c.mu.Lock()
+	entry, ok := c.schedules.get(schedules, c.now())
+	c.mu.Unlock()
+	if ok {
+		return entry.(QuestionnaireSchedules), nil
+	}
+
+	res, err := c.MeasurementApi.FetchQuestionnaireSchedules(schedules)
+	if err != nil {
+		return res, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.schedules.put(schedules, res, c.now().Add(c.ttl))
+
+	return res, nil
+}
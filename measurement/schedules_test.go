@@ -0,0 +1,62 @@
+package measurement
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const schedulesJson = `{
+  "results": [
+    {"questionnaireName": "Vægt", "schedule": {"type": "WEEKDAYS", "weekdays": ["MONDAY", "THURSDAY"], "timesOfDay": [{"hour": 8, "minute": 0}, {"hour": 23, "minute": 30}]}},
+    {"questionnaireName": "Blodtryk", "schedule": {"type": "MONTHLY", "daysInMonth": [15], "timesOfDay": [{"hour": 12, "minute": 0}]}},
+    {"questionnaireName": "Saturation", "schedule": {"type": "EVERY_NTH_DAY", "dayInterval": 3, "startingDate": "2020-05-01", "timesOfDay": [{"hour": 18, "minute": 0}]}},
+    {"questionnaireName": "KOL", "schedule": {"type": "SPECIFIC", "specificDates": ["2020-05-29"], "timesOfDay": [{"hour": 9, "minute": 30}]}},
+    {"questionnaireName": "Fri", "schedule": {"type": "UNSCHEDULED"}}
+  ]
+}`
+
+func TestIsScheduled(t *testing.T) {
+	var schedules QuestionnaireSchedules
+	if err := json.Unmarshal([]byte(schedulesJson), &schedules); err != nil {
+		t.Fatalf("Error parsing schedules - %v", err)
+	}
+
+	cph, err := time.LoadLocation("Europe/Copenhagen")
+	if err != nil {
+		t.Fatalf("Error loading location - %v", err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2020, time.May, day, hour, minute, 0, 0, cph)
+	}
+
+	tests := []struct {
+		name      string
+		timestamp time.Time
+		expected  bool
+	}{
+		{"Weekday slot", at(25, 8, 45), true},
+		{"Weekday before slot", at(25, 6, 30), true},
+		{"Weekday outside window", at(25, 11, 0), false},
+		{"Other weekday", at(26, 8, 0), false},
+		{"Window crossing midnight", at(26, 0, 30), true},
+		{"Day in month", at(15, 12, 10), true},
+		{"Every third day", at(31, 18, 0), true},
+		{"Not every third day", at(30, 18, 0), false},
+		{"Specific date", at(29, 10, 0), true},
+		{"Nothing scheduled", at(27, 12, 0), false},
+		{"Other time zone", time.Date(2020, time.May, 25, 6, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := schedules.IsScheduled(tt.timestamp.In(cph), 2*time.Hour); got != tt.expected {
+				t.Errorf("Expected scheduled %v for %s got %v", tt.expected, tt.timestamp, got)
+			}
+		})
+	}
+
+	if (QuestionnaireSchedules{}).IsScheduled(at(25, 8, 0), 2*time.Hour) {
+		t.Errorf("Expected no slots without schedules")
+	}
+}
@@ -0,0 +1,87 @@
+package measurement
+
+import (
+	"time"
+)
+
+// SeriesValue is a single reading in a continuous measurement, e.g. from a CGM sensor
+type SeriesValue struct {
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+}
+
+// SeriesSummary summarises the readings of a series within a period
+type SeriesSummary struct {
+	Start   time.Time
+	End     time.Time
+	Count   int
+	Mean    float64
+	Minimum float64
+	Maximum float64
+}
+
+// Duration returns the length of the summarised period
+func (s SeriesSummary) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// Summarise returns the summary of the readings
+func Summarise(series []SeriesValue) SeriesSummary {
+	var summary SeriesSummary
+	var sum float64
+
+	for i, v := range series {
+		if i == 0 || v.Timestamp.Before(summary.Start) {
+			summary.Start = v.Timestamp
+		}
+		if i == 0 || v.Timestamp.After(summary.End) {
+			summary.End = v.Timestamp
+		}
+		if i == 0 || v.Value < summary.Minimum {
+			summary.Minimum = v.Value
+		}
+		if i == 0 || v.Value > summary.Maximum {
+			summary.Maximum = v.Value
+		}
+		sum += v.Value
+	}
+
+	summary.Count = len(series)
+	if summary.Count > 0 {
+		summary.Mean = sum / float64(summary.Count)
+	}
+	return summary
+}
+
+// SummariseIntervals splits the readings into intervals of the given length, starting from the first reading.
+// Intervals without readings are left out
+func SummariseIntervals(series []SeriesValue, interval time.Duration) []SeriesSummary {
+	var summaries []SeriesSummary
+	if len(series) == 0 || interval <= 0 {
+		return summaries
+	}
+
+	start := Summarise(series).Start
+	buckets := make(map[int][]SeriesValue)
+	last := 0
+	for _, v := range series {
+		i := int(v.Timestamp.Sub(start) / interval)
+		buckets[i] = append(buckets[i], v)
+		if i > last {
+			last = i
+		}
+	}
+
+	for i := 0; i <= last; i++ {
+		values, ok := buckets[i]
+		if !ok {
+			continue
+		}
+		summary := Summarise(values)
+		// Intervals cover the full period, not just the first and last reading
+		summary.Start = start.Add(time.Duration(i) * interval)
+		summary.End = summary.Start.Add(interval)
+		summaries = append(summaries, summary)
+	}
+	return summaries
+}
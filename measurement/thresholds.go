@@ -0,0 +1,81 @@
+package measurement
+
+import (
+	"fmt"
+)
+
+// ThresholdValues are the alarm limits for a single value. Limits not set by the clinician are nil
+type ThresholdValues struct {
+	AlertHigh   *float64 `json:"alertHigh,omitempty"`
+	WarningHigh *float64 `json:"warningHigh,omitempty"`
+	WarningLow  *float64 `json:"warningLow,omitempty"`
+	AlertLow    *float64 `json:"alertLow,omitempty"`
+}
+
+// Range returns the agreed range for the value - the warning limits, falling back to the alert limits
+func (t ThresholdValues) Range() (min *float64, max *float64) {
+	min, max = t.WarningLow, t.WarningHigh
+	if min == nil {
+		min = t.AlertLow
+	}
+	if max == nil {
+		max = t.AlertHigh
+	}
+	return min, max
+}
+
+// PatientThreshold holds the thresholds for a measurement type. Blood pressure has separate systolic and diastolic limits
+type PatientThreshold struct {
+	Type string `json:"type"`
+	Unit string `json:"unit,omitempty"`
+	ThresholdValues
+	Systolic  *ThresholdValues `json:"systolic,omitempty"`
+	Diastolic *ThresholdValues `json:"diastolic,omitempty"`
+}
+
+// PatientThresholds is the reply from the patient thresholds link
+type PatientThresholds struct {
+	Thresholds []PatientThreshold `json:"thresholds"`
+}
+
+// ForType returns the threshold for the measurement type
+func (p PatientThresholds) ForType(measurementType string) (PatientThreshold, bool) {
+	for _, t := range p.Thresholds {
+		if t.Type == measurementType {
+			return t, true
+		}
+	}
+	return PatientThreshold{}, false
+}
+
+// FetchPatientThresholds retrieves the alarm thresholds of a patient
+func (m clinicianApi) FetchPatientThresholds(thresholds string) (PatientThresholds, error) {
+	var result PatientThresholds
+	if err := getResource(thresholds, &result); err != nil {
+		return result, err
+	}
+	log.Debug(fmt.Sprintf("Retrieved %d thresholds", len(result.Thresholds)))
+
+	return result, nil
+}
+
+// FetchPatientThresholds returns the thresholds from the cache or the wrapped api. Thresholds share ttl and size with the patients
+func (c *patientCache) FetchPatientThresholds(thresholds string) (PatientThresholds, error) {
+	c.mu.Lock()
+	entry, ok := c.thresholds.get(thresholds, c.now())
+	c.mu.Unlock()
+	if ok {
+		return entry.(PatientThresholds), nil
+	}
+
+	res, err := c.MeasurementApi.FetchPatientThresholds(thresholds)
+	if err != nil {
+		return res, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.thresholds.put(thresholds, res, c.now().Add(c.ttl))
+
+	return res, nil
+}
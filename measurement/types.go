@@ -57,20 +57,24 @@ type By struct {
 
 /// MeasurementValue denotes an OTH measurement from the REST API
 type MeasurementValue struct {
-	Unit                 string      `json:"unit"`
-	Value                interface{} `json:"value,omitempty"`
-	IsAfterMeal          bool        `json:"isAfterMeal,omitempty"`
-	IsBeforeMeal         bool        `json:"isBeforeMeal,omitempty"`
-	IsControlMeasurement bool        `json:"isControlMeasurement,omitempty"`
-	Systolic             float32     `json:"systolic,omitempty"`
-	Diastolic            float32     `json:"diastolic,omitempty"`
-	Ignored              Ignored     `json:"ignored,omitempty"`
+	Unit                 string        `json:"unit"`
+	// Value is written as null when it is missing. omitempty has no effect on a struct
+	Value                Value         `json:"value"`
+	IsAfterMeal          bool          `json:"isAfterMeal,omitempty"`
+	IsBeforeMeal         bool          `json:"isBeforeMeal,omitempty"`
+	IsControlMeasurement bool          `json:"isControlMeasurement,omitempty"`
+	Systolic             float32       `json:"systolic,omitempty"`
+	Diastolic            float32       `json:"diastolic,omitempty"`
+	Ignored              Ignored       `json:"ignored,omitempty"`
+	Series               []SeriesValue `json:"series,omitempty"`
+	Duration             float64       `json:"duration,omitempty"`
 }
 
 type Links struct {
 	Measurement string `json:"measurement,omitempty"`
 	Patient     string `json:"patient,omitempty"`
 	Clinician   string `json:"clinician,omitempty"`
+	Waveform    string `json:"waveform,omitempty"`
 }
 
 type PrimaryDeviceIdentifier struct {
@@ -119,6 +123,8 @@ type Origin struct {
 		EnteredBy string `json:"enteredBy"`
 	} `json:"manualMeasurement,omitempty"`
 	DeviceMeasurement DeviceMeasurement `json:"deviceMeasurement,omitempty"`
+	// Location is where the measurement was taken, when it was not taken at home
+	Location string `json:"location,omitempty"`
 }
 
 func (o Origin) String() string {
@@ -148,7 +154,7 @@ func (m Measurement) String() string {
 	if m.Type == "blood_pressure" {
 		return fmt.Sprintf("[%s] t: %s - %f/%f %s", m.Timestamp.Format(time.RFC822), m.Type, m.Measurement.Systolic, m.Measurement.Diastolic, m.Measurement.Unit)
 	} else {
-		return fmt.Sprintf("[%s] t: %s - %f %s patient: %s", m.Timestamp.Format(time.RFC822), m.Type, m.Measurement.Value, m.Measurement.Unit, m.Links.Patient)
+		return fmt.Sprintf("[%s] t: %s - %s %s patient: %s", m.Timestamp.Format(time.RFC822), m.Type, m.Measurement.Value, m.Measurement.Unit, m.Links.Patient)
 	}
 }
 
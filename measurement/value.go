@@ -0,0 +1,257 @@
+package measurement
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValueKind tells what a measurement value holds
+type ValueKind int
+
+const (
+	VALUE_NONE ValueKind = iota
+	VALUE_NUMERIC
+	VALUE_ENUMERATED
+	VALUE_BOOLEAN
+	VALUE_COMPOSITE
+	VALUE_INVALID
+)
+
+// Components of composite values
+const (
+	COMPONENT_SYSTOLIC  = "systolic"
+	COMPONENT_DIASTOLIC = "diastolic"
+)
+
+// Value is a measurement value from clinician. Numbers are numeric, strings are enumerated, e.g. +1 for urine, and objects
+// are composite with named components. Values that cannot be represented are decoded as invalid and carry the error
+type Value struct {
+	kind       ValueKind
+	number     float64
+	text       string
+	components map[string]Value
+	err        error
+}
+
+// ValueError is returned when a value cannot be decoded or used as requested
+type ValueError struct {
+	Value  string
+	Reason string
+}
+
+func (e ValueError) Error() string {
+	return fmt.Sprintf("Invalid measurement value '%s' - %s", e.Value, e.Reason)
+}
+
+// NumericValue returns a numeric value
+func NumericValue(number float64) Value {
+	return Value{kind: VALUE_NUMERIC, number: number}
+}
+
+// EnumeratedValue returns an enumerated value. An empty text is no value
+func EnumeratedValue(text string) Value {
+	if len(strings.TrimSpace(text)) == 0 {
+		return Value{}
+	}
+	return Value{kind: VALUE_ENUMERATED, text: text}
+}
+
+// BooleanValue returns a boolean value
+func BooleanValue(b bool) Value {
+	return Value{kind: VALUE_BOOLEAN, text: strconv.FormatBool(b)}
+}
+
+// CompositeValue returns a value composed of named components
+func CompositeValue(components map[string]Value) Value {
+	return Value{kind: VALUE_COMPOSITE, components: components}
+}
+
+func invalidValue(raw, reason string) Value {
+	return Value{kind: VALUE_INVALID, text: raw, err: ValueError{Value: raw, Reason: reason}}
+}
+
+// ValueOf returns the value of a decoded JSON value, e.g. a questionnaire answer
+func ValueOf(v interface{}) Value {
+	switch val := v.(type) {
+	case nil:
+		return Value{}
+	case Value:
+		return val
+	case int:
+		return NumericValue(float64(val))
+	case int64:
+		return NumericValue(float64(val))
+	case float32:
+		return NumericValue(float64(val))
+	case float64:
+		return NumericValue(val)
+	case string:
+		return EnumeratedValue(val)
+	case bool:
+		return BooleanValue(val)
+	case map[string]interface{}:
+		components := make(map[string]Value)
+		for name, c := range val {
+			components[name] = ValueOf(c)
+		}
+		return CompositeValue(components)
+	}
+	return invalidValue(fmt.Sprint(v), fmt.Sprintf("unsupported type %T", v))
+}
+
+// Kind returns the kind of the value
+func (v Value) Kind() ValueKind {
+	return v.kind
+}
+
+// IsEmpty reports whether there is no value
+func (v Value) IsEmpty() bool {
+	return v.kind == VALUE_NONE
+}
+
+// Err returns the error of an invalid value
+func (v Value) Err() error {
+	return v.err
+}
+
+// Float returns the value as a number. Enumerated values are parsed, and booleans are 1 or 0
+func (v Value) Float() (float64, error) {
+	switch v.kind {
+	case VALUE_NUMERIC:
+		return v.number, nil
+	case VALUE_ENUMERATED:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v.text), 64)
+		if err != nil {
+			return 0, ValueError{Value: v.text, Reason: "not numeric"}
+		}
+		return number, nil
+	case VALUE_BOOLEAN:
+		if v.text == "true" {
+			return 1, nil
+		}
+		return 0, nil
+	case VALUE_INVALID:
+		return 0, v.err
+	case VALUE_COMPOSITE:
+		return 0, ValueError{Value: v.String(), Reason: "composite value"}
+	}
+	return 0, ValueError{Reason: "missing"}
+}
+
+// IsNumeric reports whether the value can be used as a number
+func (v Value) IsNumeric() bool {
+	_, err := v.Float()
+	return err == nil
+}
+
+// Boolean returns the value of a boolean
+func (v Value) Boolean() (bool, error) {
+	if v.kind != VALUE_BOOLEAN {
+		return false, ValueError{Value: v.String(), Reason: "not a boolean"}
+	}
+	return v.text == "true", nil
+}
+
+// Component returns the named component of a composite value
+func (v Value) Component(name string) (Value, bool) {
+	c, ok := v.components[name]
+	return c, ok
+}
+
+// String returns the value as text. Numbers are formatted with the digits needed
+func (v Value) String() string {
+	switch v.kind {
+	case VALUE_NUMERIC:
+		return strconv.FormatFloat(v.number, 'f', -1, 64)
+	case VALUE_COMPOSITE:
+		var names []string
+		for name := range v.components {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var parts []string
+		for _, name := range names {
+			parts = append(parts, fmt.Sprintf("%s=%s", name, v.components[name]))
+		}
+		return strings.Join(parts, ", ")
+	}
+	return v.text
+}
+
+// MarshalJSON writes the value as clinician sends it. Invalid values are written as received
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.kind {
+	case VALUE_NUMERIC:
+		return json.Marshal(v.number)
+	case VALUE_ENUMERATED:
+		return json.Marshal(v.text)
+	case VALUE_BOOLEAN:
+		return []byte(v.text), nil
+	case VALUE_COMPOSITE:
+		return json.Marshal(v.components)
+	case VALUE_INVALID:
+		return []byte(v.text), nil
+	}
+	return []byte("null"), nil
+}
+
+// UnmarshalJSON decodes numbers, strings, booleans and objects. Other JSON, e.g. arrays, gives an invalid value, so a
+// single value cannot stop a whole page of measurements from decoding
+func (v *Value) UnmarshalJSON(data []byte) error {
+	raw := bytes.TrimSpace(data)
+	if len(raw) == 0 {
+		*v = Value{}
+		return nil
+	}
+
+	switch raw[0] {
+	case 'n':
+		*v = Value{}
+	case '"':
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			*v = invalidValue(string(raw), err.Error())
+			return nil
+		}
+		*v = EnumeratedValue(text)
+	case 't', 'f':
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			*v = invalidValue(string(raw), err.Error())
+			return nil
+		}
+		*v = BooleanValue(b)
+	case '{':
+		var components map[string]Value
+		if err := json.Unmarshal(raw, &components); err != nil {
+			*v = invalidValue(string(raw), err.Error())
+			return nil
+		}
+		*v = CompositeValue(components)
+	case '[':
+		*v = invalidValue(string(raw), "lists are not supported")
+	default:
+		var number float64
+		if err := json.Unmarshal(raw, &number); err != nil {
+			*v = invalidValue(string(raw), "not a number")
+			return nil
+		}
+		*v = NumericValue(number)
+	}
+	return nil
+}
+
+// Composite returns the components of a measurement with several values. A blood pressure is composed of systolic and diastolic
+func (mv MeasurementValue) Composite() Value {
+	if mv.Systolic != 0 || mv.Diastolic != 0 {
+		return CompositeValue(map[string]Value{
+			COMPONENT_SYSTOLIC:  NumericValue(float64(mv.Systolic)),
+			COMPONENT_DIASTOLIC: NumericValue(float64(mv.Diastolic)),
+		})
+	}
+	return mv.Value
+}
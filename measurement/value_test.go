@@ -0,0 +1,86 @@
+package measurement
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValueFloat(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  interface{}
+		output float64
+		err    bool
+	}{
+		{"String", "26.2", 26.2, false},
+		{"Int", 26, 26.0, false},
+		{"Float", 26.2, 26.2, false},
+		{"Bool", true, 1.0, false},
+		{"Text", "Pos.", 0, true},
+		{"Missing", nil, 0, true},
+		{"Composite", map[string]interface{}{"fev1": 2.5}, 0, true},
+		{"Unsupported", []int{1}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ValueOf(tt.input).Float()
+			if tt.err {
+				if err == nil {
+					t.Errorf("Expected error for %v", tt.input)
+				}
+				return
+			}
+			if err != nil || tt.output != res {
+				t.Errorf("Expected '%f' got '%f' - %v", tt.output, res, err)
+			}
+		})
+	}
+}
+
+func TestValueJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		kind ValueKind
+		text string
+	}{
+		{"Number", `{"value": 80.5}`, VALUE_NUMERIC, "80.5"},
+		{"Enumerated", `{"value": "+2"}`, VALUE_ENUMERATED, "+2"},
+		{"Boolean", `{"value": true}`, VALUE_BOOLEAN, "true"},
+		{"Null", `{"value": null}`, VALUE_NONE, ""},
+		{"Missing", `{}`, VALUE_NONE, ""},
+		{"Empty text", `{"value": ""}`, VALUE_NONE, ""},
+		{"Composite", `{"value": {"fev1": 2.5, "fev6": 3.1}}`, VALUE_COMPOSITE, "fev1=2.5, fev6=3.1"},
+		{"List", `{"value": [1, 2]}`, VALUE_INVALID, "[1, 2]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mv MeasurementValue
+			if err := json.Unmarshal([]byte(tt.json), &mv); err != nil {
+				t.Fatalf("Error decoding - %v", err)
+			}
+			if mv.Value.Kind() != tt.kind || mv.Value.String() != tt.text {
+				t.Errorf("Expected %d '%s' got %d '%s'", tt.kind, tt.text, mv.Value.Kind(), mv.Value.String())
+			}
+			if tt.kind == VALUE_INVALID && mv.Value.Err() == nil {
+				t.Errorf("Expected error for invalid value")
+			}
+
+			data, err := json.Marshal(mv.Value)
+			if err != nil {
+				t.Fatalf("Error encoding - %v", err)
+			}
+			var again Value
+			if err := json.Unmarshal(data, &again); err != nil || again.Kind() != tt.kind {
+				t.Errorf("Expected value to survive encoding - got %s", data)
+			}
+		})
+	}
+
+	mv := MeasurementValue{Systolic: 120, Diastolic: 80}
+	if c, ok := mv.Composite().Component(COMPONENT_DIASTOLIC); !ok || c.String() != "80" {
+		t.Errorf("Expected blood pressure to be composite - got %s", mv.Composite())
+	}
+}
@@ -0,0 +1,65 @@
+package measurement
+
+import (
+	"fmt"
+	"time"
+)
+
+// WaveformChannel holds the samples of a single lead or channel
+type WaveformChannel struct {
+	Name   string    `json:"name"`
+	Unit   string    `json:"unit"`
+	Values []float64 `json:"values"`
+}
+
+// Waveform is the raw data of an ECG or CTG measurement. All channels are sampled at the same rate
+type Waveform struct {
+	Start      time.Time         `json:"start"`
+	SampleRate float64           `json:"sampleRate"`
+	HeartRate  float64           `json:"heartRate,omitempty"`
+	Channels   []WaveformChannel `json:"channels"`
+}
+
+func (w Waveform) String() string {
+	return fmt.Sprintf("%s - %d channels at %.0f Hz - %s", w.Start.Format(time.RFC822), len(w.Channels), w.SampleRate, w.Duration())
+}
+
+// Samples returns the number of samples in the longest channel
+func (w Waveform) Samples() int {
+	samples := 0
+	for _, c := range w.Channels {
+		if len(c.Values) > samples {
+			samples = len(c.Values)
+		}
+	}
+	return samples
+}
+
+// Duration returns the length of the recording
+func (w Waveform) Duration() time.Duration {
+	if w.SampleRate <= 0 {
+		return 0
+	}
+	return time.Duration(float64(w.Samples()) / w.SampleRate * float64(time.Second))
+}
+
+// Channel returns the named channel
+func (w Waveform) Channel(name string) (WaveformChannel, bool) {
+	for _, c := range w.Channels {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return WaveformChannel{}, false
+}
+
+// FetchWaveform retrieves the waveform data of an ECG or CTG measurement
+func (m clinicianApi) FetchWaveform(waveform string) (Waveform, error) {
+	var result Waveform
+	if err := getResource(waveform, &result); err != nil {
+		return result, err
+	}
+	log.Debug(fmt.Sprintf("Retrieved - %s", result))
+
+	return result, nil
+}
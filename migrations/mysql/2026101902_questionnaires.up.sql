@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS questionnaire_results (
+  id varchar(100) UNIQUE NOT NULL,
+  questionnaire_result varchar(256) UNIQUE NOT NULL,
+  patient varchar(256) NOT NULL,
+  status int,
+  created_at datetime,
+  updated_at datetime,
+
+  PRIMARY KEY(id),
+  INDEX(questionnaire_result),
+  INDEX(patient)
+);
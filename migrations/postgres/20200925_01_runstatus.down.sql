@@ -0,0 +1 @@
+ALTER TABLE runstatus drop column updated_at;
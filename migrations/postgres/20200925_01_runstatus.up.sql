@@ -0,0 +1 @@
+ALTER TABLE runstatus add column updated_at timestamptz;
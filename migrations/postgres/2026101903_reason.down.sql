@@ -0,0 +1 @@
+ALTER TABLE measurements drop column reason;
@@ -0,0 +1,2 @@
+ALTER TABLE measurements drop column consent_id;
+drop table consent;
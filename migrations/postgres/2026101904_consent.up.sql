@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS consent (
+  id varchar(100) UNIQUE NOT NULL,
+  cpr varchar(10) NOT NULL DEFAULT '',
+  patient varchar(256) NOT NULL DEFAULT '',
+  comment varchar(256) NOT NULL DEFAULT '',
+  created_at timestamptz,
+
+  PRIMARY KEY(id)
+);
+CREATE INDEX IF NOT EXISTS consent_cpr ON consent (cpr);
+CREATE INDEX IF NOT EXISTS consent_patient ON consent (patient);
+
+ALTER TABLE measurements add column consent_id varchar(100) NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE questionnaire_results add column consent_id varchar(100) NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+drop table measurements;
+drop table runstatus;
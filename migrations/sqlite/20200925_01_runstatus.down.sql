@@ -0,0 +1,12 @@
+-- SQLite cannot drop columns, so the table is copied without it
+CREATE TABLE runstatus_down (
+  id varchar(100) UNIQUE NOT NULL,
+  lastrun datetime,
+  status int,
+  created_at datetime,
+
+  PRIMARY KEY(id)
+);
+INSERT INTO runstatus_down SELECT id, lastrun, status, created_at FROM runstatus;
+DROP TABLE runstatus;
+ALTER TABLE runstatus_down RENAME TO runstatus;
@@ -0,0 +1 @@
+drop table patient_cache;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS patient_cache (
+  link varchar(256) UNIQUE NOT NULL,
+  patient blob,
+  created_at datetime,
+
+  PRIMARY KEY(link)
+);
@@ -0,0 +1 @@
+drop table questionnaire_results;
@@ -0,0 +1,17 @@
+-- SQLite cannot drop columns, so the table is copied without it
+CREATE TABLE measurements_down (
+  id varchar(100) UNIQUE NOT NULL,
+  measurement varchar(256) UNIQUE NOT NULL,
+  patient varchar(256) NOT NULL,
+  status int,
+  backend_status int,
+  backend_reply varchar(256),
+  created_at datetime,
+  updated_at datetime,
+
+  PRIMARY KEY(id)
+);
+INSERT INTO measurements_down SELECT id, measurement, patient, status, backend_status, backend_reply, created_at, updated_at FROM measurements;
+DROP TABLE measurements;
+ALTER TABLE measurements_down RENAME TO measurements;
+CREATE INDEX IF NOT EXISTS measurements_patient ON measurements (patient);
@@ -0,0 +1 @@
+ALTER TABLE measurements add column reason varchar(256) NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+-- SQLite cannot drop columns, so the table is copied without it
+CREATE TABLE questionnaire_results_down (
+  id varchar(100) UNIQUE NOT NULL,
+  questionnaire_result varchar(256) UNIQUE NOT NULL,
+  patient varchar(256) NOT NULL,
+  status int,
+  created_at datetime,
+  updated_at datetime,
+
+  PRIMARY KEY(id)
+);
+INSERT INTO questionnaire_results_down SELECT id, questionnaire_result, patient, status, created_at, updated_at FROM questionnaire_results;
+DROP TABLE questionnaire_results;
+ALTER TABLE questionnaire_results_down RENAME TO questionnaire_results;
+CREATE INDEX IF NOT EXISTS questionnaire_results_patient ON questionnaire_results (patient);
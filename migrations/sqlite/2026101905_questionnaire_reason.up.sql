@@ -0,0 +1 @@
+ALTER TABLE questionnaire_results add column reason varchar(256) NOT NULL DEFAULT '';
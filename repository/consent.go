@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// NormalizeCPR removes separators from a CPR number
+func NormalizeCPR(cpr string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(cpr))
+}
+
+func validateConsent(c ConsentEntry) error {
+	if len(c.CPR) == 0 && len(c.Patient) == 0 {
+		return fmt.Errorf("Either cpr or patient must be set")
+	}
+	if len(c.CPR) > 0 {
+		if len(c.CPR) != 10 {
+			return fmt.Errorf("Invalid cpr %s - must be 10 digits", c.CPR)
+		}
+		for _, r := range c.CPR {
+			if r < '0' || r > '9' {
+				return fmt.Errorf("Invalid cpr %s - must be 10 digits", c.CPR)
+			}
+		}
+	}
+	return nil
+}
+
+// CreateConsent registers a withdrawn consent. Registering an existing cpr or patient returns the existing entry
+func (mi repositoryImpl) CreateConsent(c ConsentEntry) (ConsentEntry, error) {
+	created, err := mi.CreateConsents([]ConsentEntry{c})
+	if err != nil {
+		return c, err
+	}
+	return created[0], nil
+}
+
+// CreateConsents registers the entries in one transaction - either all entries are registered or none
+func (mi repositoryImpl) CreateConsents(entries []ConsentEntry) ([]ConsentEntry, error) {
+	created := []ConsentEntry{}
+	for i, c := range entries {
+		c.CPR = NormalizeCPR(c.CPR)
+		c.Patient = strings.TrimSpace(c.Patient)
+		if err := validateConsent(c); err != nil {
+			if len(entries) > 1 {
+				return created, fmt.Errorf("Entry %d: %v", i+1, err)
+			}
+			return created, err
+		}
+		entries[i] = c
+	}
+
+	sess, err := mi.getSession()
+	if err != nil {
+		return created, errors.Wrap(err, "Error getting conection")
+	}
+
+	tx, err := sess.Beginx()
+	if err != nil {
+		return created, errors.Wrap(err, "Error creating transaction")
+	}
+	rollback := func() {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.Errorf("Error rolling back transaction %+v", rerr)
+		}
+	}
+
+	for _, c := range entries {
+		var existing ConsentEntry
+		err = tx.Get(&existing, sess.Rebind("SELECT id,cpr,patient,comment,created_at FROM consent WHERE cpr=? AND patient=?"), c.CPR, c.Patient)
+		if err == nil {
+			created = append(created, existing)
+			continue
+		}
+		if err != sql.ErrNoRows {
+			rollback()
+			return []ConsentEntry{}, errors.Wrap(err, "Error querying consent")
+		}
+
+		c.ID = uuid.New()
+		c.CreatedAt.Time = time.Now()
+
+		if _, err := tx.Exec(sess.Rebind("INSERT INTO consent (id,cpr,patient,comment,created_at) VALUES (?,?,?,?,?)"), c.ID, c.CPR, c.Patient, c.Comment, c.CreatedAt.Time); err != nil {
+			rollback()
+			return []ConsentEntry{}, errors.Wrap(err, "Error inserting consent")
+		}
+		created = append(created, c)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return []ConsentEntry{}, errors.Wrap(err, "Error commit transaction")
+	}
+	return created, nil
+}
+
+// DeleteConsent removes a consent entry - exports for the citizen are resumed for new measurements
+func (mi repositoryImpl) DeleteConsent(id string) error {
+	sess, err := mi.getSession()
+	if err != nil {
+		return errors.Wrap(err, "Error getting conection")
+	}
+
+	res, err := sess.Exec(sess.Rebind("DELETE FROM consent WHERE id=?"), id)
+	if err != nil {
+		return errors.Wrap(err, "Error deleting consent")
+	}
+
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return fmt.Errorf("Consent %s not found : %w", id, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// FindConsents returns all registered consent entries
+func (mi repositoryImpl) FindConsents() ([]ConsentEntry, error) {
+	entries := []ConsentEntry{}
+
+	sess, err := mi.getSession()
+	if err != nil {
+		return entries, errors.Wrap(err, "Error getting session")
+	}
+
+	if err := sess.Select(&entries, "SELECT id,cpr,patient,comment,created_at FROM consent ORDER BY created_at"); err != nil {
+		return entries, errors.Wrap(err, "Error retrieving consent")
+	}
+	return entries, nil
+}
+
+// FindConsent returns the consent entries registered for the patient link or the cpr. Empty arguments are not matched
+func (mi repositoryImpl) FindConsent(patient, cpr string) ([]ConsentEntry, error) {
+	entries := []ConsentEntry{}
+
+	sess, err := mi.getSession()
+	if err != nil {
+		return entries, errors.Wrap(err, "Error getting session")
+	}
+
+	query := "SELECT id,cpr,patient,comment,created_at FROM consent WHERE (patient=? AND patient<>'') OR (cpr=? AND cpr<>'') ORDER BY created_at"
+	if err := sess.Select(&entries, sess.Rebind(query), strings.TrimSpace(patient), NormalizeCPR(cpr)); err != nil {
+		return entries, errors.Wrap(err, "Error retrieving consent")
+	}
+	return entries, nil
+}
+
+// HasConsentByCPR checks whether any consent entries are registered by cpr
+func (mi repositoryImpl) HasConsentByCPR() (bool, error) {
+	sess, err := mi.getSession()
+	if err != nil {
+		return false, errors.Wrap(err, "Error getting session")
+	}
+
+	var ids []string
+	if err := sess.Select(&ids, "SELECT id FROM consent WHERE cpr<>'' LIMIT 1"); err != nil {
+		return false, errors.Wrap(err, "Error retrieving consent")
+	}
+	return len(ids) > 0, nil
+}
+
+// ParseConsentCSV reads consent entries from CSV with the columns cpr, patient and comment. A header line is optional
+func ParseConsentCSV(r io.Reader) ([]ConsentEntry, error) {
+	var entries []ConsentEntry
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return entries, errors.Wrap(err, "Error reading csv")
+	}
+
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && strings.EqualFold(strings.TrimSpace(record[0]), "cpr") {
+			continue
+		}
+
+		entry := ConsentEntry{}
+		if len(record) > 0 {
+			entry.CPR = NormalizeCPR(record[0])
+		}
+		if len(record) > 1 {
+			entry.Patient = strings.TrimSpace(record[1])
+		}
+		if len(record) > 2 {
+			entry.Comment = strings.TrimSpace(record[2])
+		}
+		if len(entry.CPR) == 0 && len(entry.Patient) == 0 {
+			continue
+		}
+		if err := validateConsent(entry); err != nil {
+			return entries, fmt.Errorf("Line %d: %v", i+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
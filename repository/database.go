@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/mysql"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// DriverName returns the name of the database/sql driver of the database type. sqlx rebinds queries to the
+// placeholders of the driver
+func DriverName(dbType string) string {
+	if dbType == app.DATABASE_TYPE_SQLITE {
+		return "sqlite3"
+	}
+	return dbType
+}
+
+// OpenDatabase opens a connection pool to the database selected by database.type
+func OpenDatabase(cfg *app.Config) (*sqlx.DB, error) {
+	dbType, err := cfg.DatabaseType()
+	if err != nil {
+		return nil, err
+	}
+	dbURL, err := cfg.CreateDatabaseURL()
+	if err != nil {
+		return nil, errors.Wrap(err, "Error parsing db url")
+	}
+
+	return sqlx.Open(DriverName(dbType), dbURL)
+}
+
+// NewMigrate returns the migrations for the database type found in a directory per type below dir, e.g.
+// migrations/postgres. Closing the migrations closes db
+func NewMigrate(db *sql.DB, dbType string, dir string) (*migrate.Migrate, error) {
+	var driver database.Driver
+	var err error
+	switch dbType {
+	case app.DATABASE_TYPE_MYSQL:
+		driver, err = mysql.WithInstance(db, &mysql.Config{})
+	case app.DATABASE_TYPE_POSTGRES:
+		driver, err = postgres.WithInstance(db, &postgres.Config{})
+	case app.DATABASE_TYPE_SQLITE:
+		driver, err = sqlite3.WithInstance(db, &sqlite3.Config{})
+	default:
+		return nil, fmt.Errorf("Unsupported database type %s", dbType)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error setting up migration driver")
+	}
+
+	return migrate.NewWithDatabaseInstance(fmt.Sprintf("file://%s", filepath.Join(dir, dbType)), dbType, driver)
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type cachedPatient struct {
+	Link      string    `db:"link"`
+	Patient   []byte    `db:"patient"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// FindCachedPatient returns the stored patient document and the time it was stored
+func (mi repositoryImpl) FindCachedPatient(link string) ([]byte, time.Time, error) {
+	var res cachedPatient
+	sess, err := mi.getSession()
+	if err != nil {
+		return res.Patient, res.CreatedAt, errors.Wrap(err, "Error getting conection")
+	}
+
+	if err := sess.Get(&res, sess.Rebind("SELECT link,patient,created_at FROM patient_cache WHERE link=?"), link); err != nil {
+		if err == sql.ErrNoRows {
+			return res.Patient, res.CreatedAt, fmt.Errorf("Patient %s not cached : %w", link, err)
+		}
+		return res.Patient, res.CreatedAt, errors.Wrap(err, "Error querying patient cache")
+	}
+
+	return res.Patient, res.CreatedAt, nil
+}
+
+// StoreCachedPatient stores or replaces the patient document for the link
+func (mi repositoryImpl) StoreCachedPatient(link string, data []byte) error {
+	sess, err := mi.getSession()
+	if err != nil {
+		return errors.Wrap(err, "Error getting conection")
+	}
+
+	tx, err := sess.Begin()
+	if err != nil {
+		return errors.Wrap(err, "Error creating transaction")
+	}
+
+	if _, err := tx.Exec(sess.Rebind("DELETE FROM patient_cache WHERE link=?"), link); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.Errorf("Error rolling back transaction %+v", rerr)
+		}
+		return errors.Wrap(err, "Error removing cached patient")
+	}
+
+	if _, err := tx.Exec(sess.Rebind("INSERT INTO patient_cache (link,patient,created_at) VALUES (?,?,?)"), link, data, time.Now()); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.Errorf("Error rolling back transaction %+v", rerr)
+		}
+		return errors.Wrap(err, "Error storing cached patient")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "Error commiting transaction")
+	}
+	return nil
+}
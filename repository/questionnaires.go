@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// FindOrCreateQuestionnaireResult looks up the export state of a questionnaire result - creating it if not seen before
+func (mi repositoryImpl) FindOrCreateQuestionnaireResult(q QuestionnaireExportState) (QuestionnaireExportState, error) {
+	var res QuestionnaireExportState
+	sess, err := mi.getSession()
+	if err != nil {
+		log.Error("Error gettting DB session")
+		return QuestionnaireExportState{}, errors.Wrap(err, "Error getting conection")
+	}
+
+	err = sess.Get(&res, sess.Rebind("SELECT id,questionnaire_result,patient,status,reason,consent_id,created_at,updated_at FROM questionnaire_results WHERE questionnaire_result=?"), q.QuestionnaireResult)
+	if err != nil && err != sql.ErrNoRows {
+		log.Error("Error querying database", err)
+		return QuestionnaireExportState{}, errors.Wrap(err, "Error querying database")
+	}
+
+	if len(res.QuestionnaireResult) > 0 {
+		return res, nil
+	}
+
+	q.ID = uuid.New()
+	now := time.Now()
+	if q.CreatedAt.Time.IsZero() {
+		q.CreatedAt.Time = now
+	}
+	q.UpdatedAt.Time = now
+
+	tx, err := sess.Begin()
+	if err != nil {
+		return q, errors.Wrap(err, "Error creating transaction")
+	}
+	if _, err := tx.Exec(sess.Rebind("INSERT INTO questionnaire_results (id,questionnaire_result,patient,status,reason,consent_id,created_at,updated_at) VALUES (?,?,?,?,?,?,?,?)"), q.ID, q.QuestionnaireResult, q.Patient, q.Status, q.Reason, q.ConsentID, q.CreatedAt.Time, q.UpdatedAt.Time); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.Errorf("Error rolling back transaction %+v", rerr)
+		}
+		return q, errors.Wrap(err, "Error inserting data")
+	}
+	if err := tx.Commit(); err != nil {
+		return q, errors.Wrap(err, "Error commit transaction")
+	}
+
+	return q, nil
+}
+
+// UpdateQuestionnaireResult updates the export status of a questionnaire result
+func (mi repositoryImpl) UpdateQuestionnaireResult(q QuestionnaireExportState) (QuestionnaireExportState, error) {
+	if len(q.QuestionnaireResult) == 0 {
+		return q, fmt.Errorf("Questionnaire result is not set - unknown questionnaire result")
+	}
+
+	q.UpdatedAt.Time = time.Now()
+
+	sess, err := mi.getSession()
+	if err != nil {
+		return q, errors.Wrap(err, "Error getting conection")
+	}
+
+	tx, err := sess.Begin()
+	if err != nil {
+		return q, errors.Wrap(err, "Error creating transaction")
+	}
+
+	if _, err := tx.Exec(sess.Rebind("UPDATE questionnaire_results set updated_at=?, status=?, reason=?, consent_id=? where questionnaire_result=?"), q.UpdatedAt.Time, q.Status, q.Reason, q.ConsentID, q.QuestionnaireResult); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			log.Errorf("Error rollback transaction - %v", rerr)
+		}
+		return q, errors.Wrap(err, "Error updating questionnaire result")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return q, errors.Wrap(err, "Error commmiting tranaction")
+	}
+
+	return q, nil
+}
+
+// FindQuestionnaireResultsByStatus returns the questionnaire results with the given export status
+func (mi repositoryImpl) FindQuestionnaireResultsByStatus(status int) ([]QuestionnaireExportState, error) {
+	var results []QuestionnaireExportState
+
+	sess, err := mi.getSession()
+	if err != nil {
+		return results, errors.Wrap(err, "Error getting session")
+	}
+
+	if err := sess.Select(&results, sess.Rebind("SELECT id,questionnaire_result,patient,status,reason,consent_id,created_at,updated_at FROM questionnaire_results where status=?"), status); err != nil {
+		return results, errors.Wrap(err, "Error retrieving questionnaire results")
+	}
+
+	return results, nil
+}
@@ -68,12 +68,12 @@ func getSumFromDb(sess *sqlx.DB, query string) int {
 	return sum
 }
 
-func (mi repositoryImpl) GetTotals() (int, int, int, int) {
+func (mi repositoryImpl) GetTotals() (int, int, int, int, int) {
 	start := time.Now()
 	sess, err := mi.getSession()
 	if err != nil {
 		log.Errorf("Error gettting DB session - %v", err)
-		return 0, 0, 0, 0
+		return 0, 0, 0, 0, 0
 	}
 	afterdb := time.Now()
 	log.Infof("Spend %s on getting db connection", afterdb.Sub(start))
@@ -81,9 +81,39 @@ func (mi repositoryImpl) GetTotals() (int, int, int, int) {
 	failed := getSumFromDb(sess, fmt.Sprintf("SELECT count(measurement) from measurements where status=%d", FAILED))
 	tempfailed := getSumFromDb(sess, fmt.Sprintf("SELECT count(measurement) from measurements where status=%d", TEMP_FAILURE))
 	rejected := getSumFromDb(sess, fmt.Sprintf("SELECT count(measurement) from measurements where status=%d", NO_EXPORT))
+	invalid := getSumFromDb(sess, fmt.Sprintf("SELECT count(measurement) from measurements where status=%d", INVALID))
 
 	log.Infof("func=gettotals tt=%s dbt=%s totalst=%s", time.Since(start), afterdb.Sub(start), time.Since(afterdb))
-	return total, failed, tempfailed, rejected
+	return total, failed, tempfailed, rejected, invalid
+}
+
+// GetReasons returns the number of measurements with the status per reason
+func (mi repositoryImpl) GetReasons(status int) map[string]int {
+	reasons := make(map[string]int)
+	sess, err := mi.getSession()
+	if err != nil {
+		log.Errorf("Error gettting DB session - %v", err)
+		return reasons
+	}
+
+	rows, err := sess.Queryx(fmt.Sprintf("SELECT reason, count(measurement) FROM measurements WHERE status=%d AND reason <> '' GROUP BY reason", status))
+	if err != nil {
+		log.Error("Error quering db ", err)
+		return reasons
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reason string
+		var count int
+		if err := rows.Scan(&reason, &count); err != nil {
+			log.Error("Error reading reasons ", err)
+			return reasons
+		}
+		reasons[reason] = count
+	}
+
+	return reasons
 }
 
 // Returns time for last run, total runs,failed, successfull, status of last run
@@ -129,7 +159,7 @@ func (mi repositoryImpl) FindOrCreateMeasurement(m MeasurementExportState) (Meas
 		log.Error("Error gettting DB session")
 		return MeasurementExportState{}, errors.Wrap(err, "Error getting conection")
 	}
-	err = sess.Get(&res, "SELECT id,measurement,patient,status,created_at,updated_at FROM measurements WHERE measurement=?", m.Measurement)
+	err = sess.Get(&res, sess.Rebind("SELECT id,measurement,patient,status,reason,consent_id,created_at,updated_at FROM measurements WHERE measurement=?"), m.Measurement)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			log.Error("Error querying database", err)
@@ -151,7 +181,7 @@ func (mi repositoryImpl) FindOrCreateMeasurement(m MeasurementExportState) (Meas
 		}
 		m.UpdatedAt.Time = now
 		tx := sess.MustBegin()
-		_, err := tx.Exec("INSERT INTO measurements (id,measurement,patient,status,created_at,updated_at) VALUES (?,?,?,?,?,?)", m.ID, m.Measurement, m.Patient, m.Status, m.CreatedAt.Time, m.UpdatedAt.Time)
+		_, err := tx.Exec(sess.Rebind("INSERT INTO measurements (id,measurement,patient,status,reason,consent_id,created_at,updated_at) VALUES (?,?,?,?,?,?,?,?)"), m.ID, m.Measurement, m.Patient, m.Status, m.Reason, m.ConsentID, m.CreatedAt.Time, m.UpdatedAt.Time)
 
 		if err != nil {
 			log.Error("Error inserting data ", err)
@@ -189,7 +219,7 @@ func (mi repositoryImpl) UpdateMeasurement(m MeasurementExportState) (Measuremen
 		return m, errors.Wrap(err, "Error creating transaction")
 	}
 
-	res, err := tx.Exec("UPDATE measurements set updated_at=?, status=? where measurement=?", m.UpdatedAt.Time, m.Status, m.Measurement)
+	res, err := tx.Exec(sess.Rebind("UPDATE measurements set updated_at=?, status=?, reason=?, consent_id=? where measurement=?"), m.UpdatedAt.Time, m.Status, m.Reason, m.ConsentID, m.Measurement)
 	if err != nil {
 		log.Error("Error updating row", err)
 		log.Infof("Trace %+v", err)
@@ -234,7 +264,7 @@ func (mi repositoryImpl) FindMeasurement(id string) (MeasurementExportState, err
 		log.Error("Error gettting DB session")
 		return MeasurementExportState{}, errors.Wrap(err, "Error getting conection")
 	}
-	err = sess.Get(&res, "SELECT id,measurement,patient,status,created_at,updated_at FROM measurements WHERE id=?", id)
+	err = sess.Get(&res, sess.Rebind("SELECT id,measurement,patient,status,reason,consent_id,created_at,updated_at FROM measurements WHERE id=?"), id)
 	if err != nil {
 		if err != sql.ErrNoRows {
 			log.Error("Error querying database", err)
@@ -258,7 +288,7 @@ func (mi repositoryImpl) FindMeasurementsByStatus(status int) ([]MeasurementExpo
 		return measurements, errors.Wrap(err, "Error getting session")
 	}
 
-	if err := sess.Select(&measurements, "SELECT id,measurement,patient,status,created_at,updated_at FROM measurements where status=?", status); err != nil {
+	if err := sess.Select(&measurements, sess.Rebind("SELECT id,measurement,patient,status,reason,consent_id,created_at,updated_at FROM measurements where status=?"), status); err != nil {
 		return measurements, errors.Wrap(err, "Error retrieving measuremnts")
 	}
 
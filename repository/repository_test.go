@@ -4,10 +4,12 @@ import (
 	"database/sql"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/app"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pkg/errors"
@@ -19,65 +21,106 @@ const sqliteDSN = "file:repositorytest.db?cache=shared&mode=memory"
 
 var application *app.Config
 
-func setupTestDatabase() (*sql.DB, *sqlx.DB, Repository, error) {
-	// db, conn, err := testutil.SetupTestSQLDatabase(sqliteDSN)
+// testDatabase is a database the repository tests run against. The dialect decides the placeholders of the queries
+type testDatabase struct {
+	name    string
+	driver  string
+	dsn     string
+	dialect string
+}
 
-	var repo Repository
-	var conn *sqlx.DB
-	db, err := sql.Open("sqlite3", sqliteDSN)
-	if err != nil {
-		log.Fatal(err)
+// testDatabases returns the databases to test against. SQLite in memory is migrated with the SQLite migrations and
+// tested with the placeholders of both dialects, as it takes both - it does not run the MySQL or PostgreSQL migrations.
+// MySQL and PostgreSQL servers are tested when their DSN is set in EXPORTER_TEST_MYSQL_DSN or EXPORTER_TEST_POSTGRES_DSN
+// - the MySQL DSN needs parseTime=true and multiStatements=true. The server databases are dropped and migrated for each
+// test. The pull request CI sets EXPORTER_TEST_POSTGRES_DSN
+func testDatabases() []testDatabase {
+	databases := []testDatabase{
+		{name: "sqlite-mysql", driver: "sqlite3", dsn: sqliteDSN, dialect: app.DATABASE_TYPE_MYSQL},
+		{name: "sqlite-postgres", driver: "sqlite3", dsn: sqliteDSN, dialect: app.DATABASE_TYPE_POSTGRES},
+	}
+	if dsn := os.Getenv("EXPORTER_TEST_MYSQL_DSN"); len(dsn) > 0 {
+		databases = append(databases, testDatabase{name: "mysql", driver: "mysql", dsn: dsn, dialect: app.DATABASE_TYPE_MYSQL})
 	}
+	if dsn := os.Getenv("EXPORTER_TEST_POSTGRES_DSN"); len(dsn) > 0 {
+		databases = append(databases, testDatabase{name: "postgres", driver: "postgres", dsn: dsn, dialect: app.DATABASE_TYPE_POSTGRES})
+	}
+	return databases
+}
 
-	if db == nil {
-		log.Fatal("DB is nil!!")
-	} else {
-		log.Info("DB IS NOT NIL")
+// forEachDatabase runs the test against each of the test databases
+func forEachDatabase(t *testing.T, test func(t *testing.T, d testDatabase)) {
+	for _, d := range testDatabases() {
+		t.Run(d.name, func(t *testing.T) {
+			test(t, d)
+		})
 	}
+}
 
-	// defer db.Close()
-	// defer conn.Close()
-
-	// if err := testutil.PrepareDatabase(db); err != nil {
-	// 	log.Fatalf("Error preparing database - %v", err)
-	// }
-
-	createQueryMeasurements := `
-    DROP TABLE IF EXISTS measurements;
-    CREATE TABLE IF NOT EXISTS measurements (
-  id TEXT UNIQUE NOT NULL,
-  measurement TEXT UNIQUE NOT NULL PRIMARY KEY,
-  patient TEXT,
-  status int,
-  backend_status int,
-  backend_reply text,
-  created_at datetime,
-  updated_at datetime);`
-
-	if db == nil {
-		return db, conn, repo, fmt.Errorf("DB instance is nil")
+// migrateTestDatabase drops the server database and applies the migrations of the dialect
+func migrateTestDatabase(d testDatabase) error {
+	for _, drop := range []bool{true, false} {
+		db, err := sql.Open(d.driver, d.dsn)
+		if err != nil {
+			return errors.Wrap(err, "Error opening database")
+		}
+		m, err := NewMigrate(db, d.dialect, "../migrations")
+		if err != nil {
+			db.Close()
+			return errors.Wrap(err, "Error getting migrations")
+		}
+		if drop {
+			err = m.Drop()
+		} else {
+			err = m.Up()
+		}
+		m.Close()
+		if err != nil {
+			return errors.Wrap(err, "Error migrating database")
+		}
+	}
+	return nil
+}
+
+func setupTestDatabase(d testDatabase) (*sql.DB, *sqlx.DB, Repository, error) {
+	var repo Repository
+	var conn *sqlx.DB
+
+	if d.driver != "sqlite3" {
+		if err := migrateTestDatabase(d); err != nil {
+			return nil, conn, repo, err
+		}
+		db, err := sql.Open(d.driver, d.dsn)
+		if err != nil {
+			return db, conn, repo, errors.Wrap(err, "Error opening database")
+		}
+		conn = sqlx.NewDb(db, d.dialect)
+		repo, err = InitRepository(application, conn)
+		return db, conn, repo, err
 	}
 
-	_, err = db.Exec(createQueryMeasurements)
+	db, err := sql.Open(d.driver, d.dsn)
 	if err != nil {
-		return db, conn, repo, errors.Wrap(err, "Error bootstrapping db / measurements")
+		return db, conn, repo, errors.Wrap(err, "Error opening database")
 	}
 
-	createQueryRUnstatus := `
-DROP TABLE IF EXISTS runstatus;
-CREATE TABLE IF NOT EXISTS runstatus (
-  id text UNIQUE NOT NULL PRIMARY KEY,
-  lastrun datetime,
-  status int,
-  created_at datetime,
-  updated_at datetime);`
-
-	_, err = db.Exec(createQueryRUnstatus)
-	if err != nil {
-		return db, conn, repo, errors.Wrap(err, "Error bootstrapping db / runstatus")
+	// The in-memory database is gone when its last connection closes, so the migrations are not closed
+	for _, drop := range []bool{true, false} {
+		m, err := NewMigrate(db, app.DATABASE_TYPE_SQLITE, "../migrations")
+		if err != nil {
+			return db, conn, repo, errors.Wrap(err, "Error getting migrations")
+		}
+		if drop {
+			err = m.Drop()
+		} else {
+			err = m.Up()
+		}
+		if err != nil {
+			return db, conn, repo, errors.Wrap(err, "Error migrating database")
+		}
 	}
 
-	conn = sqlx.NewDb(db, "mysql")
+	conn = sqlx.NewDb(db, d.dialect)
 
 	repo, err = InitRepository(application, conn)
 	if err != nil {
@@ -141,188 +184,459 @@ func TestStatusToText(t *testing.T) {
 }
 
 func TestFindOrCreateMeasurement(t *testing.T) {
-	db, conn, repo, err := setupTestDatabase()
-	if err != nil {
-		t.Errorf("Error setting up db %+v", err)
-	}
-	defer func() {
-		repo.Close()
-		conn.Close()
-		db.Close()
-		fmt.Println("Resources closed")
-	}()
-
-	var count int
-	res := conn.QueryRow("SELECT count(*) from measurements")
-	if err := res.Scan(&count); err != nil {
-		t.Errorf("Error reading results - %+v", err)
-	}
-	fmt.Println("Count ", count)
-
-	if count != 0 {
-		t.Errorf("Database count is %d, expect 0", count)
-	}
-	mes := "/a/measurement"
-	m := MeasurementExportState{Measurement: mes}
-
-	m, err = repo.FindOrCreateMeasurement(m)
-	if err != nil {
-		t.Errorf("Error retrieving measurements %v", err)
-	}
-	if m.Status != 0 {
-		t.Errorf("Wrong status")
-	}
-	// if err := mock.ExpectationsWereMet(); err != nil {
-	// 	t.Errorf("Errors encountered %v", err)
-	// }
-	res = conn.QueryRow("SELECT count(*) from measurements")
-	if err := res.Scan(&count); err != nil {
-		t.Errorf("Error reading results - %+v", err)
-	}
-	fmt.Println("Count ", count)
-	if count != 1 {
-		t.Errorf("Database count is %d, expect 0", count)
-	}
-
-	if m.ID.URN() == "" {
-		t.Errorf("M ID %s is invalid %s", m.ID, m.ID.URN())
-	}
-
-	if m.CreatedAt.Time.IsZero() {
-		t.Errorf("Created Time stamp not set")
-	}
-	if m.UpdatedAt.Time.IsZero() {
-		t.Errorf("Updated Time stamp not set")
-	}
-	if m.Status != INITIAL {
-		t.Errorf("Status is %s should be %s", StatusToText(m.Status), StatusToText(INITIAL))
-	}
+	forEachDatabase(t, func(t *testing.T, d testDatabase) {
+		db, conn, repo, err := setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		defer func() {
+			repo.Close()
+			conn.Close()
+			db.Close()
+			fmt.Println("Resources closed")
+		}()
+
+		var count int
+		res := conn.QueryRow("SELECT count(*) from measurements")
+		if err := res.Scan(&count); err != nil {
+			t.Errorf("Error reading results - %+v", err)
+		}
+		fmt.Println("Count ", count)
+
+		if count != 0 {
+			t.Errorf("Database count is %d, expect 0", count)
+		}
+		mes := "/a/measurement"
+		m := MeasurementExportState{Measurement: mes}
+
+		m, err = repo.FindOrCreateMeasurement(m)
+		if err != nil {
+			t.Errorf("Error retrieving measurements %v", err)
+		}
+		if m.Status != 0 {
+			t.Errorf("Wrong status")
+		}
+		// if err := mock.ExpectationsWereMet(); err != nil {
+		// 	t.Errorf("Errors encountered %v", err)
+		// }
+		res = conn.QueryRow("SELECT count(*) from measurements")
+		if err := res.Scan(&count); err != nil {
+			t.Errorf("Error reading results - %+v", err)
+		}
+		fmt.Println("Count ", count)
+		if count != 1 {
+			t.Errorf("Database count is %d, expect 0", count)
+		}
+
+		if m.ID.URN() == "" {
+			t.Errorf("M ID %s is invalid %s", m.ID, m.ID.URN())
+		}
+
+		if m.CreatedAt.Time.IsZero() {
+			t.Errorf("Created Time stamp not set")
+		}
+		if m.UpdatedAt.Time.IsZero() {
+			t.Errorf("Updated Time stamp not set")
+		}
+		if m.Status != INITIAL {
+			t.Errorf("Status is %s should be %s", StatusToText(m.Status), StatusToText(INITIAL))
+		}
+	})
 }
 
 func TestCheckRepository(t *testing.T) {
-	db, conn, repo, err := setupTestDatabase()
-	if err != nil {
-		t.Errorf("Error setting up db %+v", err)
-	}
-	defer func() {
-		repo.Close()
-		conn.Close()
-		db.Close()
-		fmt.Println("Resources closed")
-	}()
-
-	if err := repo.CheckRepository(); err != nil {
-		t.Errorf("Check failed %v", err)
-	}
-
-	if err := conn.Close(); err != nil {
-		t.Errorf("Error closing connection %v", err)
-	}
-	if err := repo.CheckRepository(); err == nil {
-		t.Errorf("Check succeeded - should fail %v", err)
-	}
-
-	db, conn, repo, err = setupTestDatabase()
-	if err != nil {
-		t.Errorf("Error setting up db %+v", err)
-	}
-	if err := conn.Close(); err != nil {
-		t.Errorf("Error closing connection %v", err)
-	}
-	if err := db.Close(); err != nil {
-		t.Errorf("Error closing connection %v", err)
-	}
+	forEachDatabase(t, func(t *testing.T, d testDatabase) {
+		db, conn, repo, err := setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		defer func() {
+			repo.Close()
+			conn.Close()
+			db.Close()
+			fmt.Println("Resources closed")
+		}()
+
+		if err := repo.CheckRepository(); err != nil {
+			t.Errorf("Check failed %v", err)
+		}
+
+		if err := conn.Close(); err != nil {
+			t.Errorf("Error closing connection %v", err)
+		}
+		if err := repo.CheckRepository(); err == nil {
+			t.Errorf("Check succeeded - should fail %v", err)
+		}
+
+		db, conn, repo, err = setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		if err := conn.Close(); err != nil {
+			t.Errorf("Error closing connection %v", err)
+		}
+		if err := db.Close(); err != nil {
+			t.Errorf("Error closing connection %v", err)
+		}
+
+		if err := repo.CheckRepository(); err == nil {
+			t.Errorf("Check succeeded - should fail %v", err)
+		}
+
+		db, conn, repo, err = setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		if err := conn.Close(); err != nil {
+			t.Errorf("Error closing connection %v", err)
+		}
+		if err := db.Close(); err != nil {
+			t.Errorf("Error closing connection %v", err)
+		}
+		if err := repo.Close(); err != nil {
+			t.Errorf("Error closing connection %v", err)
+		}
+
+		if err := repo.CheckRepository(); err == nil {
+			t.Errorf("Check succeeded - should fail %v", err)
+		}
+	})
+}
 
-	if err := repo.CheckRepository(); err == nil {
-		t.Errorf("Check succeeded - should fail %v", err)
-	}
+func TestUpdateMeasurement(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d testDatabase) {
+		db, conn, repo, err := setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		defer func() {
+			repo.Close()
+			conn.Close()
+			db.Close()
+			fmt.Println("Resources closed")
+		}()
+
+		var count int
+		res := conn.QueryRow("SELECT count(*) from measurements")
+		if err := res.Scan(&count); err != nil {
+			t.Errorf("Error reading results - %+v", err)
+		}
+		fmt.Println("Count ", count)
+
+		if count != 0 {
+			t.Errorf("Database count is %d, expect 0", count)
+		}
+		mes := "/a/measurement"
+		m := MeasurementExportState{Measurement: mes, Patient: "mypatient"}
+
+		m, err = repo.FindOrCreateMeasurement(m)
+		if err != nil {
+			t.Errorf("Error retrieving measurements %v", err)
+		}
+		if m.Status != 0 {
+			t.Errorf("Wrong status")
+		}
+		if m.CreatedAt.Time.IsZero() {
+			t.Errorf("Created Time stamp not set")
+		}
+		if m.UpdatedAt.Time.IsZero() {
+			t.Errorf("Updated Time stamp not set")
+		}
+		if m.Status != INITIAL {
+			t.Errorf("Status is %s should be %s", StatusToText(m.Status), StatusToText(INITIAL))
+		}
+
+		m.Status = TEMP_FAILURE
+
+		m, err = repo.UpdateMeasurement(m)
+		if err != nil {
+			t.Errorf("Error updating measurement %v", err)
+		}
+
+		m2, err := repo.FindOrCreateMeasurement(MeasurementExportState{Measurement: mes})
+		if err != nil {
+			t.Errorf("Error retrieving measurements %v", err)
+		}
+		fmt.Println("Received ", m2, " ", m2.UpdatedAt.Time)
+		if m2.Status != m.Status {
+			t.Errorf("Status is %s - should be %s", StatusToText(m2.Status), StatusToText(m.Status))
+
+		}
+
+		if m2.ID != m.ID {
+			t.Errorf("IDS different - %s <-> %s", m2.ID.String(), m.ID.String())
+		}
+		if !m2.UpdatedAt.Valid {
+			t.Errorf("Updated time stamp not correct %v", m2.UpdatedAt.Valid)
+		}
+
+		if m2.UpdatedAt.Time.Format(time.RFC3339) != m.UpdatedAt.Time.Format(time.RFC3339) {
+			t.Errorf("Updated time are different - %s<>%s", m2.UpdatedAt.Time.Format(time.RFC3339), m.UpdatedAt.Time.Format(time.RFC3339))
+		}
+	})
+}
 
-	db, conn, repo, err = setupTestDatabase()
-	if err != nil {
-		t.Errorf("Error setting up db %+v", err)
-	}
-	if err := conn.Close(); err != nil {
-		t.Errorf("Error closing connection %v", err)
-	}
-	if err := db.Close(); err != nil {
-		t.Errorf("Error closing connection %v", err)
-	}
-	if err := repo.Close(); err != nil {
-		t.Errorf("Error closing connection %v", err)
-	}
+func TestPatientCache(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d testDatabase) {
+		db, conn, repo, err := setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		defer func() {
+			repo.Close()
+			conn.Close()
+			db.Close()
+		}()
+
+		link := "http://clinician/api/patients/1"
+		if _, _, err := repo.FindCachedPatient(link); err == nil {
+			t.Error("Patient should not be cached")
+		}
+
+		if err := repo.StoreCachedPatient(link, []byte(`{"firstName":"First"}`)); err != nil {
+			t.Fatalf("Error storing patient %v", err)
+		}
+		if err := repo.StoreCachedPatient(link, []byte(`{"firstName":"Second"}`)); err != nil {
+			t.Fatalf("Error replacing patient %v", err)
+		}
+
+		data, storedAt, err := repo.FindCachedPatient(link)
+		if err != nil {
+			t.Fatalf("Error finding patient %v", err)
+		}
+		if string(data) != `{"firstName":"Second"}` {
+			t.Errorf("Expected replaced patient - got %s", string(data))
+		}
+		if storedAt.IsZero() {
+			t.Error("Stored time not set")
+		}
+	})
+}
 
-	if err := repo.CheckRepository(); err == nil {
-		t.Errorf("Check succeeded - should fail %v", err)
-	}
+func TestQuestionnaireResults(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d testDatabase) {
+		db, conn, repo, err := setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		defer func() {
+			repo.Close()
+			conn.Close()
+			db.Close()
+		}()
+
+		q := QuestionnaireExportState{QuestionnaireResult: "http://clinician/api/questionnaire_results/1", Patient: "http://clinician/api/patients/1"}
+		created, err := repo.FindOrCreateQuestionnaireResult(q)
+		if err != nil {
+			t.Fatalf("Error creating questionnaire result %v", err)
+		}
+		if created.ID == uuid.Nil {
+			t.Error("ID should be set")
+		}
+
+		found, err := repo.FindOrCreateQuestionnaireResult(q)
+		if err != nil {
+			t.Fatalf("Error finding questionnaire result %v", err)
+		}
+		if found.ID != created.ID {
+			t.Errorf("Expected same id - got %s and %s", found.ID, created.ID)
+		}
+
+		found.Status = TEMP_FAILURE
+		if _, err := repo.UpdateQuestionnaireResult(found); err != nil {
+			t.Fatalf("Error updating questionnaire result %v", err)
+		}
+
+		failed, err := repo.FindQuestionnaireResultsByStatus(TEMP_FAILURE)
+		if err != nil {
+			t.Fatalf("Error finding questionnaire results %v", err)
+		}
+		if len(failed) != 1 || failed[0].ID != created.ID {
+			t.Errorf("Expected the temporarily failed questionnaire result - got %v", failed)
+		}
+
+		if _, err := repo.UpdateQuestionnaireResult(QuestionnaireExportState{}); err == nil {
+			t.Error("Expected error updating unknown questionnaire result")
+		}
+	})
 }
 
-func TestUpdateMeasurement(t *testing.T) {
-	db, conn, repo, err := setupTestDatabase()
-	if err != nil {
-		t.Errorf("Error setting up db %+v", err)
-	}
-	defer func() {
-		repo.Close()
-		conn.Close()
-		db.Close()
-		fmt.Println("Resources closed")
-	}()
-
-	var count int
-	res := conn.QueryRow("SELECT count(*) from measurements")
-	if err := res.Scan(&count); err != nil {
-		t.Errorf("Error reading results - %+v", err)
-	}
-	fmt.Println("Count ", count)
+func TestQuestionnaireResultConsent(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d testDatabase) {
+		db, conn, repo, err := setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		defer func() {
+			repo.Close()
+			conn.Close()
+			db.Close()
+		}()
+
+		q := QuestionnaireExportState{QuestionnaireResult: "http://clinician/api/questionnaire_results/2", Patient: "http://clinician/api/patients/2"}
+		created, err := repo.FindOrCreateQuestionnaireResult(q)
+		if err != nil {
+			t.Fatalf("Error creating questionnaire result %v", err)
+		}
+
+		consentID := uuid.New().String()
+		created.Status = NO_EXPORT
+		created.Reason = REASON_CONSENT_WITHDRAWN
+		created.ConsentID = consentID
+		if _, err := repo.UpdateQuestionnaireResult(created); err != nil {
+			t.Fatalf("Error updating questionnaire result %v", err)
+		}
+
+		found, err := repo.FindOrCreateQuestionnaireResult(q)
+		if err != nil {
+			t.Fatalf("Error finding questionnaire result %v", err)
+		}
+		if found.Reason != REASON_CONSENT_WITHDRAWN || found.ConsentID != consentID {
+			t.Errorf("Expected reason %s and consent %s - got %s and %s", REASON_CONSENT_WITHDRAWN, consentID, found.Reason, found.ConsentID)
+		}
+
+		noexport, err := repo.FindQuestionnaireResultsByStatus(NO_EXPORT)
+		if err != nil {
+			t.Fatalf("Error finding questionnaire results %v", err)
+		}
+		if len(noexport) != 1 || noexport[0].ConsentID != consentID {
+			t.Errorf("Expected the blocked questionnaire result with its consent - got %v", noexport)
+		}
+	})
+}
 
-	if count != 0 {
-		t.Errorf("Database count is %d, expect 0", count)
-	}
-	mes := "/a/measurement"
-	m := MeasurementExportState{Measurement: mes, Patient: "mypatient"}
+func TestReasons(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d testDatabase) {
+		db, conn, repo, err := setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		defer func() {
+			repo.Close()
+			conn.Close()
+			db.Close()
+		}()
+
+		for i, reason := range []string{REASON_PATIENT_GROUP_EXCLUDED, REASON_PATIENT_GROUP_EXCLUDED, ""} {
+			m, err := repo.FindOrCreateMeasurement(MeasurementExportState{Measurement: fmt.Sprintf("/a/measurement/%d", i), Patient: "mypatient"})
+			if err != nil {
+				t.Fatalf("Error creating measurement %v", err)
+			}
+			m.Status = NO_EXPORT
+			m.Reason = reason
+			if _, err := repo.UpdateMeasurement(m); err != nil {
+				t.Fatalf("Error updating measurement %v", err)
+			}
+		}
+
+		m, err := repo.FindOrCreateMeasurement(MeasurementExportState{Measurement: "/a/measurement/0"})
+		if err != nil {
+			t.Fatalf("Error finding measurement %v", err)
+		}
+		if m.Reason != REASON_PATIENT_GROUP_EXCLUDED {
+			t.Errorf("Expected reason to be stored - got '%s'", m.Reason)
+		}
+
+		reasons := repo.GetReasons(NO_EXPORT)
+		if len(reasons) != 1 || reasons[REASON_PATIENT_GROUP_EXCLUDED] != 2 {
+			t.Errorf("Expected 2 measurements excluded by patient group - got %v", reasons)
+		}
+	})
+}
 
-	m, err = repo.FindOrCreateMeasurement(m)
-	if err != nil {
-		t.Errorf("Error retrieving measurements %v", err)
-	}
-	if m.Status != 0 {
-		t.Errorf("Wrong status")
-	}
-	if m.CreatedAt.Time.IsZero() {
-		t.Errorf("Created Time stamp not set")
-	}
-	if m.UpdatedAt.Time.IsZero() {
-		t.Errorf("Updated Time stamp not set")
-	}
-	if m.Status != INITIAL {
-		t.Errorf("Status is %s should be %s", StatusToText(m.Status), StatusToText(INITIAL))
-	}
+func TestConsent(t *testing.T) {
+	forEachDatabase(t, func(t *testing.T, d testDatabase) {
+		db, conn, repo, err := setupTestDatabase(d)
+		if err != nil {
+			t.Errorf("Error setting up db %+v", err)
+		}
+		defer func() {
+			repo.Close()
+			conn.Close()
+			db.Close()
+		}()
+
+		c, err := repo.CreateConsent(ConsentEntry{CPR: "251248-4916", Comment: "Withdrawn"})
+		if err != nil {
+			t.Fatalf("Error creating consent %v", err)
+		}
+		if c.CPR != "2512484916" {
+			t.Errorf("Expected normalized cpr - got %s", c.CPR)
+		}
+
+		again, err := repo.CreateConsent(ConsentEntry{CPR: "2512484916"})
+		if err != nil {
+			t.Fatalf("Error creating consent %v", err)
+		}
+		if again.ID != c.ID {
+			t.Error("Registering the same cpr twice should return the existing entry")
+		}
+
+		if _, err := repo.CreateConsent(ConsentEntry{}); err == nil {
+			t.Error("Expected error for empty consent")
+		}
+		if _, err := repo.CreateConsent(ConsentEntry{CPR: "25124849xx"}); err == nil {
+			t.Error("Expected error for invalid cpr")
+		}
+
+		entries, err := repo.FindConsents()
+		if err != nil || len(entries) != 1 {
+			t.Fatalf("Expected 1 entry - got %d %v", len(entries), err)
+		}
+
+		if found, err := repo.FindConsent("", "251248-4916"); err != nil || len(found) != 1 || found[0].ID != c.ID {
+			t.Errorf("Expected entry found by cpr - got %v %v", found, err)
+		}
+		if found, err := repo.FindConsent("http://clinician/api/patients/14", ""); err != nil || len(found) != 0 {
+			t.Errorf("Expected no entry for unknown patient - got %v %v", found, err)
+		}
+		if byCPR, err := repo.HasConsentByCPR(); err != nil || !byCPR {
+			t.Errorf("Expected entries by cpr - got %v %v", byCPR, err)
+		}
+
+		// Imports are registered completely or not at all
+		if _, err := repo.CreateConsents([]ConsentEntry{{CPR: "0101011234"}, {CPR: "12"}}); err == nil {
+			t.Error("Expected error for import with invalid cpr")
+		}
+		if found, _ := repo.FindConsent("", "0101011234"); len(found) != 0 {
+			t.Errorf("Expected no entries registered from failed import - got %v", found)
+		}
+		imported, err := repo.CreateConsents([]ConsentEntry{{CPR: "0101011234"}, {CPR: "2512484916"}})
+		if err != nil || len(imported) != 2 || imported[1].ID != c.ID {
+			t.Errorf("Expected import to register new and return existing entries - got %v %v", imported, err)
+		}
+
+		if err := repo.DeleteConsent(c.ID.String()); err != nil {
+			t.Errorf("Error deleting consent %v", err)
+		}
+		if err := repo.DeleteConsent(c.ID.String()); err == nil || !strings.Contains(err.Error(), sql.ErrNoRows.Error()) {
+			t.Errorf("Expected not found deleting twice - got %v", err)
+		}
+	})
+}
 
-	m.Status = TEMP_FAILURE
+func TestParseConsentCSV(t *testing.T) {
+	input := `cpr,patient,comment
+251248-4916,,Letter
+,http://clinician/api/patients/14, Phone
 
-	m, err = repo.UpdateMeasurement(m)
-	if err != nil {
-		t.Errorf("Error updating measurement %v", err)
-	}
+0101011234`
 
-	m2, err := repo.FindOrCreateMeasurement(MeasurementExportState{Measurement: mes})
+	entries, err := ParseConsentCSV(strings.NewReader(input))
 	if err != nil {
-		t.Errorf("Error retrieving measurements %v", err)
+		t.Fatalf("Unexpected error %v", err)
 	}
-	fmt.Println("Received ", m2, " ", m2.UpdatedAt.Time)
-	if m2.Status != m.Status {
-		t.Errorf("Status is %s - should be %s", StatusToText(m2.Status), StatusToText(m.Status))
-
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries - got %d", len(entries))
 	}
-
-	if m2.ID != m.ID {
-		t.Errorf("IDS different - %s <-> %s", m2.ID.String(), m.ID.String())
+	if entries[0].CPR != "2512484916" || entries[0].Comment != "Letter" {
+		t.Errorf("Unexpected entry %+v", entries[0])
 	}
-	if !m2.UpdatedAt.Valid {
-		t.Errorf("Updated time stamp not correct %v", m2.UpdatedAt.Valid)
+	if entries[1].Patient != "http://clinician/api/patients/14" || entries[1].Comment != "Phone" {
+		t.Errorf("Unexpected entry %+v", entries[1])
 	}
 
-	if m2.UpdatedAt.Time.Format(time.RFC3339) != m.UpdatedAt.Time.Format(time.RFC3339) {
-		t.Errorf("Updated time are different - %s<>%s", m2.UpdatedAt.Time.Format(time.RFC3339), m.UpdatedAt.Time.Format(time.RFC3339))
+	if _, err := ParseConsentCSV(strings.NewReader("123,,\n")); err == nil {
+		t.Error("Expected error for invalid cpr")
 	}
 }
@@ -19,7 +19,7 @@ func (mi repositoryImpl) StartExport() (RunStatus, error) {
 	}
 
 	var runs []int
-	if err := sess.Select(&runs, "SELECT count(*) from runstatus where status=?", COMPLETED); err != nil {
+	if err := sess.Select(&runs, sess.Rebind("SELECT count(*) from runstatus where status=?"), COMPLETED); err != nil {
 		fmt.Println("HERE", err)
 		return lastRun, errors.Wrap(err, "Error getting row count")
 	}
@@ -40,7 +40,7 @@ func (mi repositoryImpl) StartExport() (RunStatus, error) {
 		if err != nil {
 			return lastRun, errors.Wrap(err, "Error creating transaction")
 		}
-		_, err = tx.Exec("INSERT INTO runstatus (id, lastrun, status, created_at,updated_at) VALUES (?,?,?,?,?)",
+		_, err = tx.Exec(sess.Rebind("INSERT INTO runstatus (id, lastrun, status, created_at,updated_at) VALUES (?,?,?,?,?)"),
 			uuid.New(), t, COMPLETED, t, t)
 		if err != nil {
 			if rerr := tx.Rollback(); rerr != nil {
@@ -54,7 +54,7 @@ func (mi repositoryImpl) StartExport() (RunStatus, error) {
 		}
 	} else { // Get the last one
 
-		if err := sess.Get(&lastRun, "select * from runstatus where status=? order by lastrun desc limit 1", COMPLETED); err != nil {
+		if err := sess.Get(&lastRun, sess.Rebind("select * from runstatus where status=? order by lastrun desc limit 1"), COMPLETED); err != nil {
 			if err != sql.ErrNoRows {
 				log.Errorf("Error reading from database %+v", err)
 				log.Infof("Trace %+v", err)
@@ -76,7 +76,7 @@ func (mi repositoryImpl) StartExport() (RunStatus, error) {
 	if err != nil {
 		return lastRun, errors.Wrap(err, "Error creating transaction")
 	}
-	_, err = tx.Exec("INSERT INTO runstatus (id, lastrun, status, created_at,updated_at) VALUES (?,?,?,?,?)",
+	_, err = tx.Exec(sess.Rebind("INSERT INTO runstatus (id, lastrun, status, created_at,updated_at) VALUES (?,?,?,?,?)"),
 		lr.Id, time.Now(), INITIAL, time.Now(), time.Now())
 	if err != nil {
 		return lastRun, errors.Wrap(err, "Error inserting row")
@@ -104,7 +104,7 @@ func (mi repositoryImpl) UpdateExport(rs RunStatus) error {
 		return errors.Wrap(err, "Error creating transaction")
 	}
 
-	_, err = tx.Exec("UPDATE runstatus set status=?, updated_at=? where id=?", rs.Status, time.Now(), rs.Id)
+	_, err = tx.Exec(sess.Rebind("UPDATE runstatus set status=?, updated_at=? where id=?"), rs.Status, time.Now(), rs.Id)
 
 	if err != nil {
 		if rerr := tx.Rollback(); rerr != nil {
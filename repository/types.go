@@ -16,6 +16,16 @@ const (
 	TEMP_FAILURE = 3
 	FAILED       = 4
 	NO_EXPORT    = 5
+	INVALID      = 6
+)
+
+// Reasons for not exporting a measurement or questionnaire result
+const (
+	REASON_PATIENT_GROUP_EXCLUDED = "patient group excluded"
+	REASON_CONSENT_WITHDRAWN      = "consent withdrawn"
+	REASON_UNSUPPORTED_UNIT       = "unsupported unit"
+	REASON_CONTROL_MEASUREMENT    = "control measurement"
+	REASON_ANSWER_NOT_NUMERIC     = "answer not numeric"
 )
 
 func StatusToText(s int) string {
@@ -34,6 +44,8 @@ func StatusToText(s int) string {
 		name = "FAILED"
 	case NO_EXPORT:
 		name = "NO_EXPORT"
+	case INVALID:
+		name = "INVALID"
 	}
 	return name
 }
@@ -43,6 +55,8 @@ type MeasurementExportState struct {
 	Measurement   string         `json:"measurement"`
 	Patient       string         `json:"patient"`
 	Status        int            `json:"status"`
+	Reason        string         `json:"reason"`
+	ConsentID     string         `json:"consent_id" db:"consent_id"`
 	BackendStatus sql.NullInt32  `json:"-" db:"-"`
 	BackendValue  sql.NullString `json:"-" db:"-"`
 	CreatedAt     sql.NullTime   `json:"created_at" db:"created_at"`
@@ -60,6 +74,8 @@ func (m MeasurementExportState) MarshalJSON() ([]byte, error) {
 		Measurement   string         `json:"measurement,omitempty"`
 		Patient       string         `json:"patient,omitempty"`
 		Status        string         `json:"status,omitempty"`
+		Reason        string         `json:"reason,omitempty"`
+		ConsentID     string         `json:"consent_id,omitempty"`
 		BackendStatus sql.NullInt32  `json:"-"`
 		BackendValue  sql.NullString `json:"-"`
 		CreatedAt     time.Time      `json:"created_at,omitempty"`
@@ -69,6 +85,8 @@ func (m MeasurementExportState) MarshalJSON() ([]byte, error) {
 		Measurement: m.Measurement,
 		Patient:     m.Patient,
 		Status:      StatusToText(m.Status),
+		Reason:      m.Reason,
+		ConsentID:   m.ConsentID,
 		CreatedAt:   m.CreatedAt.Time,
 		UpdatedAt:   m.UpdatedAt.Time,
 	}
@@ -76,17 +94,86 @@ func (m MeasurementExportState) MarshalJSON() ([]byte, error) {
 	return json.Marshal(values)
 }
 
+// ConsentEntry registers a citizen, by cpr or patient link, who has withdrawn consent to export
+type ConsentEntry struct {
+	ID        uuid.UUID    `json:"id"`
+	CPR       string       `json:"cpr,omitempty"`
+	Patient   string       `json:"patient,omitempty"`
+	Comment   string       `json:"comment,omitempty"`
+	CreatedAt sql.NullTime `json:"-" db:"created_at"`
+}
+
+func (c ConsentEntry) String() string {
+	return fmt.Sprintf("ID: %s - patient: %s - comment: %s", c.ID, c.Patient, c.Comment)
+}
+
+// QuestionnaireExportState holds the export state of a questionnaire result
+type QuestionnaireExportState struct {
+	ID                  uuid.UUID    `json:"id"`
+	QuestionnaireResult string       `json:"questionnaire_result" db:"questionnaire_result"`
+	Patient             string       `json:"patient"`
+	Status              int          `json:"status"`
+	Reason              string       `json:"reason"`
+	ConsentID           string       `json:"consent_id" db:"consent_id"`
+	CreatedAt           sql.NullTime `json:"created_at" db:"created_at"`
+	UpdatedAt           sql.NullTime `json:"updated_at" db:"updated_at"`
+}
+
+func (q QuestionnaireExportState) String() string {
+	return fmt.Sprintf("ID: %s - Status: %s - questionnaire result: %s", q.ID, StatusToText(q.Status), q.QuestionnaireResult)
+}
+
+func (q QuestionnaireExportState) MarshalJSON() ([]byte, error) {
+
+	values := struct {
+		ID                  uuid.UUID `json:"id,omitempty"`
+		QuestionnaireResult string    `json:"questionnaire_result,omitempty"`
+		Patient             string    `json:"patient,omitempty"`
+		Status              string    `json:"status,omitempty"`
+		Reason              string    `json:"reason,omitempty"`
+		ConsentID           string    `json:"consent_id,omitempty"`
+		CreatedAt           time.Time `json:"created_at,omitempty"`
+		UpdatedAt           time.Time `json:"updated_at,omitempty"`
+	}{
+		ID:                  q.ID,
+		QuestionnaireResult: q.QuestionnaireResult,
+		Patient:             q.Patient,
+		Status:              StatusToText(q.Status),
+		Reason:              q.Reason,
+		ConsentID:           q.ConsentID,
+		CreatedAt:           q.CreatedAt.Time,
+		UpdatedAt:           q.UpdatedAt.Time,
+	}
+
+	return json.Marshal(values)
+}
+
 type Repository interface {
 	StartExport() (RunStatus, error)
 	UpdateExport(lr RunStatus) error
-	// Returns stats. Returns total numbed of measurements, failed messaurements, temporarily failed and rejected measusmrents
-	GetTotals() (int, int, int, int)
+	// Returns stats. Returns total numbed of measurements, failed messaurements, temporarily failed, rejected and invalid measusmrents
+	GetTotals() (int, int, int, int, int)
+	// Returns number of measurements with the status per reason
+	GetReasons(status int) map[string]int
 	GetRuns() (time.Time, int, int, int, int)
 	FindOrCreateMeasurement(m MeasurementExportState) (MeasurementExportState, error)
 	UpdateMeasurement(m MeasurementExportState) (MeasurementExportState, error)
 	FindMeasurement(id string) (MeasurementExportState, error)
 	FindMeasurements() ([]MeasurementExportState, error)
 	FindMeasurementsByStatus(status int) ([]MeasurementExportState, error)
+	FindOrCreateQuestionnaireResult(q QuestionnaireExportState) (QuestionnaireExportState, error)
+	UpdateQuestionnaireResult(q QuestionnaireExportState) (QuestionnaireExportState, error)
+	FindQuestionnaireResultsByStatus(status int) ([]QuestionnaireExportState, error)
+	// Registry of citizens who have withdrawn consent
+	CreateConsent(c ConsentEntry) (ConsentEntry, error)
+	CreateConsents(entries []ConsentEntry) ([]ConsentEntry, error)
+	DeleteConsent(id string) error
+	FindConsents() ([]ConsentEntry, error)
+	FindConsent(patient, cpr string) ([]ConsentEntry, error)
+	HasConsentByCPR() (bool, error)
+	// Persistent tier for the patient cache
+	FindCachedPatient(link string) ([]byte, time.Time, error)
+	StoreCachedPatient(link string, data []byte) error
 	CheckRepository() error
 	Close() error
 }
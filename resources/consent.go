@@ -0,0 +1,109 @@
+package resources
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// requireToken only lets requests with the configured token as bearer token through. Without a token the endpoints are disabled
+func requireToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := config.AppConfig.Token
+		if len(token) == 0 {
+			render.Render(w, r, &RestResponse{HTTPStatusCode: http.StatusForbidden, StatusText: "Endpoint is disabled - no token configured"}) // nolint
+			return
+		}
+
+		authorization := r.Header.Get("Authorization")
+		given := strings.TrimPrefix(authorization, "Bearer ")
+		if given == authorization || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			render.Render(w, r, &RestResponse{HTTPStatusCode: http.StatusUnauthorized, StatusText: "Unauthorized"}) // nolint
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withoutCPR removes the cpr from the entries. CPR numbers are never returned by the API
+func withoutCPR(entries []repository.ConsentEntry) []repository.ConsentEntry {
+	res := make([]repository.ConsentEntry, len(entries))
+	for i, c := range entries {
+		c.CPR = ""
+		res[i] = c
+	}
+	return res
+}
+
+// consentListHandler returns the registered consent entries - without cpr
+func consentListHandler(w http.ResponseWriter, r *http.Request) {
+	entries, err := repo.FindConsents()
+	if err != nil {
+		logger.Error("Error retrieving consent ", err)
+		render.Render(w, r, &RestResponse{HTTPStatusCode: http.StatusInternalServerError, StatusText: err.Error()}) // nolint
+		return
+	}
+	render.JSON(w, r, withoutCPR(entries))
+}
+
+// consentCreateHandler registers withdrawn consent. Accepts a single JSON entry or CSV with the columns cpr, patient and comment
+func consentCreateHandler(w http.ResponseWriter, r *http.Request) {
+	var entries []repository.ConsentEntry
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		parsed, err := repository.ParseConsentCSV(r.Body)
+		if err != nil {
+			render.Render(w, r, &RestResponse{HTTPStatusCode: http.StatusBadRequest, StatusText: err.Error()}) // nolint
+			return
+		}
+		entries = parsed
+	} else {
+		var entry repository.ConsentEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			render.Render(w, r, &RestResponse{HTTPStatusCode: http.StatusBadRequest, StatusText: fmt.Sprintf("Error parsing consent - %v", err)}) // nolint
+			return
+		}
+		entries = append(entries, entry)
+	}
+
+	// All entries are registered, or none are
+	created, err := repo.CreateConsents(entries)
+	if err != nil {
+		logger.Error("Error creating consent ", err)
+		render.Render(w, r, &RestResponse{HTTPStatusCode: http.StatusBadRequest, StatusText: err.Error()}) // nolint
+		return
+	}
+
+	created = withoutCPR(created)
+	render.Status(r, http.StatusCreated)
+	if len(created) == 1 {
+		render.JSON(w, r, created[0])
+		return
+	}
+	render.JSON(w, r, created)
+}
+
+// consentDeleteHandler removes a consent entry
+func consentDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "consent")
+
+	if err := repo.DeleteConsent(id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			render.Render(w, r, &RestResponse{HTTPStatusCode: http.StatusNotFound, StatusText: fmt.Sprintf("Consent %s not found", id)}) // nolint
+			return
+		}
+		logger.Error("Error deleting consent ", err)
+		render.Render(w, r, &RestResponse{HTTPStatusCode: http.StatusInternalServerError, StatusText: err.Error()}) // nolint
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -30,6 +30,7 @@ type RootResource struct {
 		Apidoc      string `json:"apidoc,omitempty"`
 		Categories  string `json:"categories,omitempty"`
 		Measurement string `json:"measurement,omitempty"`
+		Consent     string `json:"consent,omitempty"`
 		Contents    string `json:"contents,omitempty"`
 		Export      string `json:"export,omitempty"`
 		Failed      string `json:"failed,omitempty"`
@@ -71,6 +72,9 @@ type exportOverview struct {
 		TempFailedMeasurements int
 		RejectedMeasurements   int
 		FailedMeasurements     int
+		InvalidMeasurements    int
+		RejectedReasons        map[string]int `json:",omitempty"`
+		InvalidReasons         map[string]int `json:",omitempty"`
 	}
 	LastRun struct {
 		TimeStamp string
@@ -99,6 +103,7 @@ type exportOverview struct {
 	Service struct {
 		Started string
 	}
+	PatientCache *measurement.CacheStatistics `json:",omitempty"`
 }
 
 func setupRootResource() RootResource {
@@ -119,6 +124,7 @@ func setupRootResource() RootResource {
 	root.Links.Export = fmt.Sprintf("%s/export", host)
 	root.Links.Failed = fmt.Sprintf("%s/failed", host)
 	root.Links.Status = fmt.Sprintf("%s/status", host)
+	root.Links.Consent = fmt.Sprintf("%s/consent", host)
 	return root
 }
 
@@ -154,6 +160,12 @@ func InitRouter(appConfig *app.Config, rp repository.Repository, ap measurement.
 	r.Get("/export", exportHandler)
 	r.Get("/failed", failedHandler)
 	r.Get("/measurement/{measurement}", measurementHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(requireToken)
+		r.Get("/consent", consentListHandler)
+		r.Post("/consent", consentCreateHandler)
+		r.Delete("/consent/{consent}", consentDeleteHandler)
+	})
 
 	return r, nil
 }
@@ -15,6 +15,7 @@ import (
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/app"
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend"
+	_ "github.com/KvalitetsIT/kih-telecare-exporter/backend/oioxds"
 	"github.com/KvalitetsIT/kih-telecare-exporter/internal"
 	"github.com/KvalitetsIT/kih-telecare-exporter/internal/testutil"
 	othtest "github.com/KvalitetsIT/kih-telecare-exporter/internal/testutil"
@@ -47,7 +48,8 @@ func (rp failedRepositoryMock) StartExport() (repository.RunStatus, error) {
 func (rp failedRepositoryMock) UpdateExport(lr repository.RunStatus) error { return nil }
 
 // Returns stats. Returns total numbed of measurements, failed messaurements, temporarily failed and rejected measusmrents
-func (rp failedRepositoryMock) GetTotals() (int, int, int, int) { return 0, 0, 0, 0 }
+func (rp failedRepositoryMock) GetTotals() (int, int, int, int, int) { return 0, 0, 0, 0, 0 }
+func (rp failedRepositoryMock) GetReasons(status int) map[string]int { return map[string]int{} }
 func (rp failedRepositoryMock) GetRuns() (time.Time, int, int, int, int) {
 	return time.Now(), 0, 0, 0, 0
 }
@@ -69,6 +71,39 @@ func (rp failedRepositoryMock) FindMeasurements() ([]repository.MeasurementExpor
 func (rp failedRepositoryMock) FindMeasurementsByStatus(status int) ([]repository.MeasurementExportState, error) {
 	return []repository.MeasurementExportState{}, nil
 }
+func (rp failedRepositoryMock) FindOrCreateQuestionnaireResult(q repository.QuestionnaireExportState) (repository.QuestionnaireExportState, error) {
+	return q, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) UpdateQuestionnaireResult(q repository.QuestionnaireExportState) (repository.QuestionnaireExportState, error) {
+	return q, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) FindQuestionnaireResultsByStatus(status int) ([]repository.QuestionnaireExportState, error) {
+	return []repository.QuestionnaireExportState{}, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) CreateConsent(c repository.ConsentEntry) (repository.ConsentEntry, error) {
+	return c, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) CreateConsents(entries []repository.ConsentEntry) ([]repository.ConsentEntry, error) {
+	return []repository.ConsentEntry{}, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) DeleteConsent(id string) error {
+	return fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) FindConsents() ([]repository.ConsentEntry, error) {
+	return []repository.ConsentEntry{}, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) FindConsent(patient, cpr string) ([]repository.ConsentEntry, error) {
+	return []repository.ConsentEntry{}, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) HasConsentByCPR() (bool, error) {
+	return false, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) FindCachedPatient(link string) ([]byte, time.Time, error) {
+	return []byte{}, time.Time{}, fmt.Errorf("Its and error")
+}
+func (rp failedRepositoryMock) StoreCachedPatient(link string, data []byte) error {
+	return fmt.Errorf("Its and error")
+}
 func (rp failedRepositoryMock) CheckRepository() error {
 	return fmt.Errorf("Its and error")
 }
@@ -101,8 +136,12 @@ func (em exportMock) ExportMeasurement(measurement measurement.Measurement, m re
 	return backend.ExportResult{}, nil
 }
 
-func (em exportMock) HandleMeasurement(measurement measurement.Measurement, m repository.MeasurementExportState) (backend.ExportResult, int, int, int, error) {
-	return backend.ExportResult{}, 0, 0, 0, nil
+func (em exportMock) HandleMeasurement(measurement measurement.Measurement, m repository.MeasurementExportState) (backend.ExportResult, int, int, int, int, error) {
+	return backend.ExportResult{}, 0, 0, 0, 0, nil
+}
+
+func (em exportMock) ExportQuestionnaireResults(since time.Time) ([]backend.ExportResult, int, int, int, error) {
+	return []backend.ExportResult{}, 0, 0, 0, nil
 }
 
 func TestMain(m *testing.M) {
@@ -653,7 +692,8 @@ func TestFailedMeasurementsEndpoint(t *testing.T) {
 	tofail, _ := mApi.FetchMeasurements(time.Now().AddDate(-1, 0, 0), 400)
 	setFailed := 0
 	for i, v := range tofail.Results {
-		if exportr.ShouldExport(v) {
+		// Control measurements are not exported
+		if exportr.ShouldExport(v) && !v.Measurement.IsControlMeasurement {
 			m := repository.MeasurementExportState{}
 			setFailed++
 			m.Measurement = v.Links.Measurement
@@ -714,3 +754,115 @@ func TestFailedMeasurementsEndpoint(t *testing.T) {
 		}
 	}
 }
+
+func TestConsentResource(t *testing.T) {
+	xprtr := exportMock{}
+	appConfig, _ := app.InitConfig()
+	appConfig.AppConfig.Token = "secret"
+	api := internal.TestInjectorApi{}
+
+	db, conn, repo, err := setupTestDatabase()
+	if err != nil {
+		t.Fatal("Error setting up DB")
+	}
+	defer func() {
+		repo.Close()
+		conn.Close()
+		db.Close()
+	}()
+
+	router, err := InitRouter(appConfig, repo, api, xprtr)
+	if err != nil {
+		t.Errorf("Error creating router %v", err)
+	}
+
+	token := "secret"
+	request := func(method, path, contentType, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Error creating http request %v", err)
+		}
+		if len(contentType) > 0 {
+			req.Header.Set("Content-Type", contentType)
+		}
+		if len(token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := request("POST", "/consent", "application/json", `{"cpr":"251248-4916","comment":"Withdrawn by phone"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Status code should be 201 but is %d - %s", rr.Code, rr.Body.String())
+	}
+	var created repository.ConsentEntry
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Error unmarshalling consent %v", err)
+	}
+	if len(created.CPR) > 0 || strings.Contains(rr.Body.String(), "2512484916") {
+		t.Errorf("CPR must not be returned - got %s", rr.Body.String())
+	}
+
+	rr = request("POST", "/consent", "text/csv", "cpr,patient,comment\n,http://clinician:8080/clinician/api/patients/14,Letter\n0101011234,,\n")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Status code should be 201 but is %d - %s", rr.Code, rr.Body.String())
+	}
+
+	if rr = request("POST", "/consent", "application/json", `{"cpr":"12"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Invalid cpr should be rejected - got %d", rr.Code)
+	}
+
+	rr = request("GET", "/consent", "", "")
+	var entries []repository.ConsentEntry
+	if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+		t.Fatalf("Error unmarshalling consent %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("Expected 3 consent entries - got %d", len(entries))
+	}
+	for _, c := range entries {
+		if len(c.CPR) > 0 {
+			t.Errorf("CPR must not be returned - got %v", c)
+		}
+	}
+
+	// Requests without the token are rejected
+	token = "wrong"
+	for _, method := range []string{"GET", "POST"} {
+		if rr = request(method, "/consent", "application/json", `{"cpr":"0202021234"}`); rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s without token should be unauthorized - got %d", method, rr.Code)
+		}
+	}
+	if rr = request("DELETE", "/consent/"+created.ID.String(), "", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("DELETE without token should be unauthorized - got %d", rr.Code)
+	}
+	token = "secret"
+
+	// The token is only accepted as a bearer token
+	rr = httptest.NewRecorder()
+	req, err := http.NewRequest("GET", "/consent", nil)
+	if err != nil {
+		t.Fatalf("Error creating http request %v", err)
+	}
+	req.Header.Set("Authorization", token)
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Token without the Bearer scheme should be unauthorized - got %d", rr.Code)
+	}
+
+	// Without a configured token the endpoint is disabled
+	appConfig.AppConfig.Token = ""
+	if rr = request("GET", "/consent", "", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("Consent should be disabled without token - got %d", rr.Code)
+	}
+	appConfig.AppConfig.Token = "secret"
+
+	if rr = request("DELETE", "/consent/"+created.ID.String(), "", ""); rr.Code != http.StatusNoContent {
+		t.Errorf("Status code should be 204 but is %d", rr.Code)
+	}
+	if rr = request("DELETE", "/consent/"+created.ID.String(), "", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Status code should be 404 but is %d", rr.Code)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/KvalitetsIT/kih-telecare-exporter/backend"
+	"github.com/KvalitetsIT/kih-telecare-exporter/backend/registry"
 	"github.com/KvalitetsIT/kih-telecare-exporter/measurement"
 	"github.com/KvalitetsIT/kih-telecare-exporter/repository"
 	"github.com/go-chi/render"
@@ -27,11 +28,14 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 
 	overview := exportOverview{}
 
-	total, failed, tempfailed, rejects := repo.GetTotals()
+	total, failed, tempfailed, rejects, invalid := repo.GetTotals()
 	overview.Measurements.TotalMeasurements = total
 	overview.Measurements.TempFailedMeasurements = tempfailed
 	overview.Measurements.FailedMeasurements = failed
 	overview.Measurements.RejectedMeasurements = rejects
+	overview.Measurements.RejectedReasons = repo.GetReasons(repository.NO_EXPORT)
+	overview.Measurements.InvalidMeasurements = invalid
+	overview.Measurements.InvalidReasons = repo.GetReasons(repository.INVALID)
 
 	lasttime, laststatus, totalruns, successfullruns, failedruns := repo.GetRuns()
 	overview.LastRun.TimeStamp = lasttime.Format(time.RFC3339)
@@ -44,13 +48,18 @@ func statusHandler(w http.ResponseWriter, r *http.Request) {
 	overview.Source.LastSuccesfullPing = lastSuccesfullSourcePing.Format(time.RFC3339)
 	overview.Source.LastFailedPing = lastFailedSourcePing.Format(time.RFC3339)
 	overview.Destination.Type = config.Export.Backend
-	overview.Destination.Endpoint = config.Export.GetExportEndpoint()
+	overview.Destination.Endpoint = registry.Endpoint(config)
 	overview.Destination.LastSuccesfullPing = lastSuccesfullDestinatiomPing.Format(time.RFC3339)
 	overview.Destination.LastFailedPing = lastFailedDestinatiomPing.Format(time.RFC3339)
 	overview.DB.LastSuccesfullPing = lastSuccesfullDBPing.Format(time.RFC3339)
 	overview.DB.LastFailedPing = lastFailedDBPing.Format(time.RFC3339)
 
 	overview.Service.Started = serviceStarted.Format(time.RFC3339)
+
+	if cache, ok := api.(measurement.CacheReporter); ok {
+		stats := cache.CacheStatistics()
+		overview.PatientCache = &stats
+	}
 	render.JSON(w, r, overview)
 }
 